@@ -0,0 +1,450 @@
+package recipeDatabase
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+//BakersPercentage is the weight of one ingredient as a percentage of the
+//total weight of flour in a recipe, which is always 100%.
+type BakersPercentage struct {
+	Name    string   // name of ingredient
+	Percent Fraction // percentage of total flour weight
+	Grams   Fraction // weight of ingredient in grams
+	IsFlour bool     // ingredient is part of the flour base
+}
+
+//ingredientDensities are grams per milliliter of common ingredients, used to
+//convert volumes to weights when an ingredient has no conversion of its own.
+//Longer names are matched before shorter ones, so bread flour is not matched
+//as flour.
+var ingredientDensities = map[string]float64{
+	"flour":                 0.507,
+	"all-purpose flour":     0.507,
+	"bread flour":           0.537,
+	"whole wheat flour":     0.507,
+	"rye flour":             0.431,
+	"cake flour":            0.482,
+	"water":                 1,
+	"milk":                  1.03,
+	"buttermilk":            1.03,
+	"cream":                 1.01,
+	"yogurt":                1.03,
+	"sugar":                 0.845,
+	"brown sugar":           0.9,
+	"powdered sugar":        0.507,
+	"honey":                 1.42,
+	"butter":                0.96,
+	"oil":                   0.92,
+	"olive oil":             0.92,
+	"salt":                  1.22,
+	"kosher salt":           0.69,
+	"instant yeast":         0.63,
+	"active dry yeast":      0.63,
+	"yeast":                 0.63,
+	"baking soda":           0.93,
+	"baking powder":         0.81,
+	"cocoa powder":          0.42,
+	"rolled oats":           0.38,
+	"cornmeal":              0.67,
+	"semolina":              0.71,
+	"vital wheat gluten":    0.55,
+	"diastatic malt powder": 0.55,
+}
+
+//waterContent is the fraction of an ingredient's weight that is water, used
+//when calculating dough hydration. Ingredients are matched by the words of
+//their names, so eggplant is not egg.
+var waterContent = []struct {
+	Name     string
+	Fraction float64
+}{
+	{"buttermilk", 0.9},
+	{"water", 1},
+	{"milk", 0.87},
+	{"cream", 0.6},
+	{"yogurt", 0.85},
+	{"beer", 0.92},
+	{"juice", 0.88},
+	{"egg", 0.75},
+	{"butter", 0.16},
+	{"honey", 0.17},
+}
+
+//itemWeights are the grams of one of an ingredient counted without a unit,
+//ie: 3 eggs, used to weigh it when it has no conversion of its own
+var itemWeights = map[string]float64{
+	"egg":          50,
+	"egg yolk":     18,
+	"egg white":    32,
+	"banana":       118,
+	"apple":        182,
+	"lemon":        58,
+	"lime":         44,
+	"orange":       131,
+	"onion":        110,
+	"potato":       213,
+	"carrot":       61,
+	"tomato":       123,
+	"avocado":      150,
+	"clove":        5,
+	"garlic clove": 5,
+}
+
+//lookupItemWeight finds the weight of one of an ingredient by the longest
+//known name among the words of name
+func lookupItemWeight(name string) (float64, bool) {
+	words := nameWords(name)
+	best := ""
+	for known := range itemWeights {
+		if len(phraseSpans(words, known)) > 0 && len(known) > len(best) {
+			best = known
+		}
+	}
+	if best == "" {
+		return 0, false
+	}
+	return itemWeights[best], true
+}
+
+//lookupDensity finds the density of an ingredient by the longest known
+//name contained in name.
+func lookupDensity(name string) (float64, bool) {
+	lowerName := strings.ToLower(name)
+	best := ""
+	for known := range ingredientDensities {
+		if strings.Contains(lowerName, known) && len(known) > len(best) {
+			best = known
+		}
+	}
+	if best == "" {
+		return 0, false
+	}
+	return ingredientDensities[best], true
+}
+
+//Grams returns the weight of the ingredient in grams. Volumes are converted
+//using the ingredient's own conversions if it has one to a mass unit, and
+//then the table of common ingredient densities. Ingredients counted without
+//a unit, or counted in a unit that is itself an item such as cloves, use
+//the weight of one item.
+func (i Ingredient) Grams() (Fraction, error) {
+	grams, err := i.convertQuantity("g")
+	if err == nil {
+		return grams, nil
+	}
+	if _, ok := lookupUnit(i.QuantityNeeded.Unit); ok {
+		return grams, err
+	}
+	// the count is of the unit when there is one, ie: 2 cans of tomatoes
+	// are not weighed as 2 tomatoes
+	item := i.QuantityNeeded.Unit
+	if item == "" {
+		item = i.Name
+	}
+	weight, ok := lookupItemWeight(item)
+	if !ok {
+		return grams, err
+	}
+	return i.QuantityNeeded.Amount.MulFloat(weight), nil
+}
+
+//convertQuantity converts the quantity of an ingredient to toUnit, crossing
+//between volume and mass where the ingredient has a known density.
+func (i Ingredient) convertQuantity(toUnit string) (Fraction, error) {
+	fromUnit := i.QuantityNeeded.Unit
+	converted, err := ConvertUnits(i.QuantityNeeded.Amount, fromUnit, toUnit)
+	if err == nil {
+		return converted, nil
+	}
+
+	// conversions stored with the ingredient take priority, either directly
+	// or through the base units
+	for _, c := range i.Conversions {
+		if sameUnit(c.FromUnit, fromUnit) && sameUnit(c.ToUnit, toUnit) {
+			return i.QuantityNeeded.Amount.MulFloat(c.ConversionFactor), nil
+		}
+		if sameUnit(c.ToUnit, fromUnit) && sameUnit(c.FromUnit, toUnit) && c.ConversionFactor != 0 {
+			return i.QuantityNeeded.Amount.Div(FractionFromFloat(c.ConversionFactor)), nil
+		}
+	}
+	for _, c := range i.Conversions {
+		fromQuantity, fromErr := ConvertUnits(i.QuantityNeeded.Amount, fromUnit, c.FromUnit)
+		if fromErr != nil {
+			continue
+		}
+		converted, toErr := ConvertUnits(fromQuantity.MulFloat(c.ConversionFactor), c.ToUnit, toUnit)
+		if toErr == nil {
+			return converted, nil
+		}
+	}
+
+	from, fromOk := lookupUnit(fromUnit)
+	to, toOk := lookupUnit(toUnit)
+	density, densityOk := lookupDensity(i.Name)
+	if !fromOk || !toOk || !densityOk {
+		return Fraction{}, fmt.Errorf("no conversion from %s to %s for %s", fromUnit, toUnit, i.Name)
+	}
+	base := i.QuantityNeeded.Amount.MulFloat(from.ToBase)
+	switch {
+	case from.Family == Volume && to.Family == Mass:
+		base = base.MulFloat(density)
+	case from.Family == Mass && to.Family == Volume:
+		base = base.Div(FractionFromFloat(density))
+	default:
+		return Fraction{}, fmt.Errorf("no conversion from %s to %s for %s", fromUnit, toUnit, i.Name)
+	}
+	return base.Div(FractionFromFloat(to.ToBase)), nil
+}
+
+//FlourWeight returns the total weight in grams of every ingredient marked
+//as flour, and the names of the flours that could not be weighed and are
+//left out.
+func (r Recipe) FlourWeight() (Fraction, []string, error) {
+	var total Fraction
+	var unweighed []string
+	found := false
+	for _, ingredient := range r.Ingredients {
+		if !ingredient.IsFlour {
+			continue
+		}
+		found = true
+		grams, err := ingredient.Grams()
+		if err != nil {
+			unweighed = append(unweighed, ingredient.Name)
+			continue
+		}
+		total = total.Add(grams)
+	}
+	if !found {
+		return Fraction{}, unweighed, errors.New("no ingredients are marked as flour")
+	}
+	if total.IsZero() {
+		return Fraction{}, unweighed, errors.New("flour weight is zero")
+	}
+	return total, unweighed, nil
+}
+
+//BakersPercentages returns every ingredient of the recipe as a percentage
+//of the total flour weight. Ingredients that cannot be weighed are left out
+//and their names returned.
+func (r Recipe) BakersPercentages() ([]BakersPercentage, []string, error) {
+	flour, unweighed, err := r.FlourWeight()
+	if err != nil {
+		return nil, unweighed, err
+	}
+	percentages := make([]BakersPercentage, 0, len(r.Ingredients))
+	for _, ingredient := range r.Ingredients {
+		grams, err := ingredient.Grams()
+		if err != nil {
+			unweighed = appendIfMissing(unweighed, ingredient.Name)
+			continue
+		}
+		percentages = append(percentages, BakersPercentage{
+			Name:    ingredient.Name,
+			Percent: grams.Div(flour).Mul(NewFraction(100, 1)),
+			Grams:   grams,
+			IsFlour: ingredient.IsFlour,
+		})
+	}
+	return percentages, unweighed, nil
+}
+
+//Hydration returns the weight of water in the recipe as a percentage of the
+//flour weight. Water contained in other ingredients such as milk or eggs is
+//included. Watery ingredients that cannot be weighed are left out and their
+//names returned.
+func (r Recipe) Hydration() (Fraction, []string, error) {
+	flour, unweighed, err := r.FlourWeight()
+	if err != nil {
+		return Fraction{}, unweighed, err
+	}
+	var water Fraction
+	for _, ingredient := range r.Ingredients {
+		words := nameWords(ingredient.Name)
+		for _, content := range waterContent {
+			if len(phraseSpans(words, content.Name)) == 0 {
+				continue
+			}
+			grams, err := ingredient.Grams()
+			if err != nil {
+				unweighed = appendIfMissing(unweighed, ingredient.Name)
+				break
+			}
+			water = water.Add(grams.MulFloat(content.Fraction))
+			break
+		}
+	}
+	return water.Div(flour).Mul(NewFraction(100, 1)), unweighed, nil
+}
+
+//DoughWeight returns the total weight in grams of every ingredient, and the
+//names of the ingredients that could not be weighed and are left out.
+func (r Recipe) DoughWeight() (Fraction, []string) {
+	var total Fraction
+	var unweighed []string
+	for _, ingredient := range r.Ingredients {
+		grams, err := ingredient.Grams()
+		if err != nil {
+			unweighed = append(unweighed, ingredient.Name)
+			continue
+		}
+		total = total.Add(grams)
+	}
+	return total, unweighed
+}
+
+//ScaleToDoughWeight returns a copy of the recipe scaled so the total weight
+//of all ingredients is grams, and the names of the ingredients that could
+//not be weighed. Those are scaled with the rest but are not counted in the
+//weight, so the scaled recipe weighs more than grams.
+func (r Recipe) ScaleToDoughWeight(grams Fraction) (Recipe, []string, error) {
+	current, unweighed := r.DoughWeight()
+	if current.IsZero() {
+		return r, unweighed, errors.New("recipe has no dough weight to scale from")
+	}
+	scaled, err := r.Scale(grams.Div(current).Float64())
+	return scaled, unweighed, err
+}
+
+//BakersString acts like String() but lists ingredients as baker's
+//percentages along with the flour weight and hydration.
+func (r Recipe) BakersString() string {
+	percentages, unweighed, err := r.BakersPercentages()
+	if err != nil {
+		return fmt.Sprintf("%s \n\nCannot show baker's percentages: %s\n", r.Name, err)
+	}
+	stringString := fmt.Sprintf("%s \n\n", r.Name)
+	flour, _, _ := r.FlourWeight()
+	dough, _ := r.DoughWeight()
+	stringString += fmt.Sprintf("Flour weight: %s\n", displayQuantity(flour, "g"))
+	stringString += fmt.Sprintf("Dough weight: %s\n", displayQuantity(dough, "g"))
+	hydration, _, err := r.Hydration()
+	if err == nil {
+		stringString += fmt.Sprintf("Hydration: %s%%\n", hydration.DecimalString(1))
+	}
+	stringString += "Ingredients: \n"
+	sorted := make([]BakersPercentage, len(percentages))
+	copy(sorted, percentages)
+	// flours first, then largest to smallest, like a baker's formula
+	sort.SliceStable(sorted, func(a, b int) bool {
+		if sorted[a].IsFlour != sorted[b].IsFlour {
+			return sorted[a].IsFlour
+		}
+		return sorted[a].Percent.Cmp(sorted[b].Percent) > 0
+	})
+	for _, percentage := range sorted {
+		stringString += fmt.Sprintf("\t%s: %s%% (%s)\n", percentage.Name,
+			percentage.Percent.DecimalString(1), displayQuantity(percentage.Grams, "g"))
+	}
+	if len(unweighed) > 0 {
+		stringString += fmt.Sprintf("Warning: could not weigh %s, left out\n", strings.Join(unweighed, ", "))
+	}
+	if r.Nutrition != nil {
+		stringString += r.Nutrition.String()
+	}
+	if r.Cost != nil {
+		stringString += "Cost: " + r.Cost.Summary() + r.Cost.Warning()
+	}
+	stringString += "\n"
+	return stringString
+}
+
+//NewBakersRecipe creates a recipe from baker's percentages and the total
+//dough weight in grams it should make. At least one percentage must be
+//marked as flour, and the flour percentages should add up to 100.
+func NewBakersRecipe(name string, percentages []BakersPercentage, doughWeight Fraction) (Recipe, error) {
+	var recipe Recipe
+	recipe.Name = name
+	recipe.QuantityMade = NewQuantity(doughWeight, "g")
+
+	var totalPercent, flourPercent Fraction
+	for _, percentage := range percentages {
+		if percentage.Percent.Sign() < 0 {
+			return recipe, fmt.Errorf("%s has a negative percentage", percentage.Name)
+		}
+		totalPercent = totalPercent.Add(percentage.Percent)
+		if percentage.IsFlour {
+			flourPercent = flourPercent.Add(percentage.Percent)
+		}
+	}
+	if flourPercent.IsZero() {
+		return recipe, errors.New("no ingredients are marked as flour")
+	}
+	if flourPercent.Cmp(NewFraction(100, 1)) != 0 {
+		return recipe, fmt.Errorf("flour percentages add up to %s%%, not 100%%", flourPercent.DecimalString(2))
+	}
+	if doughWeight.Sign() <= 0 {
+		return recipe, errors.New("dough weight must be greater than zero")
+	}
+
+	flourWeight := doughWeight.Mul(NewFraction(100, 1)).Div(totalPercent)
+	for _, percentage := range percentages {
+		recipe.Ingredients = append(recipe.Ingredients, Ingredient{
+			Name:           percentage.Name,
+			QuantityNeeded: NewQuantity(flourWeight.Mul(percentage.Percent).Div(NewFraction(100, 1)), "g"),
+			IsFlour:        percentage.IsFlour,
+		})
+	}
+	return recipe, nil
+}
+
+//ReadBakersRecipe creates a recipe by prompting user for baker's
+//percentages and a target dough weight
+func ReadBakersRecipe() (Recipe, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Print("Enter recipe Name: ")
+	name, err := reader.ReadString('\n')
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	fmt.Print("Enter total dough weight in grams: ")
+	tempString, err := reader.ReadString('\n')
+	if err != nil {
+		return Recipe{}, err
+	}
+	doughWeight, err := ParseFraction(strings.TrimSuffix(strings.TrimSpace(tempString), "g"))
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	var percentages []BakersPercentage
+	fmt.Println("Enter ingredients as: <percent> <name>, prefix flours with *. " +
+		"ie: *100 bread flour. Enter a blank line when done")
+	for {
+		fmt.Print("Ingredient: ")
+		tempString, err = reader.ReadString('\n')
+		if err != nil {
+			return Recipe{}, err
+		}
+		tempString = strings.TrimSpace(tempString)
+		if tempString == "" {
+			break
+		}
+		var percentage BakersPercentage
+		if strings.HasPrefix(tempString, "*") {
+			percentage.IsFlour = true
+			tempString = strings.TrimSpace(tempString[1:])
+		}
+		fields := strings.SplitN(tempString, " ", 2)
+		if len(fields) != 2 {
+			fmt.Println("Expected a percentage followed by a name")
+			continue
+		}
+		percentage.Percent, err = ParseFraction(strings.TrimSuffix(fields[0], "%"))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		percentage.Name = strings.TrimSpace(fields[1])
+		percentages = append(percentages, percentage)
+	}
+
+	return NewBakersRecipe(strings.TrimSpace(name), percentages, doughWeight)
+}
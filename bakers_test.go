@@ -0,0 +1,82 @@
+package recipeDatabase
+
+import "testing"
+
+func TestIngredientGrams(t *testing.T) {
+	tests := []struct {
+		amount Fraction
+		unit   string
+		name   string
+		want   Fraction
+	}{
+		{NewFraction(500, 1), "g", "bread flour", NewFraction(500, 1)},
+		{NewFraction(1, 1), "kg", "water", NewFraction(1000, 1)},
+		{NewFraction(3, 1), "", "eggs", NewFraction(150, 1)},
+		{NewFraction(2, 1), "cloves", "garlic", NewFraction(10, 1)},
+		{NewFraction(2, 1), "cups", "water", FractionFromFloat(473.176473)},
+	}
+	for _, test := range tests {
+		ingredient := Ingredient{Name: test.name, QuantityNeeded: NewQuantity(test.amount, test.unit)}
+		got, err := ingredient.Grams()
+		if err != nil {
+			t.Errorf("Grams() of %s %s returned error: %s", ingredient.QuantityNeeded, test.name, err)
+			continue
+		}
+		if got.Cmp(test.want) != 0 {
+			t.Errorf("Grams() of %s %s = %s, want %s", ingredient.QuantityNeeded, test.name,
+				got.DecimalString(3), test.want.DecimalString(3))
+		}
+	}
+	// a count of a unit that is not an item is not weighed as the ingredient
+	ingredient := Ingredient{Name: "tomatoes", QuantityNeeded: NewQuantity(NewFraction(1, 1), "can")}
+	if got, err := ingredient.Grams(); err == nil {
+		t.Errorf("Grams() of 1 can tomatoes = %s, want an error", got)
+	}
+}
+
+func TestBakersPercentages(t *testing.T) {
+	recipe := Recipe{Name: "brioche", Ingredients: []Ingredient{
+		{Name: "bread flour", QuantityNeeded: NewQuantity(NewFraction(500, 1), "g"), IsFlour: true},
+		{Name: "milk", QuantityNeeded: NewQuantity(NewFraction(100, 1), "g")},
+		{Name: "eggs", QuantityNeeded: NewQuantity(NewFraction(3, 1), "")},
+		{Name: "eggplant", QuantityNeeded: NewQuantity(NewFraction(100, 1), "g")},
+		{Name: "vanilla bean", QuantityNeeded: NewQuantity(NewFraction(1, 1), "")},
+	}}
+	percentages, unweighed, err := recipe.BakersPercentages()
+	if err != nil {
+		t.Fatalf("BakersPercentages() returned error: %s", err)
+	}
+	want := map[string]Fraction{"bread flour": NewFraction(100, 1), "milk": NewFraction(20, 1),
+		"eggs": NewFraction(30, 1), "eggplant": NewFraction(20, 1)}
+	if len(percentages) != len(want) {
+		t.Errorf("BakersPercentages() returned %d ingredients, want %d", len(percentages), len(want))
+	}
+	for _, percentage := range percentages {
+		if percentage.Percent.Cmp(want[percentage.Name]) != 0 {
+			t.Errorf("%s is %s%%, want %s%%", percentage.Name, percentage.Percent, want[percentage.Name])
+		}
+	}
+	if len(unweighed) != 1 || unweighed[0] != "vanilla bean" {
+		t.Errorf("unweighed ingredients are %v, want [vanilla bean]", unweighed)
+	}
+
+	// milk is 87% water and eggs 75%, eggplant is not egg
+	hydration, _, err := recipe.Hydration()
+	if err != nil {
+		t.Fatalf("Hydration() returned error: %s", err)
+	}
+	if got := hydration.DecimalString(1); got != "39.9" {
+		t.Errorf("Hydration() = %s%%, want 39.9%%", got)
+	}
+
+	scaled, unweighed, err := recipe.ScaleToDoughWeight(NewFraction(1700, 1))
+	if err != nil {
+		t.Fatalf("ScaleToDoughWeight() returned error: %s", err)
+	}
+	if len(unweighed) != 1 {
+		t.Errorf("ScaleToDoughWeight() unweighed %v, want [vanilla bean]", unweighed)
+	}
+	if weight, _ := scaled.DoughWeight(); weight.DecimalString(0) != "1700" {
+		t.Errorf("scaled dough weighs %s g, want 1700 g", weight.DecimalString(2))
+	}
+}
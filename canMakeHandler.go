@@ -0,0 +1,252 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	backend "github.com/sww1235/recipe-database"
+)
+
+//rankRecipes ranks every recipe in the database by how fully the current
+//inventory covers it, warning about ingredients the household members
+//named by eaters, or every member if it is empty, can not eat
+func rankRecipes(db *sql.DB, options backend.CoverageOptions, eaters []string) ([]backend.RecipeCoverage, error) {
+	recipes, err := selectAllRecipes(db)
+	if err != nil {
+		return nil, err
+	}
+	inventory, err := inventoryByIngredient(db)
+	if err != nil {
+		return nil, err
+	}
+	if options.Staples == nil {
+		options.Staples = config.PantryStaples
+	}
+	if options.Substitutions == nil {
+		options.Substitutions, err = selectSubstitutions(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.Household == nil {
+		check, err := newHouseholdCheck(db)
+		if err != nil {
+			return nil, err
+		}
+		if options.Household, err = backend.MembersNamed(check.members, eaters); err != nil {
+			return nil, err
+		}
+		options.Catalog = check.catalog
+	}
+	return backend.RankRecipes(recipes, inventory, options), nil
+}
+
+//splitTags splits a comma separated list of tags, dropping empty ones
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+//canMakeString lists ranked recipes, with the missing ingredients of each
+func canMakeString(ranked []backend.RecipeCoverage) string {
+	if len(ranked) == 0 {
+		return "No recipes match\n"
+	}
+	stringString := ""
+	for _, coverage := range ranked {
+		stringString += coverage.String()
+	}
+	return stringString
+}
+
+//canMakeCommand runs the canmake subcommand given on the command line
+func canMakeCommand(db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("canmake", flag.ContinueOnError)
+	ignoreStaples := flags.Bool("staples", false, "Ignore pantry staples such as salt and water")
+	tags := flags.String("tags", "", "Only show recipes with all of these comma separated tags")
+	diets := flags.String("diet", "", "Only show recipes suiting all of these comma separated diets, ie: vegan,gluten-free")
+	maxMissing := flags.Int("missing", 2, "Only show recipes missing at most this many ingredients, -1 for all")
+	format := flags.String("format", "text", "Output format: text or json")
+	noSubstitutes := flags.Bool("nosubs", false, "Do not count substitutions on hand as covering an ingredient")
+	eaters := flags.String("for", "", "Warn about the comma separated household members eating, default everyone")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: cookbook canmake [flags]")
+		fmt.Fprintln(flags.Output(), "Ranks recipes by how much of their ingredients are in inventory")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	options := backend.CoverageOptions{IgnoreStaples: *ignoreStaples, Tags: splitTags(*tags),
+		MaxMissing: *maxMissing}
+	var err error
+	if options.Diets, err = backend.ParseDiets(*diets); err != nil {
+		return err
+	}
+	if *noSubstitutes {
+		// an empty, not nil, list stops rankRecipes loading them
+		options.Substitutions = []backend.Substitution{}
+	}
+	ranked, err := rankRecipes(db, options, splitTags(*eaters))
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "json":
+		if ranked == nil {
+			ranked = []backend.RecipeCoverage{}
+		}
+		bytes, err := json.MarshalIndent(ranked, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(bytes))
+	case "text":
+		fmt.Print(canMakeString(ranked))
+	default:
+		return fmt.Errorf("unknown format %s, expected text or json", *format)
+	}
+	return nil
+}
+
+//canMakeState is the open what can I make screen of the CUI
+type canMakeState struct {
+	db      *sql.DB
+	options backend.CoverageOptions
+	results string // ranked recipes for options
+}
+
+//refresh ranks the recipes again after the options change
+func (c *canMakeState) refresh() {
+	ranked, err := rankRecipes(c.db, c.options, nil)
+	if err != nil {
+		c.results = fmt.Sprintln("Error ranking recipes:", err)
+		return
+	}
+	c.results = canMakeString(ranked)
+}
+
+//canMake is the open what can I make screen, nil when it is closed
+var canMake *canMakeState
+
+//what can I make view names
+const (
+	canMakeTagsView   = "canMakeTags"
+	canMakeResultView = "canMakeResults"
+)
+
+//openCanMake shows recipes ranked by inventory coverage over the main view
+func openCanMake(gui *gocui.Gui, db *sql.DB) error {
+	if screenOpen() {
+		return nil
+	}
+	canMake = &canMakeState{db: db, options: backend.CoverageOptions{MaxMissing: 2}}
+	canMake.refresh()
+	if err := canMakeLayout(gui); err != nil {
+		return err
+	}
+	bindings := []struct {
+		key     interface{}
+		handler func(*gocui.Gui, *gocui.View) error
+	}{
+		{gocui.KeyEnter, canMakeApplyTags},
+		{gocui.KeyCtrlS, canMakeToggleStaples},
+		{gocui.KeyCtrlN, canMakeToggleNearMisses},
+		{gocui.KeyEsc, closeCanMake},
+	}
+	for _, binding := range bindings {
+		if err := gui.SetKeybinding(canMakeTagsView, binding.key, gocui.ModNone, binding.handler); err != nil {
+			return err
+		}
+	}
+	gui.Cursor = true
+	_, err := gui.SetCurrentView(canMakeTagsView)
+	return err
+}
+
+//canMakeLayout creates or resizes the what can I make views
+func canMakeLayout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	tagsView, err := gui.SetView(canMakeTagsView, 0, 0, maxX-1, 2, 0)
+	if err != nil {
+		if !gocui.IsUnknownView(err) {
+			return err
+		}
+		tagsView.Editable = true
+		tagsView.Title = "Only recipes with these tags or diets, separated by commas"
+	}
+
+	resultView, err := gui.SetView(canMakeResultView, 0, 3, maxX-1, maxY-3, 0)
+	if err != nil {
+		if !gocui.IsUnknownView(err) {
+			return err
+		}
+		resultView.Wrap = true
+	}
+	resultView.Title = "What can I make"
+	if canMake.options.IgnoreStaples {
+		resultView.Title += ", ignoring staples"
+	}
+	if canMake.options.MaxMissing < 0 {
+		resultView.Title += ", all recipes"
+	}
+	resultView.Clear()
+	fmt.Fprint(resultView, canMake.results)
+	return nil
+}
+
+//canMakeApplyTags filters the recipes by the tags and diets typed in. Names
+//of diets, such as vegan, filter by diet rather than by tag.
+func canMakeApplyTags(gui *gocui.Gui, view *gocui.View) error {
+	canMake.options.Tags = nil
+	canMake.options.Diets = nil
+	for _, tag := range splitTags(viewText(view)) {
+		if diet, err := backend.ParseDiet(tag); err == nil {
+			canMake.options.Diets = append(canMake.options.Diets, diet)
+		} else {
+			canMake.options.Tags = append(canMake.options.Tags, tag)
+		}
+	}
+	canMake.refresh()
+	return canMakeLayout(gui)
+}
+
+func canMakeToggleStaples(gui *gocui.Gui, _ *gocui.View) error {
+	canMake.options.IgnoreStaples = !canMake.options.IgnoreStaples
+	canMake.refresh()
+	return canMakeLayout(gui)
+}
+
+//canMakeToggleNearMisses switches between near misses only and every recipe
+func canMakeToggleNearMisses(gui *gocui.Gui, _ *gocui.View) error {
+	if canMake.options.MaxMissing < 0 {
+		canMake.options.MaxMissing = 2
+	} else {
+		canMake.options.MaxMissing = -1
+	}
+	canMake.refresh()
+	return canMakeLayout(gui)
+}
+
+//closeCanMake returns to the main view
+func closeCanMake(gui *gocui.Gui, _ *gocui.View) error {
+	for _, name := range []string{canMakeTagsView, canMakeResultView} {
+		gui.DeleteKeybindings(name)
+		if err := gui.DeleteView(name); err != nil {
+			return err
+		}
+	}
+	canMake = nil
+	gui.Cursor = false
+	_, err := gui.SetCurrentView("main")
+	return err
+}
@@ -0,0 +1,234 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//Categories of catalog ingredients, roughly the aisles of a grocery store
+const (
+	CategoryProduce   = "produce"
+	CategoryDairy     = "dairy"
+	CategoryMeat      = "meat"
+	CategorySeafood   = "seafood"
+	CategoryBakery    = "bakery"
+	CategoryBaking    = "baking"
+	CategorySpices    = "spices"
+	CategoryPantry    = "pantry"
+	CategoryFrozen    = "frozen"
+	CategoryBeverages = "beverages"
+	CategoryOther     = "other"
+)
+
+//Categories lists every catalog category
+var Categories = []string{CategoryProduce, CategoryDairy, CategoryMeat, CategorySeafood,
+	CategoryBakery, CategoryBaking, CategorySpices, CategoryPantry, CategoryFrozen,
+	CategoryBeverages, CategoryOther}
+
+//ParseCategory reads a category name. An empty string is no category.
+func ParseCategory(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	switch s {
+	case "vegetables", "fruit", "veg":
+		return CategoryProduce, nil
+	case "spice", "herbs", "seasoning":
+		return CategorySpices, nil
+	case "drinks", "beverage":
+		return CategoryBeverages, nil
+	}
+	for _, category := range Categories {
+		if s == category {
+			return category, nil
+		}
+	}
+	return "", fmt.Errorf("unknown category %q, expected one of %s", s, strings.Join(Categories, ", "))
+}
+
+//A CatalogIngredient is the identity of an ingredient, shared by every
+//recipe that uses it, as opposed to an Ingredient which is the amount of it
+//used by one recipe.
+type CatalogIngredient struct {
+	ID          int      // id of ingredient in catalog
+	Name        string   // canonical name, as returned by CanonicalName
+	Plural      string   // plural of name when it is not the usual english plural
+	Category    string   // one of Categories, empty if unknown
+	DefaultUnit string   // unit used when a recipe gives an amount without one
+	Aliases     []string // other names for the ingredient
+	// what the ingredient contains that diets rule out, classified from
+	// its names unless ContainsOverride is set
+	Contains         []string
+	ContainsOverride bool
+}
+
+//NewCatalogIngredient returns a catalog entry for the ingredient called name
+func NewCatalogIngredient(name string) CatalogIngredient {
+	entry := CatalogIngredient{Name: CanonicalName(name)}
+	entry.Classify()
+	return entry
+}
+
+func (c CatalogIngredient) String() string {
+	stringString := c.Name
+	var details []string
+	if c.Category != "" {
+		details = append(details, c.Category)
+	}
+	if c.DefaultUnit != "" {
+		details = append(details, "default unit "+c.DefaultUnit)
+	}
+	if len(details) > 0 {
+		stringString += " (" + strings.Join(details, ", ") + ")"
+	}
+	stringString += "\n"
+	if c.Plural != "" {
+		stringString += fmt.Sprintf("\tplural: %s\n", c.Plural)
+	}
+	if len(c.Aliases) > 0 {
+		stringString += fmt.Sprintf("\taliases: %s\n", strings.Join(c.Aliases, ", "))
+	}
+	if len(c.Contains) > 0 || c.ContainsOverride {
+		contains := strings.Join(c.Contains, ", ")
+		if contains == "" {
+			contains = "nothing"
+		}
+		if c.ContainsOverride {
+			contains += " (set by hand)"
+		}
+		stringString += fmt.Sprintf("\tcontains: %s\n", contains)
+	}
+	return stringString
+}
+
+//PluralName returns the plural of the ingredient name
+func (c CatalogIngredient) PluralName() string {
+	if c.Plural != "" {
+		return c.Plural
+	}
+	words := strings.Fields(c.Name)
+	if len(words) == 0 {
+		return ""
+	}
+	words[len(words)-1] = pluralWord(words[len(words)-1])
+	return strings.Join(words, " ")
+}
+
+//AddAlias adds another name for the ingredient, unless it is already one of
+//its names
+func (c *CatalogIngredient) AddAlias(alias string) {
+	alias = normalizeName(alias)
+	if alias == "" || c.Matches(alias) {
+		return
+	}
+	c.Aliases = append(c.Aliases, alias)
+	sort.Strings(c.Aliases)
+	c.Classify()
+}
+
+//Matches reports whether name is the name, plural or one of the aliases of
+//the ingredient
+func (c CatalogIngredient) Matches(name string) bool {
+	name = normalizeName(name)
+	if name == normalizeName(c.Name) || (c.Plural != "" && name == normalizeName(c.Plural)) {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if name == normalizeName(alias) {
+			return true
+		}
+	}
+	return false
+}
+
+//Register makes the plural and aliases of the ingredient other names for it
+//in CanonicalName, so recipes and inventory using any of them are matched
+func (c CatalogIngredient) Register() {
+	if c.Plural != "" {
+		AddIngredientAlias(c.Plural, c.Name)
+	}
+	for _, alias := range c.Aliases {
+		AddIngredientAlias(alias, c.Name)
+	}
+}
+
+//Merge folds other into the ingredient: the name, plural and aliases of
+//other become aliases, and the category, default unit and what it contains
+//are kept from other when the ingredient has none set.
+func (c *CatalogIngredient) Merge(other CatalogIngredient) {
+	c.AddAlias(other.Name)
+	if other.Plural != "" {
+		c.AddAlias(other.Plural)
+	}
+	for _, alias := range other.Aliases {
+		c.AddAlias(alias)
+	}
+	if c.Category == "" {
+		c.Category = other.Category
+	}
+	if c.DefaultUnit == "" {
+		c.DefaultUnit = other.DefaultUnit
+	}
+	if !c.ContainsOverride && other.ContainsOverride {
+		c.SetContains(other.Contains)
+	}
+}
+
+//FindCatalogIngredient returns the entry of catalog for the ingredient name,
+//matching its name, plural and aliases first, then its canonical name
+func FindCatalogIngredient(name string, catalog []CatalogIngredient) (CatalogIngredient, bool) {
+	for _, entry := range catalog {
+		if entry.Matches(name) {
+			return entry, true
+		}
+	}
+	canonical := CanonicalName(name)
+	for _, entry := range catalog {
+		if CanonicalName(entry.Name) == canonical {
+			return entry, true
+		}
+	}
+	return CatalogIngredient{}, false
+}
+
+//DuplicateGroups finds entries of catalog that are the same ingredient,
+//because they have the same canonical name or one is an alias of another.
+//The first entry of each group is the one to keep: the entry whose name is
+//the canonical name, or else the one with the lowest id.
+func DuplicateGroups(catalog []CatalogIngredient) [][]CatalogIngredient {
+	groups := make(map[string][]CatalogIngredient)
+	var order []string
+	for _, entry := range catalog {
+		key := CanonicalName(entry.Name)
+		// an entry named after an alias of another joins the group of the
+		// entry it is an alias of
+		for _, other := range catalog {
+			if other.ID != entry.ID && other.Matches(entry.Name) && !entry.Matches(other.Name) {
+				key = CanonicalName(other.Name)
+				break
+			}
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], entry)
+	}
+	var duplicates [][]CatalogIngredient
+	for _, key := range order {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(a, b int) bool {
+			keepA, keepB := group[a].Name == key, group[b].Name == key
+			if keepA != keepB {
+				return keepA
+			}
+			return group[a].ID < group[b].ID
+		})
+		duplicates = append(duplicates, group)
+	}
+	return duplicates
+}
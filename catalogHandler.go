@@ -0,0 +1,534 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const catalogUsage = `usage: cookbook catalog <command> [arguments]
+
+commands:
+	list [-category <category>]       list the ingredients in the catalog
+	show <ingredient>                 show one ingredient of the catalog
+	add [entry flags] <ingredient>    add an ingredient to the catalog
+	set [entry flags] <ingredient>    change an ingredient of the catalog
+	alias <ingredient> <alias> ...    add other names for an ingredient
+	merge <ingredient> <duplicate> ...
+		merge duplicates into ingredient, their names become aliases of it
+	dedupe [-apply]
+		link recipe ingredients to the catalog and list the entries that are
+		the same ingredient, with -apply they are merged
+
+entry flags:
+	-plural <plural>       plural of the name, when not the usual english plural
+	-category <category>   one of produce, dairy, meat, seafood, bakery, baking,
+	                       spices, pantry, frozen, beverages or other
+	-unit <unit>           unit used when a recipe gives an amount without one
+	-alias <aliases>       comma separated other names for the ingredient
+
+Recipe ingredients are added to the catalog when a recipe is saved.
+`
+
+//catalogCommand runs the catalog subcommand given on the command line
+func catalogCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(catalogUsage)
+		return errors.New("no catalog command given")
+	}
+	switch args[0] {
+	case "list":
+		flags := flag.NewFlagSet("catalog list", flag.ContinueOnError)
+		category := flags.String("category", "", "Only list ingredients in category")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		wanted, err := backend.ParseCategory(*category)
+		if err != nil {
+			return err
+		}
+		catalog, err := selectCatalog(db)
+		if err != nil {
+			return err
+		}
+		for _, entry := range catalog {
+			if wanted == "" || entry.Category == wanted {
+				fmt.Printf("%d) %s", entry.ID, entry)
+			}
+		}
+		return nil
+	case "show":
+		if len(args) < 2 {
+			return errors.New("usage: catalog show <ingredient>")
+		}
+		catalog, err := selectCatalog(db)
+		if err != nil {
+			return err
+		}
+		entry, ok := backend.FindCatalogIngredient(args[1], catalog)
+		if !ok {
+			return fmt.Errorf("%s is not in the catalog", args[1])
+		}
+		fmt.Printf("%d) %s", entry.ID, entry)
+		var count int
+		err = db.QueryRow("SELECT COUNT(DISTINCT ingredient_recipe.recipeID) FROM ingredients "+
+			"INNER JOIN ingredient_recipe ON ingredients.id = ingredient_recipe.ingredientID "+
+			"WHERE ingredients.catalogID = ?", entry.ID).Scan(&count)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\tused by %d recipes\n", count)
+		return nil
+	case "add", "set":
+		flags := flag.NewFlagSet("catalog "+args[0], flag.ContinueOnError)
+		plural := flags.String("plural", "", "Plural of the name")
+		category := flags.String("category", "", "Category of the ingredient")
+		unit := flags.String("unit", "", "Unit used when a recipe gives none")
+		aliases := flags.String("alias", "", "Comma separated other names")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			fmt.Print(catalogUsage)
+			return errors.New("catalog " + args[0] + " needs one ingredient name")
+		}
+		parsedCategory, err := backend.ParseCategory(*category)
+		if err != nil {
+			return err
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		catalog, err := newCatalogCache(tx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		entry, found := backend.FindCatalogIngredient(flags.Arg(0), catalog.entries)
+		switch {
+		case args[0] == "add" && found:
+			tx.Rollback()
+			return fmt.Errorf("%s is already in the catalog as %s", flags.Arg(0), entry.Name)
+		case args[0] == "set" && !found:
+			tx.Rollback()
+			return fmt.Errorf("%s is not in the catalog", flags.Arg(0))
+		case !found:
+			entry = backend.NewCatalogIngredient(flags.Arg(0))
+		}
+		// only the flags given change an existing entry
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "plural":
+				entry.Plural = strings.TrimSpace(*plural)
+			case "category":
+				entry.Category = parsedCategory
+			case "unit":
+				entry.DefaultUnit = strings.TrimSpace(*unit)
+			case "alias":
+				for _, alias := range strings.Split(*aliases, ",") {
+					entry.AddAlias(alias)
+				}
+			}
+		})
+		if found {
+			err = updateCatalogTx(tx, entry)
+		} else {
+			var id int64
+			id, err = insertCatalogTx(tx, entry)
+			entry.ID = int(id)
+		}
+		if err == nil {
+			_, err = recomputeDietsTx(tx)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		fmt.Printf("%d) %s", entry.ID, entry)
+		return nil
+	case "alias":
+		if len(args) < 3 {
+			return errors.New("usage: catalog alias <ingredient> <alias> ...")
+		}
+		catalog, err := selectCatalog(db)
+		if err != nil {
+			return err
+		}
+		entry, ok := backend.FindCatalogIngredient(args[1], catalog)
+		if !ok {
+			return fmt.Errorf("%s is not in the catalog", args[1])
+		}
+		for _, alias := range args[2:] {
+			if other, ok := backend.FindCatalogIngredient(alias, catalog); ok && other.ID != entry.ID &&
+				other.Matches(alias) {
+				return fmt.Errorf("%s is already a name of %s, merge them instead", alias, other.Name)
+			}
+			entry.AddAlias(alias)
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err = updateCatalogTx(tx, entry); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err = recomputeDietsTx(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		fmt.Printf("%d) %s", entry.ID, entry)
+		return nil
+	case "merge":
+		if len(args) < 3 {
+			return errors.New("usage: catalog merge <ingredient> <duplicate> ...")
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		entry, err := mergeCatalogNamesTx(tx, args[1], args[2:])
+		if err == nil {
+			_, err = recomputeDietsTx(tx)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		fmt.Printf("%d) %s", entry.ID, entry)
+		return nil
+	case "dedupe":
+		flags := flag.NewFlagSet("catalog dedupe", flag.ContinueOnError)
+		apply := flags.Bool("apply", false, "Merge the duplicates found")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		return dedupeCatalog(db, *apply)
+	}
+	fmt.Print(catalogUsage)
+	return errors.New("unknown catalog command " + args[0])
+}
+
+//selectCatalog reads every ingredient of the catalog, with its aliases,
+//sorted by name
+func selectCatalog(q queryer) ([]backend.CatalogIngredient, error) {
+	rows, err := q.Query("SELECT ingredientCatalog.id, ingredientCatalog.name, " +
+		"IFNULL(ingredientCatalog.plural, ''), IFNULL(ingredientCatalog.category, ''), " +
+		"IFNULL(units.name, '') FROM ingredientCatalog " +
+		"LEFT JOIN units ON ingredientCatalog.defaultUnit = units.id ORDER BY ingredientCatalog.name")
+	if err != nil {
+		return nil, err
+	}
+	var catalog []backend.CatalogIngredient
+	index := make(map[int]int)
+	for rows.Next() {
+		var entry backend.CatalogIngredient
+		err = rows.Scan(&entry.ID, &entry.Name, &entry.Plural, &entry.Category, &entry.DefaultUnit)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[entry.ID] = len(catalog)
+		catalog = append(catalog, entry)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query("SELECT catalogID, alias FROM ingredientCatalogAliases ORDER BY alias")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var catalogID int
+		var alias string
+		if err = rows.Scan(&catalogID, &alias); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if i, ok := index[catalogID]; ok {
+			catalog[i].Aliases = append(catalog[i].Aliases, alias)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range catalog {
+		catalog[i].Classify()
+	}
+	rows, err = q.Query("SELECT catalogID, IFNULL(contains, '') FROM ingredientContains")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var catalogID int
+		var contains string
+		if err = rows.Scan(&catalogID, &contains); err != nil {
+			return nil, err
+		}
+		if i, ok := index[catalogID]; ok {
+			// an empty override means the ingredient contains nothing
+			catalog[i].SetContains(append([]string{}, splitTags(contains)...))
+		}
+	}
+	return catalog, rows.Err()
+}
+
+//registerCatalog makes the plurals and aliases in the catalog other names
+//for their ingredients, so they are matched everywhere
+func registerCatalog(db *sql.DB) error {
+	catalog, err := selectCatalog(db)
+	if err != nil {
+		return err
+	}
+	for _, entry := range catalog {
+		entry.Register()
+	}
+	return nil
+}
+
+//A catalogCache holds the catalog while recipe ingredients are linked to it
+//in a transaction, adding ingredients that are not in it yet
+type catalogCache struct {
+	tx      *sql.Tx
+	entries []backend.CatalogIngredient
+}
+
+func newCatalogCache(tx *sql.Tx) (*catalogCache, error) {
+	entries, err := selectCatalog(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &catalogCache{tx, entries}, nil
+}
+
+//entry returns the catalog entry for the ingredient name, adding a new one
+//if there is none
+func (c *catalogCache) entry(name string) (backend.CatalogIngredient, error) {
+	if entry, ok := backend.FindCatalogIngredient(name, c.entries); ok {
+		return entry, nil
+	}
+	entry := backend.NewCatalogIngredient(name)
+	id, err := insertCatalogTx(c.tx, entry)
+	if err != nil {
+		return entry, err
+	}
+	entry.ID = int(id)
+	c.entries = append(c.entries, entry)
+	return entry, nil
+}
+
+//insertCatalogTx stores a new catalog entry and its aliases as part of tx
+//and returns its id
+func insertCatalogTx(tx *sql.Tx, entry backend.CatalogIngredient) (int64, error) {
+	unit, err := unitID(tx, entry.DefaultUnit)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("INSERT INTO ingredientCatalog (name, plural, category, defaultUnit) "+
+		"VALUES (?, ?, ?, ?)", entry.Name, entry.Plural, entry.Category, unit)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	entry.ID = int(id)
+	if err = saveCatalogContainsTx(tx, entry); err != nil {
+		return 0, err
+	}
+	return id, saveCatalogAliasesTx(tx, entry)
+}
+
+//updateCatalogTx saves the changes to an existing catalog entry as part of
+//tx
+func updateCatalogTx(tx *sql.Tx, entry backend.CatalogIngredient) error {
+	unit, err := unitID(tx, entry.DefaultUnit)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE ingredientCatalog SET name = ?, plural = ?, category = ?, defaultUnit = ? "+
+		"WHERE id = ?", entry.Name, entry.Plural, entry.Category, unit, entry.ID)
+	if err != nil {
+		return err
+	}
+	if err = saveCatalogContainsTx(tx, entry); err != nil {
+		return err
+	}
+	return saveCatalogAliasesTx(tx, entry)
+}
+
+//saveCatalogContainsTx stores what a catalog entry contains when it was set
+//by hand. Entries classified from their names are not stored.
+func saveCatalogContainsTx(tx *sql.Tx, entry backend.CatalogIngredient) error {
+	_, err := tx.Exec("DELETE FROM ingredientContains WHERE catalogID = ?", entry.ID)
+	if err != nil || !entry.ContainsOverride {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO ingredientContains (catalogID, contains) VALUES (?, ?)",
+		entry.ID, strings.Join(entry.Contains, ","))
+	return err
+}
+
+//saveCatalogAliasesTx replaces the aliases stored for a catalog entry
+func saveCatalogAliasesTx(tx *sql.Tx, entry backend.CatalogIngredient) error {
+	_, err := tx.Exec("DELETE FROM ingredientCatalogAliases WHERE catalogID = ?", entry.ID)
+	if err != nil {
+		return err
+	}
+	for _, alias := range entry.Aliases {
+		_, err = tx.Exec("INSERT OR REPLACE INTO ingredientCatalogAliases (alias, catalogID) VALUES (?, ?)",
+			alias, entry.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//mergeCatalogTx merges the catalog entry from into into as part of tx.
+//Recipe ingredients linked to from are linked to into, and from is
+//deleted.
+func mergeCatalogTx(tx *sql.Tx, into *backend.CatalogIngredient, from backend.CatalogIngredient) error {
+	if into.ID == from.ID {
+		return fmt.Errorf("cannot merge %s into itself", from.Name)
+	}
+	into.Merge(from)
+	_, err := tx.Exec("UPDATE ingredients SET catalogID = ? WHERE catalogID = ?", into.ID, from.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM ingredientCatalogAliases WHERE catalogID = ?", from.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM ingredientContains WHERE catalogID = ?", from.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM ingredientCatalog WHERE id = ?", from.ID)
+	if err != nil {
+		return err
+	}
+	return updateCatalogTx(tx, *into)
+}
+
+//mergeCatalogNamesTx merges the catalog entries named duplicates into the
+//entry named name
+func mergeCatalogNamesTx(tx *sql.Tx, name string, duplicates []string) (backend.CatalogIngredient, error) {
+	catalog, err := selectCatalog(tx)
+	if err != nil {
+		return backend.CatalogIngredient{}, err
+	}
+	into, ok := backend.FindCatalogIngredient(name, catalog)
+	if !ok {
+		return into, fmt.Errorf("%s is not in the catalog", name)
+	}
+	for _, duplicate := range duplicates {
+		from, ok := backend.FindCatalogIngredient(duplicate, catalog)
+		if !ok {
+			return into, fmt.Errorf("%s is not in the catalog", duplicate)
+		}
+		if err = mergeCatalogTx(tx, &into, from); err != nil {
+			return into, err
+		}
+	}
+	return into, nil
+}
+
+//dedupeCatalog links every recipe ingredient not yet in the catalog, then
+//lists the catalog entries that are the same ingredient. With apply the
+//changes are saved and the duplicates merged, otherwise nothing is changed.
+func dedupeCatalog(db *sql.DB, apply bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	catalog, err := newCatalogCache(tx)
+	if err != nil {
+		return err
+	}
+
+	type unlinked struct {
+		id   int
+		name string
+	}
+	var ingredients []unlinked
+	rows, err := tx.Query("SELECT id, name FROM ingredients WHERE catalogID IS NULL OR catalogID = 0")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var ingredient unlinked
+		var name sql.NullString
+		if err = rows.Scan(&ingredient.id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		ingredient.name = name.String
+		ingredients = append(ingredients, ingredient)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for _, ingredient := range ingredients {
+		entry, err := catalog.entry(ingredient.name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE ingredients SET catalogID = ? WHERE id = ?", entry.ID, ingredient.id)
+		if err != nil {
+			return err
+		}
+	}
+	if len(ingredients) > 0 {
+		fmt.Printf("Linked %d recipe ingredients to the catalog\n", len(ingredients))
+	}
+
+	groups := backend.DuplicateGroups(catalog.entries)
+	if len(groups) == 0 {
+		fmt.Println("No duplicate ingredients found")
+	}
+	for _, group := range groups {
+		into := group[0]
+		var names []string
+		for _, from := range group[1:] {
+			names = append(names, from.Name)
+			if err = mergeCatalogTx(tx, &into, from); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("%s: %s\n", into.Name, strings.Join(names, ", "))
+	}
+	if !apply {
+		if len(groups) > 0 || len(ingredients) > 0 {
+			fmt.Println("Nothing changed, run with -apply to save")
+		}
+		return nil
+	}
+	if _, err = recomputeDietsTx(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
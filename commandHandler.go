@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const commandUsage = `usage: cookbook [flags] [command] [arguments]
+
+commands:
+	recipes     list and search recipes, filtered by tags and diets
+	inventory   manage kitchen inventory
+	products    import and search the offline product catalog
+	cook        use a recipe's ingredients from inventory and log it as made
+	shop        build a shopping list for recipes, minus inventory on hand
+	canmake     rank recipes by how much of their ingredients are in inventory
+	subs        manage ingredient substitutions
+	catalog     manage the ingredient catalog and merge duplicate ingredients
+	plan        plan meals by date, shop for them and export them to a calendar
+	nutrition   import FoodData Central nutrition data and show recipe nutrition
+	cost        track prices and show recipe costs and spending
+	diet        classify ingredients and show the diets recipes suit
+	household   manage household members' allergies, intolerances and dislikes
+	journal     log cooking with ratings and adjustments, and promote revisions
+	suggest     suggest recipes to make, with why, or pick one at random
+	menu        plan multi-course menus for guests, with shopping, equipment and a menu card
+
+Run cookbook <command> with no arguments for help with a command.
+Run cookbook -h for a list of flags.
+`
+
+//runCommand runs the subcommand given after the flags on the command line
+func runCommand(db *sql.DB, args []string) error {
+	switch args[0] {
+	case "recipes":
+		return recipesCommand(db, args[1:])
+	case "inventory":
+		return inventoryCommand(db, args[1:])
+	case "products":
+		return productCommand(db, args[1:])
+	case "cook":
+		return cookCommand(db, args[1:])
+	case "shop":
+		return shopCommand(db, args[1:])
+	case "canmake":
+		return canMakeCommand(db, args[1:])
+	case "subs":
+		return substitutionCommand(db, args[1:])
+	case "catalog":
+		return catalogCommand(db, args[1:])
+	case "plan":
+		return mealPlanCommand(db, args[1:])
+	case "nutrition":
+		return nutritionCommand(db, args[1:])
+	case "cost":
+		return costCommand(db, args[1:])
+	case "diet":
+		return dietCommand(db, args[1:])
+	case "household":
+		return householdCommand(db, args[1:])
+	case "journal":
+		return journalCommand(db, args[1:])
+	case "suggest":
+		return suggestCommand(db, args[1:])
+	case "menu":
+		return menuCommand(db, args[1:])
+	case "help":
+		fmt.Print(commandUsage)
+		return nil
+	}
+	fmt.Print(commandUsage)
+	return errors.New("unknown command " + args[0])
+}
@@ -10,15 +10,32 @@ import (
 type Configuration struct {
 	IPConfig       string `json:"ipconfig"`
 	RecipeDatabase string `json:"recipedatabase"`
+	RoundMetric    bool   `json:"roundmetric"` // round metric quantities when displayed
+	//preferred measurement system for display, metric or imperial
+	MeasurementSystem string `json:"measurementsystem"`
+	//preferred temperature scale for display, C or F
+	TemperatureScale string `json:"temperaturescale"`
+	//ingredients to ignore when asked to ignore pantry staples, the
+	//default list if not set
+	PantryStaples []string `json:"pantrystaples"`
+	//other names for ingredients, mapped to the name to use for them
+	IngredientAliases map[string]string `json:"ingredientaliases"`
+	//use southern hemisphere seasons when suggesting recipes
+	SouthernHemisphere bool `json:"southernhemisphere"`
+	//ovens in the kitchen, for finding menu dishes that need the oven at
+	//different temperatures, 1 if not set
+	Ovens int `json:"ovens"`
 	//not stored, only used internally
 }
 
-func readConfig(filename string) (Configuration, error) {
+//readConfig reads a config file. Settings missing from the file keep their
+//value from defaults.
+func readConfig(filename string, defaults Configuration) (Configuration, error) {
 	bytes, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return Configuration{}, err
 	}
-	var config Configuration
+	config := defaults
 	err = json.Unmarshal(bytes, &config)
 
 	if err != nil {
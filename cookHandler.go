@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+//inventoryChange records how many packages of an inventory item were used
+//so that cooking a recipe can be undone
+type inventoryChange struct {
+	InventoryID int
+	Name        string
+	Used        backend.Fraction         // packages used
+	LotsUsed    map[int]backend.Fraction // packages used from each lot, by lot id
+}
+
+//cookRecord is everything changed in the database by cooking a recipe once
+type cookRecord struct {
+	RecipeName string
+	LastMadeID int64
+	Changes    []inventoryChange
+	Leftovers  *leftoverChange // leftovers kept in inventory, nil if none
+	Warnings   []string        // shortfalls and ingredients not linked to inventory
+}
+
+//cookHistory holds every recipe cooked this session, most recent last, so
+//they can be undone
+var cookHistory []cookRecord
+
+//cookCommand runs the cook subcommand given on the command line
+func cookCommand(db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("cook", flag.ContinueOnError)
+	scale := flags.Float64("s", 1, "Factor to scale recipe by")
+	yield := flags.String("y", "", "Target yield to scale recipe to, ie: \"3 loaves\"")
+	journalFlags := addJournalFlags(flags)
+	leftoverFlags := addLeftoverFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: cookbook cook [flags] <recipe name>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no recipe given to cook")
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	recipe, err := chooseRecipe(db, strings.Join(flags.Args(), " "), reader)
+	if err != nil {
+		return err
+	}
+	recipe, err = scaleRecipe(recipe, *scale, *yield)
+	if err != nil {
+		return err
+	}
+	entry, err := journalFlags.entry(recipe)
+	if err != nil {
+		return err
+	}
+	leftovers, err := leftoverFlags.request(recipe)
+	if err != nil {
+		return err
+	}
+
+	record, err := cookRecipe(db, recipe, entry, leftovers)
+	if err != nil {
+		return err
+	}
+	fmt.Print(record.String())
+	if *leftoverFlags.label && record.Leftovers != nil {
+		fmt.Print(record.Leftovers.Label.String())
+	}
+
+	fmt.Print("Enter u to undo, or press enter to finish: ")
+	tempString, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(strings.ToLower(tempString)) == "u" {
+		undone, err := undoLastCook(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Undid cooking %s\n", undone.RecipeName)
+	}
+	return nil
+}
+
+func (c cookRecord) String() string {
+	stringString := fmt.Sprintf("Cooked %s\n", c.RecipeName)
+	for _, change := range c.Changes {
+		stringString += fmt.Sprintf("\tUsed %s packages of %s\n", change.Used.DecimalString(2), change.Name)
+	}
+	if c.Leftovers != nil {
+		stringString += fmt.Sprintf("\tKept %s of leftovers", c.Leftovers.Label.Amount.Format())
+		if !c.Leftovers.Label.UseBy.IsZero() {
+			stringString += ", use by " + c.Leftovers.Label.UseBy.Format(backend.DateFormat)
+		}
+		stringString += "\n"
+	}
+	for _, warning := range c.Warnings {
+		stringString += fmt.Sprintf("\tWarning: %s\n", warning)
+	}
+	return stringString
+}
+
+//cookRecipe deducts every ingredient of recipe from the inventory items
+//linked to it and records the recipe as made in the journal, all in one
+//transaction. recipe should already be scaled to the amount being made.
+//Ingredients that are short or not linked to inventory are reported as
+//warnings rather than errors. Ingredients not linked to inventory are taken
+//from leftovers of the same name, and any leftovers requested are kept as
+//a new lot of inventory.
+func cookRecipe(db *sql.DB, recipe backend.Recipe, entry backend.JournalEntry,
+	leftovers leftoverRequest) (cookRecord, error) {
+	record := cookRecord{RecipeName: recipe.Name}
+	tx, err := db.Begin()
+	if err != nil {
+		return record, err
+	}
+
+	for _, ingredient := range recipe.Ingredients {
+		items, err := linkedInventory(tx, ingredient.ID)
+		if err != nil {
+			tx.Rollback()
+			return record, err
+		}
+		if len(items) == 0 {
+			if items, err = leftoverInventory(tx, ingredient.Name); err != nil {
+				tx.Rollback()
+				return record, err
+			}
+		}
+		if len(items) == 0 {
+			record.Warnings = append(record.Warnings,
+				fmt.Sprintf("%s is not linked to any inventory", ingredient.Name))
+			continue
+		}
+		changes, short, warnings, err := consumeItemsTx(tx, items, ingredient.QuantityNeeded)
+		if err != nil {
+			tx.Rollback()
+			return record, fmt.Errorf("could not use %s: %s", ingredient.Name, err)
+		}
+		record.Changes = append(record.Changes, changes...)
+		for _, warning := range warnings {
+			record.Warnings = append(record.Warnings, fmt.Sprintf("could not use %s from %s",
+				ingredient.Name, warning))
+		}
+		if !short.IsZero() {
+			record.Warnings = append(record.Warnings, fmt.Sprintf("short %s of %s",
+				short.Format(), ingredient.Name))
+		}
+	}
+
+	if err = insertJournalTx(tx, &entry); err != nil {
+		tx.Rollback()
+		return record, err
+	}
+	record.LastMadeID = int64(entry.ID)
+	for _, change := range record.Changes {
+		usage := backend.UsageRecord{Date: entry.Date, Packages: change.Used, Reason: "cook"}
+		if err = recordUsageTx(tx, change.InventoryID, usage, record.LastMadeID); err != nil {
+			tx.Rollback()
+			return record, err
+		}
+	}
+	if !leftovers.Amount.IsZero() {
+		change, err := storeLeftoversTx(tx, recipe, leftovers, entry.Date)
+		if err != nil {
+			tx.Rollback()
+			return record, fmt.Errorf("could not keep leftovers: %s", err)
+		}
+		record.Leftovers = &change
+	}
+	if err = tx.Commit(); err != nil {
+		return record, err
+	}
+	cookHistory = append(cookHistory, record)
+	return record, nil
+}
+
+//consumeItemsTx takes amount from items in order, moving on to the next item
+//once one runs out, and returns the packages used from each along with the
+//part of amount that was not in stock. Items that can not measure amount are
+//skipped and returned as warnings, only database errors are returned as
+//errors.
+func consumeItemsTx(tx *sql.Tx, items []backend.InventoryItem, amount backend.Quantity) ([]inventoryChange, backend.Quantity, []string, error) {
+	var changes []inventoryChange
+	var warnings []string
+	remaining := amount
+	for i := range items {
+		if remaining.IsZero() {
+			break
+		}
+		item := items[i]
+		before := item.Quantity
+		lotsBefore := make(map[int]backend.Fraction)
+		for _, lot := range item.Lots {
+			lotsBefore[lot.ID] = lot.Quantity
+		}
+		short, err := item.Consume(remaining)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", item.Name, err))
+			continue
+		}
+		used := before.Sub(item.Quantity)
+		if !used.IsZero() {
+			if err = saveInventoryItemTx(tx, &item); err != nil {
+				return nil, remaining, warnings, err
+			}
+			change := inventoryChange{item.ID, item.Name, used, make(map[int]backend.Fraction)}
+			for _, lot := range item.Lots {
+				if lotUsed := lotsBefore[lot.ID].Sub(lot.Quantity); !lotUsed.IsZero() {
+					change.LotsUsed[lot.ID] = lotUsed
+				}
+			}
+			changes = append(changes, change)
+		}
+		// the shortfall is in the package units of this item, convert it
+		// back so the next item can be tried
+		if short.IsZero() {
+			remaining = short
+		} else if converted, err := short.Convert(remaining.Unit); err == nil {
+			remaining = converted
+		} else {
+			remaining = short
+		}
+	}
+	return changes, remaining, warnings, nil
+}
+
+//linkedInventory returns every inventory item linked to an ingredient
+func linkedInventory(tx *sql.Tx, ingredientID int) ([]backend.InventoryItem, error) {
+	return selectInventory(tx, "INNER JOIN ingredient_inventory ON "+
+		"inventory.id = ingredient_inventory.inventoryID "+
+		"WHERE ingredient_inventory.ingredientID = ? ORDER BY inventory.id", ingredientID)
+}
+
+//undoLastCook reverses the most recent cookRecipe of this session, putting
+//back the inventory it used, taking out the leftovers it kept and removing
+//its journal entry
+func undoLastCook(db *sql.DB) (cookRecord, error) {
+	if len(cookHistory) == 0 {
+		return cookRecord{}, errors.New("nothing has been cooked this session")
+	}
+	record := cookHistory[len(cookHistory)-1]
+	tx, err := db.Begin()
+	if err != nil {
+		return record, err
+	}
+	for _, change := range record.Changes {
+		var quantity backend.Fraction
+		err = tx.QueryRow("SELECT quantity FROM inventory WHERE id = ?", change.InventoryID).Scan(&quantity)
+		if err != nil {
+			tx.Rollback()
+			return record, err
+		}
+		_, err = tx.Exec("UPDATE inventory SET quantity = ? WHERE id = ?",
+			quantity.Add(change.Used), change.InventoryID)
+		if err != nil {
+			tx.Rollback()
+			return record, err
+		}
+		for lotID, used := range change.LotsUsed {
+			err = tx.QueryRow("SELECT quantity FROM inventoryLots WHERE id = ?", lotID).Scan(&quantity)
+			if err != nil {
+				tx.Rollback()
+				return record, err
+			}
+			_, err = tx.Exec("UPDATE inventoryLots SET quantity = ? WHERE id = ?", quantity.Add(used), lotID)
+			if err != nil {
+				tx.Rollback()
+				return record, err
+			}
+		}
+	}
+	if record.Leftovers != nil {
+		if err = undoLeftoversTx(tx, *record.Leftovers); err != nil {
+			tx.Rollback()
+			return record, err
+		}
+	}
+	_, err = tx.Exec("DELETE FROM inventoryUsage WHERE lastMadeID = ?", record.LastMadeID)
+	if err != nil {
+		tx.Rollback()
+		return record, err
+	}
+	err = deleteJournalTx(tx, int(record.LastMadeID))
+	if err != nil {
+		tx.Rollback()
+		return record, err
+	}
+	if err = tx.Commit(); err != nil {
+		return record, err
+	}
+	cookHistory = cookHistory[:len(cookHistory)-1]
+	return record, nil
+}
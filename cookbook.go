@@ -17,6 +17,8 @@ import (
 	"log"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/awesome-gocui/gocui"
 	backend "github.com/sww1235/recipe-database"
@@ -25,9 +27,16 @@ import (
 //Configuration stores the configuration that is read in and out from a file
 
 var viewedRecipe string
+var recipeScale float64
+var recipeYield string
 var addRecipeToggle bool
+var addBakersRecipeToggle bool
+var bakersMode bool
+var doughWeight string
 var httpServer bool
 var httpServerFlagIP string
+var statsFormat string
+var statsMonths int
 
 var config Configuration
 
@@ -43,13 +52,47 @@ func main() {
 	}
 
 	db := initDB(config.RecipeDatabase)
+	err = registerCatalog(db)
+	if err != nil {
+		fatalLogger.Panicln("Could not read ingredient catalog", err)
+	}
 
-	if viewedRecipe != "" {
-		err := displaySingleRecipe(viewedRecipe)
+	if flag.NArg() > 0 {
+		err := runCommand(db, flag.Args())
+		if err != nil {
+			// mistakes in a command are the user's, so report them without
+			// a stack trace
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			db.Close()
+			os.Exit(1)
+		}
+		finalize(db)
+	} else if viewedRecipe != "" {
+		err := displaySingleRecipe(db, viewedRecipe)
+		if err != nil {
+			fatalLogger.Panicf("%s not found in Recipes, check your spelling and capitilization. Err: %s\n",
+				viewedRecipe, err)
+		}
+		finalize(db)
+	} else if statsFormat != "" {
+		err := displayStats(db, statsFormat, statsMonths)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error showing statistics:", err)
+			db.Close()
+			os.Exit(1)
+		}
+		finalize(db)
+	} else if addBakersRecipeToggle {
+		//read in baker's percentages from commandline
+		tempRecipe, err := backend.ReadBakersRecipe()
 		if err != nil {
-			fatalLogger.Panicf("%s not found in Recipes, check your spelling and capitilization\n",
-				viewedRecipe)
+			fatalLogger.Panicln("Error reading baker's percentages from command line:", err)
 		}
+		err = insertRecipe(db, tempRecipe)
+		if err != nil {
+			fatalLogger.Panicln("Error inserting new recipe into database:", err)
+		}
+		fmt.Print(tempRecipe.BakersString())
 		finalize(db)
 	} else if addRecipeToggle {
 		//read in recipe from commandline
@@ -65,12 +108,12 @@ func main() {
 		}
 		finalize(db)
 	} else if httpServer {
-		err := startHTTPServer()
+		err := startHTTPServer(db)
 		if err != nil {
 			fatalLogger.Panicln("Something went wrong with the HTTP server", err)
 		}
 	} else {
-		err := startCUI()
+		err := startCUI(db)
 		if err != nil && err != gocui.ErrQuit {
 			fatalLogger.Panicln("Something went wrong with the CUI", err)
 		}
@@ -112,6 +155,7 @@ func initialization() error {
 
 	//setting config to defaults
 	config.RecipeDatabase = defaultRecipeDatabase
+	config.RoundMetric = true
 
 	//Define and Parse commandline flags here
 	//Defaults are set in flags as appropriate
@@ -120,10 +164,19 @@ func initialization() error {
 		"and must be typed exactly. This flag is provided as a courtesy for " +
 		"scripting and people who can't run termbox"
 	flagViewedRecipe := flag.String("r", "", flagViewedRecipeUsage)
+	flagRecipeScale := flag.Float64("s", 1, "Factor to scale viewed recipe by")
+	flagRecipeYield := flag.String("y", "", "Target yield to scale viewed recipe to, ie: \"3 loaves\"")
 	flagRecipeDatabaseDir := flag.String("db", defaultRecipeDatabaseDir, "Directory to store recipe database")
 	flagAddRecipeToggle := flag.Bool("n", false, "Add new recipe")
+	flagAddBakersRecipeToggle := flag.Bool("nb", false, "Add new recipe from baker's percentages and a dough weight")
+	flagBakersMode := flag.Bool("b", false, "Show viewed recipe as baker's percentages")
+	flagDoughWeight := flag.String("w", "", "Total dough weight to scale viewed recipe to, ie: \"1800 g\"")
 	flagHTTPServer := flag.Bool("H", false, "Use HTTP server instead of terminal")
 	flagIPConfig := flag.String("ip", defaultServerIP, "IP to start HTTP server on")
+	flagMeasurementSystem := flag.String("units", "", "Measurement system to display quantities in, metric or imperial. Overrides config")
+	flagTemperatureScale := flag.String("temp", "", "Temperature scale to display temperatures in, C or F. Overrides config")
+	flagStats := flag.String("stats", "", "Show cookbook statistics as a table or json")
+	flagStatsMonths := flag.Int("months", 6, "List recipes not made in this many months in statistics")
 	flagDebugLogging := flag.Bool("D", false, "Show debug logs")
 	flag.Parse()
 
@@ -135,9 +188,16 @@ func initialization() error {
 	//Retrieve some values from flags and set global variables
 	//if these flags are not set, defaults will be set
 	viewedRecipe = *flagViewedRecipe
+	recipeScale = *flagRecipeScale
+	recipeYield = *flagRecipeYield
 	addRecipeToggle = *flagAddRecipeToggle
+	addBakersRecipeToggle = *flagAddBakersRecipeToggle
+	bakersMode = *flagBakersMode
+	doughWeight = *flagDoughWeight
 	httpServer = *flagHTTPServer
 	httpServerFlagIP = *flagIPConfig
+	statsFormat = *flagStats
+	statsMonths = *flagStatsMonths
 
 	if *flagConfigPath != defaultConfigPath {
 		infoLogger.Println("Using config file path from flag", *flagConfigPath)
@@ -145,10 +205,12 @@ func initialization() error {
 	}
 
 	//Attempt to read config
-	config, cfgErr := readConfig(*flagConfigPath)
+	fileConfig, cfgErr := readConfig(*flagConfigPath, config)
 	if cfgErr != nil {
 		infoLogger.Printf("config file not openable at path: %s. Err: %s. "+
 			"Using default configuration", *flagConfigPath, cfgErr)
+	} else {
+		config = fileConfig
 	}
 
 	//try flag recipeDatabaseDir
@@ -162,6 +224,37 @@ func initialization() error {
 		infoLogger.Printf("Using default recipeDir %s", defaultRecipeDatabaseDir)
 		config.RecipeDatabase = defaultRecipeDatabase
 	}
+
+	//the server address from the flag wins over the config file, unless it
+	//was left at the default
+	if httpServerFlagIP == defaultServerIP && config.IPConfig != "" {
+		httpServerFlagIP = config.IPConfig
+	}
+
+	for alias, name := range config.IngredientAliases {
+		backend.AddIngredientAlias(alias, name)
+	}
+
+	backend.Display.RoundMetric = config.RoundMetric
+
+	//display preferences from flags override the config file, but are not
+	//saved to it
+	measurementSystem := config.MeasurementSystem
+	if *flagMeasurementSystem != "" {
+		measurementSystem = *flagMeasurementSystem
+	}
+	backend.Display.System, err = backend.ParseMeasurementSystem(measurementSystem)
+	if err != nil {
+		return err
+	}
+	temperatureScale := config.TemperatureScale
+	if *flagTemperatureScale != "" {
+		temperatureScale = *flagTemperatureScale
+	}
+	backend.Display.TempScale, err = backend.ParseTempUnit(temperatureScale)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -176,16 +269,134 @@ func initialization() error {
 //recipeName is passed into sql prepared statement.
 //Multiple recipes can be returned from sql query, and so the user is prompted
 //for which one they want.
-func displaySingleRecipe(recipeName string) error {
-	var tempRecipe backend.Recipe
-	fmt.Print(tempRecipe.String())
+//The recipe is scaled by the -s or -y flags before it is printed.
+func displaySingleRecipe(db *sql.DB, recipeName string) error {
+	reader := bufio.NewReader(os.Stdin)
+	tempRecipe, err := chooseRecipe(db, recipeName, reader)
+	if err != nil {
+		return err
+	}
+
+	err = addNutrition(db, &tempRecipe)
+	if err != nil {
+		return err
+	}
+	err = addCost(db, &tempRecipe)
+	if err != nil {
+		return err
+	}
+	err = addJournal(db, &tempRecipe)
+	if err != nil {
+		return err
+	}
+	tempRecipe, err = scaleRecipe(tempRecipe, recipeScale, recipeYield)
+	if err != nil {
+		return err
+	}
+	if doughWeight != "" {
+		grams, err := parseGrams(doughWeight)
+		if err != nil {
+			return err
+		}
+		var unweighed []string
+		tempRecipe, unweighed, err = tempRecipe.ScaleToDoughWeight(grams)
+		if err != nil {
+			return err
+		}
+		if len(unweighed) > 0 {
+			fmt.Printf("Warning: could not weigh %s, so the dough weighs more than %s\n",
+				strings.Join(unweighed, ", "), doughWeight)
+		}
+	}
+	if bakersMode {
+		fmt.Print(tempRecipe.BakersString())
+	} else {
+		fmt.Print(tempRecipe.String())
+	}
+	substitutions, err := selectSubstitutions(db)
+	if err != nil {
+		return err
+	}
+	fmt.Print(backend.RecipeSubstitutions(tempRecipe, substitutions))
+	check, err := newHouseholdCheck(db)
+	if err != nil {
+		return err
+	}
+	conflicts, err := check.conflicts(tempRecipe, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Print(backend.ConflictsString(conflicts))
 
 	fmt.Println("Press enter to exit program")
 	//will keep attempting to read from stdin until it receives a '\n'
-	bufio.NewReader(os.Stdin).ReadBytes('\n')
+	reader.ReadBytes('\n')
 	return nil
 }
 
+//chooseRecipe reads the recipe named recipeName from the database. If there
+//are multiple recipes with that name, the user is prompted for which one
+//they want.
+func chooseRecipe(db *sql.DB, recipeName string, reader *bufio.Reader) (backend.Recipe, error) {
+	recipes, err := selectRecipes(db, recipeName)
+	if err != nil {
+		return backend.Recipe{}, err
+	}
+	if len(recipes) == 0 {
+		return backend.Recipe{}, fmt.Errorf("no recipe named %s", recipeName)
+	}
+	if len(recipes) == 1 {
+		return recipes[0], nil
+	}
+	for i, recipe := range recipes {
+		if recipe.Version > 1 {
+			fmt.Printf("%d) %s (revision %d): %s\n", i+1, recipe.Name, recipe.Version, recipe.Description)
+			continue
+		}
+		fmt.Printf("%d) %s: %s\n", i+1, recipe.Name, recipe.Description)
+	}
+	fmt.Print("Multiple recipes found, enter number of recipe to use: ")
+	tempString, err := reader.ReadString('\n')
+	if err != nil {
+		return backend.Recipe{}, err
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(tempString))
+	if err != nil || choice < 1 || choice > len(recipes) {
+		return backend.Recipe{}, fmt.Errorf("invalid recipe choice %q", strings.TrimSpace(tempString))
+	}
+	return recipes[choice-1], nil
+}
+
+//scaleRecipe applies a scale factor or target yield to a recipe. yield is
+//a quantity such as "3 loaves" and takes precedence over factor.
+func scaleRecipe(recipe backend.Recipe, factor float64, yield string) (backend.Recipe, error) {
+	if yield != "" {
+		target, err := backend.ParseQuantity(yield)
+		if err != nil {
+			return recipe, fmt.Errorf("invalid yield %q: %s", yield, err)
+		}
+		return recipe.ScaleTo(target)
+	}
+	if factor != 1 {
+		return recipe.Scale(factor)
+	}
+	return recipe, nil
+}
+
+//parseGrams reads a weight such as "1800", "1800 g" or "4 lb" and returns it
+//in grams. A weight without a unit is assumed to be in grams.
+func parseGrams(weight string) (backend.Fraction, error) {
+	quantity, err := backend.ParseQuantity(weight)
+	if err != nil {
+		return backend.Fraction{}, err
+	}
+	if quantity.Unit == "" {
+		return quantity.Amount, nil
+	}
+	quantity, err = quantity.Convert("g")
+	return quantity.Amount, err
+}
+
 //view recipe function
 
 //format recipe to markdown
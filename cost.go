@@ -0,0 +1,432 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//CurrencySymbol is shown before every amount of money
+var CurrencySymbol = "$"
+
+//FormatMoney shows an amount of money to the cent, ie: $4.99
+func FormatMoney(amount Fraction) string {
+	if amount.Sign() < 0 {
+		return "-" + CurrencySymbol + amount.Abs().value().FloatString(2)
+	}
+	return CurrencySymbol + amount.value().FloatString(2)
+}
+
+//formatChange shows a change in an amount of money with its sign, ie: +$0.25
+func formatChange(amount Fraction) string {
+	if amount.Sign() < 0 {
+		return FormatMoney(amount)
+	}
+	return "+" + FormatMoney(amount)
+}
+
+//ParseMoney reads a price such as "4.99" or "$4.99"
+func ParseMoney(s string) (Fraction, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSpace(strings.TrimPrefix(s, CurrencySymbol))
+	price, err := ParseFraction(s)
+	if err != nil {
+		return Fraction{}, fmt.Errorf("invalid price %q", s)
+	}
+	if price.Sign() < 0 {
+		return Fraction{}, fmt.Errorf("price %q is negative", s)
+	}
+	return price, nil
+}
+
+//A PricePoint is the price of one package of an inventory item at a store
+//on a date. Prices recorded when buying the item also count the packages
+//bought, prices only seen at a store do not.
+type PricePoint struct {
+	ID       int       // id of price in database
+	ItemID   int       // id of inventory item priced
+	Item     string    // name of inventory item priced
+	Price    Fraction  // price of one package
+	Store    string    // where the price was seen
+	Date     time.Time // date of the price
+	Packages Fraction  // packages bought at the price, zero if none
+}
+
+//Spent returns the money spent on the packages bought
+func (p PricePoint) Spent() Fraction {
+	return p.Price.Mul(p.Packages)
+}
+
+func (p PricePoint) String() string {
+	stringString := fmt.Sprintf("%s %s", p.Date.Format(DateFormat), FormatMoney(p.Price))
+	if p.Store != "" {
+		stringString += " at " + p.Store
+	}
+	if p.Packages.Sign() > 0 {
+		stringString += fmt.Sprintf(", bought %s for %s", p.Packages.DecimalString(2), FormatMoney(p.Spent()))
+	}
+	return stringString
+}
+
+//A PriceHistory is every price recorded for one inventory item
+type PriceHistory []PricePoint
+
+//Sort orders the prices oldest first. Prices on the same day stay in the
+//order they were recorded.
+func (h PriceHistory) Sort() {
+	sort.SliceStable(h, func(a, b int) bool {
+		return h[a].Date.Before(h[b].Date)
+	})
+}
+
+//PriceOn returns the last price recorded on or before date, or the latest
+//price if date is zero. The history must be sorted.
+func (h PriceHistory) PriceOn(date time.Time) (PricePoint, bool) {
+	for i := len(h) - 1; i >= 0; i-- {
+		if date.IsZero() || !h[i].Date.After(date) {
+			return h[i], true
+		}
+	}
+	return PricePoint{}, false
+}
+
+//String lists the prices oldest first, with how much each changed from the
+//one before it
+func (h PriceHistory) String() string {
+	stringString := ""
+	for i, price := range h {
+		stringString += fmt.Sprintf("%d) %s", price.ID, price.String())
+		if i > 0 && h[i-1].Price.Sign() > 0 && price.Price.Cmp(h[i-1].Price) != 0 {
+			change := price.Price.Sub(h[i-1].Price)
+			stringString += fmt.Sprintf(" (%s, %s%%)", formatChange(change),
+				change.Div(h[i-1].Price).MulFloat(100).DecimalString(0))
+		}
+		stringString += "\n"
+	}
+	return stringString
+}
+
+//IngredientCost is the cost of the amount of one ingredient a recipe uses,
+//priced from the inventory item used for it
+type IngredientCost struct {
+	Name     string     // name of ingredient
+	Needed   Quantity   // amount of ingredient used
+	Price    PricePoint // price of the item used, zero if not priced
+	Packages Fraction   // packages of the item used
+	Cost     Fraction   // cost of the amount used
+}
+
+//Priced reports whether a price was found for the ingredient
+func (c IngredientCost) Priced() bool {
+	return c.Price.ItemID != 0
+}
+
+func (c IngredientCost) String() string {
+	if !c.Priced() {
+		return fmt.Sprintf("%s: not priced\n", c.Name)
+	}
+	return fmt.Sprintf("%s: %s, %s packages of %s at %s\n", c.Name, FormatMoney(c.Cost),
+		c.Packages.DecimalString(2), c.Price.Item, FormatMoney(c.Price.Price))
+}
+
+//RecipeCost is the cost of the ingredients of a recipe from the prices of
+//the inventory items used for them
+type RecipeCost struct {
+	Recipe      string           // name of recipe
+	Date        time.Time        // date prices are from, zero for the latest prices
+	Total       Fraction         // cost of the priced ingredients
+	Servings    Fraction         // servings the recipe makes, zero if unknown
+	Ingredients []IngredientCost // cost of each ingredient
+	Unpriced    []string         // ingredients with no priced item
+	Unconverted []string         // ingredients that could not be converted to packages
+}
+
+//CostRecipe prices every ingredient of recipe from the first item it can
+//use with a price on date, or the latest price if date is zero. inventory
+//maps canonical ingredient names to the items that can be used for them,
+//and prices maps inventory ids to their sorted price history.
+func CostRecipe(recipe Recipe, inventory map[string][]InventoryItem, prices map[int]PriceHistory,
+	date time.Time) RecipeCost {
+	cost := RecipeCost{Recipe: recipe.Name, Date: date, Servings: recipe.Servings()}
+	for _, ingredient := range recipe.Ingredients {
+		item := IngredientCost{Name: ingredient.Name, Needed: ingredient.QuantityNeeded}
+		priced := false
+		for _, inventoryItem := range inventory[CanonicalName(ingredient.Name)] {
+			price, ok := prices[inventoryItem.ID].PriceOn(date)
+			if !ok {
+				continue
+			}
+			priced = true
+			// an ingredient used to taste costs nothing
+			if ingredient.QuantityNeeded.Amount.Sign() == 0 {
+				item.Price = price
+				break
+			}
+			packages, err := inventoryItem.PackagesOf(ingredient.QuantityNeeded)
+			if err != nil {
+				continue
+			}
+			item.Price = price
+			item.Packages = packages
+			item.Cost = price.Price.Mul(packages)
+			break
+		}
+		switch {
+		case !priced:
+			cost.Unpriced = append(cost.Unpriced, ingredient.Name)
+		case !item.Priced():
+			cost.Unconverted = append(cost.Unconverted, ingredient.Name)
+		}
+		cost.Total = cost.Total.Add(item.Cost)
+		cost.Ingredients = append(cost.Ingredients, item)
+	}
+	return cost
+}
+
+//PerServing returns the cost of one serving, or the total cost if the
+//servings the recipe makes are unknown
+func (r RecipeCost) PerServing() Fraction {
+	if r.Servings.Sign() <= 0 {
+		return r.Total
+	}
+	return r.Total.Div(r.Servings)
+}
+
+//Scale returns the cost of the recipe scaled by factor. The cost per
+//serving does not change, the recipe makes more servings.
+func (r RecipeCost) Scale(factor float64) RecipeCost {
+	scaled := r
+	multiplier := FractionFromFloat(factor)
+	scaled.Total = r.Total.Mul(multiplier)
+	scaled.Servings = r.Servings.Mul(multiplier)
+	scaled.Ingredients = make([]IngredientCost, len(r.Ingredients))
+	for i, ingredient := range r.Ingredients {
+		ingredient.Needed = ingredient.Needed.Mul(multiplier)
+		ingredient.Packages = ingredient.Packages.Mul(multiplier)
+		ingredient.Cost = ingredient.Cost.Mul(multiplier)
+		scaled.Ingredients[i] = ingredient
+	}
+	return scaled
+}
+
+//Complete reports whether every ingredient was priced
+func (r RecipeCost) Complete() bool {
+	return len(r.Unpriced) == 0 && len(r.Unconverted) == 0
+}
+
+//Warning describes the ingredients left out of the cost, empty if there
+//are none
+func (r RecipeCost) Warning() string {
+	stringString := ""
+	if len(r.Unpriced) > 0 {
+		stringString += fmt.Sprintf("Warning: no price for %s\n", strings.Join(r.Unpriced, ", "))
+	}
+	if len(r.Unconverted) > 0 {
+		stringString += fmt.Sprintf("Warning: could not convert %s to packages\n",
+			strings.Join(r.Unconverted, ", "))
+	}
+	return stringString
+}
+
+//Summary is the total cost and cost per serving on one line
+func (r RecipeCost) Summary() string {
+	stringString := FormatMoney(r.Total)
+	if r.Servings.Sign() > 0 {
+		stringString += fmt.Sprintf(", %s per serving of %s", FormatMoney(r.PerServing()),
+			r.Servings.DecimalString(2))
+	}
+	if !r.Complete() {
+		stringString += " (incomplete)"
+	}
+	return stringString + "\n"
+}
+
+func (r RecipeCost) String() string {
+	stringString := "Cost: " + r.Summary()
+	for _, ingredient := range r.Ingredients {
+		stringString += "\t" + ingredient.String()
+	}
+	return stringString + r.Warning()
+}
+
+//A CostChange is how much the cost of one ingredient of a recipe changed
+//between two dates, and the prices that caused it
+type CostChange struct {
+	Name   string         // name of ingredient
+	Before IngredientCost // cost on the earlier date
+	After  IngredientCost // cost on the later date
+}
+
+//Change returns how much the cost of the ingredient went up
+func (c CostChange) Change() Fraction {
+	return c.After.Cost.Sub(c.Before.Cost)
+}
+
+func (c CostChange) String() string {
+	stringString := fmt.Sprintf("%s: %s -> %s (%s)", c.Name, FormatMoney(c.Before.Cost),
+		FormatMoney(c.After.Cost), formatChange(c.Change()))
+	switch {
+	case !c.Before.Priced():
+		stringString += fmt.Sprintf(", first priced at %s on %s", FormatMoney(c.After.Price.Price),
+			c.After.Price.Date.Format(DateFormat))
+	case !c.After.Priced():
+		stringString += ", no longer priced"
+	case c.Before.Price.ItemID != c.After.Price.ItemID:
+		stringString += fmt.Sprintf(", now uses %s", c.After.Price.Item)
+	default:
+		stringString += fmt.Sprintf(", %s went from %s on %s to %s on %s", c.After.Price.Item,
+			FormatMoney(c.Before.Price.Price), c.Before.Price.Date.Format(DateFormat),
+			FormatMoney(c.After.Price.Price), c.After.Price.Date.Format(DateFormat))
+		if c.After.Price.Store != "" {
+			stringString += " at " + c.After.Price.Store
+		}
+	}
+	return stringString + "\n"
+}
+
+//CompareCosts returns the ingredients whose cost changed between two costs
+//of the same recipe, biggest change first
+func CompareCosts(before RecipeCost, after RecipeCost) []CostChange {
+	var changes []CostChange
+	for i := range after.Ingredients {
+		if i >= len(before.Ingredients) {
+			break
+		}
+		change := CostChange{Name: after.Ingredients[i].Name, Before: before.Ingredients[i],
+			After: after.Ingredients[i]}
+		if change.Change().Sign() != 0 || change.Before.Priced() != change.After.Priced() {
+			changes = append(changes, change)
+		}
+	}
+	sort.SliceStable(changes, func(a, b int) bool {
+		return changes[a].Change().Abs().Cmp(changes[b].Change().Abs()) > 0
+	})
+	return changes
+}
+
+//CostChangeReport shows how the cost of a recipe moved between two costs
+//of it, and which ingredients moved it. Empty if the cost did not change.
+func CostChangeReport(before RecipeCost, after RecipeCost) string {
+	changes := CompareCosts(before, after)
+	if len(changes) == 0 {
+		return ""
+	}
+	change := after.Total.Sub(before.Total)
+	stringString := fmt.Sprintf("%s: %s on %s -> %s on %s (%s", after.Recipe, FormatMoney(before.Total),
+		before.Date.Format(DateFormat), FormatMoney(after.Total), after.Date.Format(DateFormat),
+		formatChange(change))
+	if before.Total.Sign() > 0 {
+		stringString += fmt.Sprintf(", %s%%", change.Div(before.Total).MulFloat(100).DecimalString(0))
+	}
+	stringString += ")\n"
+	for _, ingredient := range changes {
+		stringString += "\t" + ingredient.String()
+	}
+	return stringString
+}
+
+//Spending is the packages bought over a period, from the prices recorded
+//when buying them
+type Spending struct {
+	From      time.Time    // first day of period
+	To        time.Time    // last day of period
+	Purchases []PricePoint // prices of packages bought in the period
+}
+
+//Total returns the money spent over the period
+func (s Spending) Total() Fraction {
+	var total Fraction
+	for _, purchase := range s.Purchases {
+		total = total.Add(purchase.Spent())
+	}
+	return total
+}
+
+func (s Spending) String() string {
+	stringString := fmt.Sprintf("Spending from %s to %s\n", s.From.Format(DateFormat), s.To.Format(DateFormat))
+	totals := make(map[string]Fraction)
+	var stores []string
+	for _, purchase := range s.Purchases {
+		store := purchase.Store
+		if store == "" {
+			store = "unknown store"
+		}
+		if _, ok := totals[store]; !ok {
+			stores = append(stores, store)
+		}
+		totals[store] = totals[store].Add(purchase.Spent())
+	}
+	sort.SliceStable(stores, func(a, b int) bool {
+		return totals[stores[a]].Cmp(totals[stores[b]]) > 0
+	})
+	for _, store := range stores {
+		stringString += fmt.Sprintf("%s: %s\n", store, FormatMoney(totals[store]))
+		for _, purchase := range s.Purchases {
+			if purchase.Store == store || (purchase.Store == "" && store == "unknown store") {
+				stringString += fmt.Sprintf("\t%s %s: %s packages at %s, %s\n",
+					purchase.Date.Format(DateFormat), purchase.Item, purchase.Packages.DecimalString(2),
+					FormatMoney(purchase.Price), FormatMoney(purchase.Spent()))
+			}
+		}
+	}
+	return stringString + fmt.Sprintf("Total: %s\n", FormatMoney(s.Total()))
+}
+
+//MealCost is the cost of one planned meal
+type MealCost struct {
+	Meal PlannedMeal
+	Cost RecipeCost
+}
+
+//PlanCost is the cost of every meal in a meal plan
+type PlanCost struct {
+	Meals []MealCost
+}
+
+//CostMealPlan prices every meal of plan, scaled to the servings planned,
+//at the prices on the day of the meal
+func CostMealPlan(plan MealPlan, inventory map[string][]InventoryItem, prices map[int]PriceHistory) PlanCost {
+	var cost PlanCost
+	for _, meal := range plan.Meals {
+		recipeCost := CostRecipe(meal.Recipe, inventory, prices, meal.Date).Scale(meal.Scale())
+		cost.Meals = append(cost.Meals, MealCost{meal, recipeCost})
+	}
+	return cost
+}
+
+//Total returns the cost of every meal
+func (p PlanCost) Total() Fraction {
+	var total Fraction
+	for _, meal := range p.Meals {
+		total = total.Add(meal.Cost.Total)
+	}
+	return total
+}
+
+func (p PlanCost) String() string {
+	stringString := ""
+	var missing []string
+	for _, meal := range p.Meals {
+		stringString += fmt.Sprintf("%s %s: %s, %s", meal.Meal.Date.Format(DateFormat), meal.Meal.Slot,
+			meal.Meal.Recipe.Name, meal.Cost.Summary())
+		for _, name := range append(meal.Cost.Unpriced, meal.Cost.Unconverted...) {
+			missing = appendIfMissing(missing, name)
+		}
+	}
+	stringString += fmt.Sprintf("Total: %s\n", FormatMoney(p.Total()))
+	if len(missing) > 0 {
+		stringString += fmt.Sprintf("Warning: no price for %s\n", strings.Join(missing, ", "))
+	}
+	return stringString
+}
+
+//appendIfMissing appends s to list unless it is already in it
+func appendIfMissing(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
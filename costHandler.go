@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const costUsage = `usage: cookbook cost <command> [arguments]
+
+commands:
+	price [-store <store>] [-date <date>] [-bought <packages>] <item> <price>
+		record the price of one package of an inventory item, ie: price 3 4.99
+	history <item>             list the prices recorded for an item
+	recipe [-date <date>] <recipe name>
+		cost of each ingredient of a recipe and its cost per serving, at the
+		prices on date, default the latest prices
+	list                       every recipe's cost per serving, cheapest first
+	changes [-since <date>] [recipe name]
+		how the cost of a recipe, or of every recipe, moved since date,
+		default 90 days ago, and the ingredients that moved it
+	plan [from] [to]           cost of the meals planned from from to to,
+	                           default the next 7 days
+	spending [from] [to]       money spent on inventory by store, default the
+	                           last 30 days
+
+Prices are also recorded when adding packages to inventory with -price.
+Recipes are priced from the inventory items used for their ingredients.
+<item> is either the id or the barcode of an inventory item.
+`
+
+//costCommand runs the cost subcommand given on the command line
+func costCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(costUsage)
+		return errors.New("no cost command given")
+	}
+	switch args[0] {
+	case "price":
+		flags := flag.NewFlagSet("cost price", flag.ContinueOnError)
+		store := flags.String("store", "", "Store the price was seen at")
+		date := flags.String("date", "", "Date of the price as YYYY-MM-DD, default today")
+		bought := flags.String("bought", "0", "Packages bought at the price")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 2 {
+			return errors.New("usage: cookbook cost price [flags] <item> <price>")
+		}
+		item, err := findInventoryItem(db, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		price := backend.PricePoint{ItemID: item.ID, Item: item.Name, Store: *store, Date: today()}
+		if price.Price, err = backend.ParseMoney(flags.Arg(1)); err != nil {
+			return err
+		}
+		if price.Packages, err = backend.ParseFraction(*bought); err != nil {
+			return err
+		}
+		if *date != "" {
+			if price.Date, err = parsePlanDate(*date, today()); err != nil {
+				return err
+			}
+		}
+		if err = insertPrice(db, price); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", item.Name, price.String())
+	case "history":
+		if len(args) != 2 {
+			return errors.New("usage: cookbook cost history <item>")
+		}
+		item, err := findInventoryItem(db, args[1])
+		if err != nil {
+			return err
+		}
+		prices, err := selectPrices(db, "WHERE prices.inventoryID = ?", item.ID)
+		if err != nil {
+			return err
+		}
+		if len(prices) == 0 {
+			fmt.Printf("No prices recorded for %s\n", item.Name)
+			return nil
+		}
+		fmt.Printf("%s:\n%s", item.Name, backend.PriceHistory(prices).String())
+	case "recipe":
+		flags := flag.NewFlagSet("cost recipe", flag.ContinueOnError)
+		date := flags.String("date", "", "Use the prices on date, default the latest prices")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: cookbook cost recipe [-date <date>] <recipe name>")
+		}
+		var on time.Time
+		var err error
+		if *date != "" {
+			if on, err = parsePlanDate(*date, today()); err != nil {
+				return err
+			}
+		}
+		recipe, err := chooseRecipe(db, flags.Arg(0), bufio.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		pricing, err := newRecipePricing(db)
+		if err != nil {
+			return err
+		}
+		cost := pricing.cost(recipe, on)
+		fmt.Print(recipe.Name + "\n" + cost.String())
+	case "list":
+		recipes, err := selectAllRecipes(db)
+		if err != nil {
+			return err
+		}
+		pricing, err := newRecipePricing(db)
+		if err != nil {
+			return err
+		}
+		var costs []backend.RecipeCost
+		for _, recipe := range recipes {
+			costs = append(costs, pricing.cost(recipe, time.Time{}))
+		}
+		sort.SliceStable(costs, func(a, b int) bool {
+			return costs[a].PerServing().Cmp(costs[b].PerServing()) < 0
+		})
+		for _, cost := range costs {
+			fmt.Print(cost.Recipe + ": " + cost.Summary())
+		}
+	case "changes":
+		flags := flag.NewFlagSet("cost changes", flag.ContinueOnError)
+		since := flags.String("since", "", "Date to compare prices from, default 90 days ago")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		from := today().AddDate(0, 0, -90)
+		var err error
+		if *since != "" {
+			if from, err = parsePlanDate(*since, today()); err != nil {
+				return err
+			}
+		}
+		var recipes []backend.Recipe
+		if flags.NArg() > 0 {
+			recipe, err := chooseRecipe(db, flags.Arg(0), bufio.NewReader(os.Stdin))
+			if err != nil {
+				return err
+			}
+			recipes = append(recipes, recipe)
+		} else if recipes, err = selectAllRecipes(db); err != nil {
+			return err
+		}
+		pricing, err := newRecipePricing(db)
+		if err != nil {
+			return err
+		}
+		changed := false
+		for _, recipe := range recipes {
+			report := backend.CostChangeReport(pricing.cost(recipe, from), pricing.cost(recipe, today()))
+			if report != "" {
+				changed = true
+				fmt.Print(report)
+			}
+		}
+		if !changed {
+			fmt.Printf("No recipe costs changed since %s\n", from.Format(backend.DateFormat))
+		}
+	case "plan":
+		from, to, err := planRange(args[1:])
+		if err != nil {
+			return err
+		}
+		plan, err := selectMealPlan(db, from, to)
+		if err != nil {
+			return err
+		}
+		if len(plan.Meals) == 0 {
+			fmt.Printf("No meals planned from %s to %s\n", from.Format(backend.DateFormat),
+				to.Format(backend.DateFormat))
+			return nil
+		}
+		pricing, err := newRecipePricing(db)
+		if err != nil {
+			return err
+		}
+		cost := backend.CostMealPlan(plan, pricing.inventory, pricing.prices)
+		fmt.Print(cost.String())
+	case "spending":
+		to := today()
+		from := to.AddDate(0, 0, -29)
+		var err error
+		if len(args) > 1 {
+			if from, err = parsePlanDate(args[1], to); err != nil {
+				return err
+			}
+		}
+		if len(args) > 2 {
+			if to, err = parsePlanDate(args[2], to); err != nil {
+				return err
+			}
+		}
+		spending := backend.Spending{From: from, To: to}
+		prices, err := selectPrices(db, "WHERE prices.date >= ? AND prices.date <= ?",
+			from.Format(backend.DateFormat), to.Format(backend.DateFormat))
+		if err != nil {
+			return err
+		}
+		// packages are stored as text when they are not whole, so only
+		// compare them once scanned
+		for _, price := range prices {
+			if price.Packages.Sign() > 0 {
+				spending.Purchases = append(spending.Purchases, price)
+			}
+		}
+		fmt.Print(spending.String())
+	default:
+		fmt.Print(costUsage)
+		return fmt.Errorf("unknown cost command %s", args[0])
+	}
+	return nil
+}
+
+//selectPrices returns the prices matching a where clause, oldest first
+func selectPrices(q queryer, where string, args ...interface{}) ([]backend.PricePoint, error) {
+	rows, err := q.Query("SELECT prices.id, prices.inventoryID, IFNULL(inventory.name, ''), prices.price, "+
+		"IFNULL(prices.store, ''), prices.date, IFNULL(prices.packages, 0) FROM prices "+
+		"INNER JOIN inventory ON prices.inventoryID = inventory.id "+where+
+		" ORDER BY prices.date, prices.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var prices []backend.PricePoint
+	for rows.Next() {
+		var price backend.PricePoint
+		var date string
+		err = rows.Scan(&price.ID, &price.ItemID, &price.Item, &price.Price, &price.Store, &date,
+			&price.Packages)
+		if err != nil {
+			return nil, err
+		}
+		if price.Date, err = parseDate(date); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	return prices, rows.Err()
+}
+
+//selectPriceHistories returns the price history of every inventory item,
+//keyed by inventory id
+func selectPriceHistories(q queryer) (map[int]backend.PriceHistory, error) {
+	prices, err := selectPrices(q, "")
+	if err != nil {
+		return nil, err
+	}
+	histories := make(map[int]backend.PriceHistory)
+	for _, price := range prices {
+		histories[price.ItemID] = append(histories[price.ItemID], price)
+	}
+	return histories, nil
+}
+
+//insertPrice records the price of a package of an inventory item
+func insertPrice(db *sql.DB, price backend.PricePoint) error {
+	_, err := db.Exec("INSERT INTO prices (inventoryID, price, store, date, packages) "+
+		"VALUES (?, ?, ?, ?, ?)", price.ItemID, price.Price, price.Store, nullDate(price.Date),
+		price.Packages)
+	return err
+}
+
+//recipePricing holds the inventory and prices used to cost recipes, so
+//they are read once when costing many recipes
+type recipePricing struct {
+	inventory map[string][]backend.InventoryItem
+	prices    map[int]backend.PriceHistory
+}
+
+//newRecipePricing reads the inventory and every price
+func newRecipePricing(db *sql.DB) (recipePricing, error) {
+	var pricing recipePricing
+	var err error
+	if pricing.inventory, err = inventoryByIngredient(db); err != nil {
+		return pricing, err
+	}
+	pricing.prices, err = selectPriceHistories(db)
+	return pricing, err
+}
+
+//cost prices recipe at the prices on date, or the latest prices if date is
+//zero
+func (p recipePricing) cost(recipe backend.Recipe, date time.Time) backend.RecipeCost {
+	return backend.CostRecipe(recipe, p.inventory, p.prices, date)
+}
+
+//addCost fills in the cost of a recipe for display, once any price has
+//been recorded
+func addCost(db *sql.DB, recipe *backend.Recipe) error {
+	pricing, err := newRecipePricing(db)
+	if err != nil || len(pricing.prices) == 0 {
+		return err
+	}
+	cost := pricing.cost(*recipe, time.Time{})
+	recipe.Cost = &cost
+	return nil
+}
@@ -0,0 +1,110 @@
+package recipeDatabase
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+//testCostRecipe returns a recipe with its inventory and prices. Flour got
+//dearer in June, there is no saffron, and water can not be measured in
+//the jugs it is stocked in.
+func testCostRecipe(t *testing.T) (Recipe, map[string][]InventoryItem, map[int]PriceHistory) {
+	recipe := Recipe{Name: "bread", QuantityMade: NewQuantity(NewFraction(4, 1), "servings"),
+		Ingredients: []Ingredient{
+			{Name: "flour", QuantityNeeded: NewQuantity(NewFraction(1, 1), "lb")},
+			{Name: "eggs", QuantityNeeded: NewQuantity(NewFraction(2, 1), "")},
+			{Name: "salt", QuantityNeeded: NewQuantity(Fraction{}, "")},
+			{Name: "saffron", QuantityNeeded: NewQuantity(NewFraction(1, 1), "pinch")},
+			{Name: "water", QuantityNeeded: NewQuantity(NewFraction(1, 1), "cup")},
+		}}
+	inventory := map[string][]InventoryItem{
+		CanonicalName("flour"): {{ID: 1, Name: "flour", PackageQuantity: NewQuantity(NewFraction(5, 1), "lb")}},
+		CanonicalName("eggs"):  {{ID: 2, Name: "eggs"}},
+		CanonicalName("salt"):  {{ID: 3, Name: "salt", PackageQuantity: NewQuantity(NewFraction(1, 1), "kg")}},
+		CanonicalName("water"): {{ID: 4, Name: "water", PackageQuantity: NewQuantity(NewFraction(1, 1), "jug")}},
+	}
+	prices := map[int]PriceHistory{
+		1: {{ItemID: 1, Item: "flour", Price: NewFraction(4, 1), Date: testDate(t, "2026-01-01")},
+			{ItemID: 1, Item: "flour", Price: NewFraction(5, 1), Date: testDate(t, "2026-06-01")}},
+		2: {{ItemID: 2, Item: "eggs", Price: NewFraction(1, 2), Date: testDate(t, "2026-01-01")}},
+		3: {{ItemID: 3, Item: "salt", Price: NewFraction(2, 1), Date: testDate(t, "2026-01-01")}},
+		4: {{ItemID: 4, Item: "water", Price: NewFraction(1, 1), Date: testDate(t, "2026-01-01")}},
+	}
+	return recipe, inventory, prices
+}
+
+func TestCostRecipe(t *testing.T) {
+	recipe, inventory, prices := testCostRecipe(t)
+	tests := []struct {
+		date       time.Time
+		total      Fraction
+		perServing string
+	}{
+		// the latest prices are used without a date
+		{time.Time{}, NewFraction(2, 1), "$0.50"},
+		{testDate(t, "2026-03-01"), NewFraction(9, 5), "$0.45"},
+		{testDate(t, "2026-06-01"), NewFraction(2, 1), "$0.50"},
+	}
+	for _, test := range tests {
+		cost := CostRecipe(recipe, inventory, prices, test.date)
+		if cost.Total.Cmp(test.total) != 0 {
+			t.Errorf("CostRecipe on %s costs %s, want %s", test.date.Format(DateFormat),
+				FormatMoney(cost.Total), FormatMoney(test.total))
+		}
+		if got := FormatMoney(cost.PerServing()); got != test.perServing {
+			t.Errorf("CostRecipe on %s costs %s per serving, want %s", test.date.Format(DateFormat), got,
+				test.perServing)
+		}
+		if cost.Complete() {
+			t.Errorf("CostRecipe on %s is complete without saffron", test.date.Format(DateFormat))
+		}
+		if !reflect.DeepEqual(cost.Unpriced, []string{"saffron"}) {
+			t.Errorf("unpriced ingredients are %v, want [saffron]", cost.Unpriced)
+		}
+		if !reflect.DeepEqual(cost.Unconverted, []string{"water"}) {
+			t.Errorf("unconverted ingredients are %v, want [water]", cost.Unconverted)
+		}
+	}
+
+	// salt to taste is priced but costs nothing
+	cost := CostRecipe(recipe, inventory, prices, time.Time{})
+	if salt := cost.Ingredients[2]; !salt.Priced() || !salt.Cost.IsZero() {
+		t.Errorf("salt to taste costs %s, priced %v, want $0.00 and priced", FormatMoney(salt.Cost),
+			salt.Priced())
+	}
+	// prices from before anything was bought are not priced
+	cost = CostRecipe(recipe, inventory, prices, testDate(t, "2025-12-31"))
+	if !cost.Total.IsZero() || len(cost.Unpriced) != len(recipe.Ingredients) {
+		t.Errorf("CostRecipe before any prices costs %s with unpriced %v, want $0.00 and every ingredient unpriced",
+			FormatMoney(cost.Total), cost.Unpriced)
+	}
+
+	scaled := CostRecipe(recipe, inventory, prices, time.Time{}).Scale(2)
+	if scaled.Total.Cmp(NewFraction(4, 1)) != 0 || FormatMoney(scaled.PerServing()) != "$0.50" {
+		t.Errorf("doubled cost is %s, %s per serving, want $4.00, $0.50 per serving",
+			FormatMoney(scaled.Total), FormatMoney(scaled.PerServing()))
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Fraction
+	}{
+		{"4.99", NewFraction(499, 100)},
+		{"$4.99", NewFraction(499, 100)},
+		{" $ 12 ", NewFraction(12, 1)},
+	}
+	for _, test := range tests {
+		got, err := ParseMoney(test.input)
+		if err != nil || got.Cmp(test.want) != 0 {
+			t.Errorf("ParseMoney(%q) = %s, %v, want %s", test.input, got, err, FormatMoney(test.want))
+		}
+	}
+	for _, input := range []string{"", "free", "-1"} {
+		if got, err := ParseMoney(input); err == nil {
+			t.Errorf("ParseMoney(%q) = %s, want an error", input, got)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"sort"
+)
+
+//DefaultPantryStaples are ingredients most kitchens always have, which can
+//be ignored when deciding what can be made
+var DefaultPantryStaples = []string{"salt", "pepper", "black pepper", "water", "ice",
+	"sugar", "vegetable oil", "olive oil", "oil", "cooking spray"}
+
+//CoverageOptions control which recipes RankRecipes returns
+type CoverageOptions struct {
+	IgnoreStaples bool     // treat staples as always on hand
+	Staples       []string // pantry staples, DefaultPantryStaples if nil
+	Tags          []string // only rank recipes with every one of these tags
+	Diets         []string // only rank recipes suiting every one of these diets
+	MaxMissing    int      // only rank recipes missing at most this many ingredients, -1 for any
+	// substitutions that can cover ingredients that are not on hand
+	Substitutions []Substitution
+	// household members eating, to warn about ingredients they can not eat
+	Household []HouseholdMember
+	Catalog   []CatalogIngredient // catalog to classify ingredients for warnings
+}
+
+//An IngredientCoverage is how much of one ingredient of a recipe is on hand
+type IngredientCoverage struct {
+	Name    string   `json:"name"`
+	Needed  Quantity `json:"needed"`
+	OnHand  Quantity `json:"onHand"`
+	Missing Quantity `json:"missing"` // amount still needed, zero if covered
+	Staple  bool     `json:"staple"`  // ignored as a pantry staple
+	// substitution on hand for the missing amount, empty if none
+	Substitute string `json:"substitute,omitempty"`
+}
+
+//Covered reports whether enough of the ingredient, or a substitute for it,
+//is on hand
+func (c IngredientCoverage) Covered() bool {
+	return c.Staple || c.Missing.Amount.Sign() <= 0 || c.Substitute != ""
+}
+
+//A RecipeCoverage is how fully the inventory covers the ingredients of a
+//recipe
+type RecipeCoverage struct {
+	Recipe      Recipe               `json:"-"`
+	Name        string               `json:"name"`
+	Coverage    float64              `json:"coverage"` // from 0, nothing on hand, to 1, can make it
+	Ingredients []IngredientCoverage `json:"ingredients"`
+	Warnings    []MemberConflict     `json:"warnings,omitempty"`
+}
+
+//CanMake reports whether every ingredient is covered
+func (r RecipeCoverage) CanMake() bool {
+	return len(r.Missing()) == 0
+}
+
+//Missing returns the ingredients that are not covered
+func (r RecipeCoverage) Missing() []IngredientCoverage {
+	var missing []IngredientCoverage
+	for _, ingredient := range r.Ingredients {
+		if !ingredient.Covered() {
+			missing = append(missing, ingredient)
+		}
+	}
+	return missing
+}
+
+func (r RecipeCoverage) String() string {
+	stringString := fmt.Sprintf("%3.0f%% %s\n", r.Coverage*100, r.Name)
+	for _, ingredient := range r.Ingredients {
+		if ingredient.Substitute != "" {
+			stringString += fmt.Sprintf("\tfor %s %s, %s\n", ingredient.Missing.Format(),
+				ingredient.Name, ingredient.Substitute)
+		}
+	}
+	for _, ingredient := range r.Missing() {
+		if ingredient.OnHand.IsZero() {
+			stringString += fmt.Sprintf("\tmissing %s %s\n", ingredient.Missing.Format(), ingredient.Name)
+		} else {
+			stringString += fmt.Sprintf("\tmissing %s %s, have %s\n", ingredient.Missing.Format(),
+				ingredient.Name, ingredient.OnHand.Format())
+		}
+	}
+	for _, warning := range r.Warnings {
+		stringString += "\twarning: " + warning.String() + "\n"
+	}
+	return stringString
+}
+
+//CoverRecipe works out how much of each ingredient of recipe is on hand.
+//inventory maps canonical ingredient names to the items that can be used
+//for them, and staples are canonical names of ingredients to ignore. An
+//ingredient that is partly on hand counts for the part on hand, unless one
+//of substitutions is on hand for the rest.
+func CoverRecipe(recipe Recipe, inventory map[string][]InventoryItem, staples map[string]bool,
+	substitutions []Substitution) RecipeCoverage {
+	available := func(ingredient Ingredient) bool {
+		name := CanonicalName(ingredient.Name)
+		if staples[name] {
+			return true
+		}
+		have, _ := onHand(name, ingredient.QuantityNeeded.Unit, inventory)
+		return have.Amount.Cmp(ingredient.QuantityNeeded.Amount) >= 0
+	}
+	coverage := RecipeCoverage{Recipe: recipe, Name: recipe.Name}
+	if len(recipe.Ingredients) == 0 {
+		return coverage
+	}
+	total := 0.0
+	for _, ingredient := range recipe.Ingredients {
+		name := CanonicalName(ingredient.Name)
+		item := IngredientCoverage{Name: ingredient.Name, Needed: ingredient.QuantityNeeded}
+		item.OnHand, _ = onHand(name, ingredient.QuantityNeeded.Unit, inventory)
+		item.Missing = item.Needed
+		item.Missing.Amount = item.Needed.Amount.Sub(item.OnHand.Amount)
+		if item.Missing.Amount.Sign() < 0 {
+			item.Missing.Amount = Fraction{}
+		}
+		item.Staple = staples[name]
+		if !item.Covered() {
+			if substitute, ok := findSubstitute(name, item.Missing, substitutions, available); ok {
+				item.Substitute = substitute.String()
+			}
+		}
+		switch {
+		case item.Covered():
+			total++
+		case item.Needed.Amount.Sign() > 0:
+			total += item.OnHand.Amount.Div(item.Needed.Amount).Float64()
+		}
+		coverage.Ingredients = append(coverage.Ingredients, item)
+	}
+	coverage.Coverage = total / float64(len(recipe.Ingredients))
+	return coverage
+}
+
+//RankRecipes returns how fully the inventory covers each recipe that
+//matches options, best covered first. Recipes covered equally are ordered
+//by fewest missing ingredients, then name.
+func RankRecipes(recipes []Recipe, inventory map[string][]InventoryItem, options CoverageOptions) []RecipeCoverage {
+	staples := make(map[string]bool)
+	if options.IgnoreStaples {
+		names := options.Staples
+		if names == nil {
+			names = DefaultPantryStaples
+		}
+		for _, name := range names {
+			staples[CanonicalName(name)] = true
+		}
+	}
+	var ranked []RecipeCoverage
+	for _, recipe := range recipes {
+		if !recipe.HasTags(options.Tags) || !recipe.SuitsDiets(options.Diets) {
+			continue
+		}
+		coverage := CoverRecipe(recipe, inventory, staples, options.Substitutions)
+		if options.MaxMissing >= 0 && len(coverage.Missing()) > options.MaxMissing {
+			continue
+		}
+		coverage.Warnings = HouseholdConflicts(recipe, options.Household, options.Catalog, options.Substitutions)
+		ranked = append(ranked, coverage)
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		if ranked[a].Coverage != ranked[b].Coverage {
+			return ranked[a].Coverage > ranked[b].Coverage
+		}
+		missingA, missingB := len(ranked[a].Missing()), len(ranked[b].Missing())
+		if missingA != missingB {
+			return missingA < missingB
+		}
+		return ranked[a].Name < ranked[b].Name
+	})
+	return ranked
+}
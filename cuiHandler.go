@@ -1,21 +1,24 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 
 	"github.com/awesome-gocui/gocui"
 )
 
-func startCUI() error {
+func startCUI(db *sql.DB) error {
 	gui, err := gocui.NewGui(gocui.Output256, true)
 	if err != nil {
 		return err
 	}
 	defer gui.Close()
+	// barcode scanners and the stocktake screen need the escape key
+	gui.InputEsc = true
 
 	gui.SetManagerFunc(layout)
 
-	if err := initKeybindings(gui); err != nil {
+	if err := initKeybindings(gui, db); err != nil {
 		return err
 	}
 
@@ -25,6 +28,11 @@ func startCUI() error {
 	return nil
 }
 
+//screenOpen reports whether a screen is open over the main view
+func screenOpen() bool {
+	return stocktake != nil || canMake != nil || mealPlan != nil || statsScreen != nil
+}
+
 func quit(_ *gocui.Gui, _ *gocui.View) error {
 	return gocui.ErrQuit
 }
@@ -38,7 +46,21 @@ func layout(gui *gocui.Gui) error {
 		if !gocui.IsUnknownView(cmdErr) {
 			return cmdErr
 		}
-		fmt.Fprintln(cmdView, "^C: Exit")
+	}
+	cmdView.Clear()
+	switch {
+	case stocktake != nil && stocktake.creating != "":
+		fmt.Fprintln(cmdView, "Tab: Next field  Enter: Save  Esc: Cancel  ^C: Exit")
+	case stocktake != nil:
+		fmt.Fprintln(cmdView, "Enter: Scan  ^T: Add/Use mode  ^U: Undo  ^S: Commit  ^D: Discard  Esc: Back  ^C: Exit")
+	case canMake != nil:
+		fmt.Fprintln(cmdView, "Enter: Filter tags/diets  ^S: Ignore staples  ^N: Near misses/all  Esc: Back  ^C: Exit")
+	case mealPlan != nil:
+		fmt.Fprintln(cmdView, "Enter: Add/remove meal  ^N: Next week  ^P: Previous week  Esc: Back  ^C: Exit")
+	case statsScreen != nil:
+		fmt.Fprintln(cmdView, "Enter: Set months  Up/Down: Scroll  Esc: Back  ^C: Exit")
+	default:
+		fmt.Fprintln(cmdView, "F2: Stocktake  F3: What can I make  F4: Meal plan  F5: Statistics  ^C: Exit")
 	}
 
 	// main view shows usage instructions and main keyboard commands
@@ -50,6 +72,19 @@ func layout(gui *gocui.Gui) error {
 		fmt.Fprintln(mainView, "this is a test")
 	}
 
+	if stocktake != nil {
+		return stocktakeLayout(gui)
+	}
+	if canMake != nil {
+		return canMakeLayout(gui)
+	}
+	if mealPlan != nil {
+		return mealPlanLayout(gui)
+	}
+	if statsScreen != nil {
+		return statsLayout(gui)
+	}
+
 	// recipe view displays individual recipe
 
 	//recipeView, recipeErr := gui.SetView("recipe",
@@ -57,10 +92,34 @@ func layout(gui *gocui.Gui) error {
 	return nil
 }
 
-func initKeybindings(gui *gocui.Gui) error {
+func initKeybindings(gui *gocui.Gui, db *sql.DB) error {
 	if err := gui.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
 		return err
 	}
+	err := gui.SetKeybinding("", gocui.KeyF2, gocui.ModNone, func(gui *gocui.Gui, _ *gocui.View) error {
+		return openStocktake(gui, db)
+	})
+	if err != nil {
+		return err
+	}
+	err = gui.SetKeybinding("", gocui.KeyF3, gocui.ModNone, func(gui *gocui.Gui, _ *gocui.View) error {
+		return openCanMake(gui, db)
+	})
+	if err != nil {
+		return err
+	}
+	err = gui.SetKeybinding("", gocui.KeyF4, gocui.ModNone, func(gui *gocui.Gui, _ *gocui.View) error {
+		return openMealPlan(gui, db)
+	})
+	if err != nil {
+		return err
+	}
+	err = gui.SetKeybinding("", gocui.KeyF5, gocui.ModNone, func(gui *gocui.Gui, _ *gocui.View) error {
+		return openStats(gui, db)
+	})
+	if err != nil {
+		return err
+	}
 	if err := gui.SetKeybinding("", gocui.KeyArrowDown, gocui.ModNone, test3); err != nil {
 		return err
 	}
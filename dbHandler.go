@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	backend "github.com/sww1235/recipe-database"
@@ -80,18 +81,22 @@ func initDB(databasePath string) *sql.DB {
 		}
 	}
 
-	for table, missing := range requiredTables {
+	for _, missing := range requiredTables {
 		// any false values will cancel out the true initial value
 		// for needInit. needInit will only be true if all tables are missing
 		needInit = missing && needInit
 		// missingTable will be true if any of the tables are missing
 		missingTable = missing || missingTable
-		if missing {
+	}
+
+	for table, missing := range requiredTables {
+		// a brand new database is missing every table, which is expected
+		if missing && !needInit {
 			infoLogger.Printf("Table %s missing. Manually create this table, or delete database so it can be recreated.", table)
 		}
 	}
 
-	if missingTable {
+	if missingTable && !needInit {
 		fatalLogger.Panicln("Existing database missing critical table. See log messages above.")
 	}
 
@@ -108,11 +113,14 @@ func initDB(databasePath string) *sql.DB {
 
 		createQueries["InvTable"] = "CREATE TABLE inventory (id INTEGER NOT NULL PRIMARY KEY, " +
 			"EAN TEXT UNIQUE, name TEXT, description TEXT, quantity NUM, packageQuantity NUM, " +
-			"packageQuantityUnits INTEGER, FOREIGN KEY(packageQuantityUnits) REFERENCES units(id))"
+			"packageQuantityUnits INTEGER, minimumStock NUM DEFAULT 0, reorderQuantity NUM DEFAULT 0, " +
+			"FOREIGN KEY(packageQuantityUnits) REFERENCES units(id))"
 
 		createQueries["IngTable"] = "CREATE TABLE ingredients (id INTEGER NOT NULL PRIMARY KEY, " +
-			"name TEXT, quantity NUM, quantityUnits INTEGER, inventoryID INTEGER, " +
+			"name TEXT, quantity NUM, quantityUnits INTEGER, inventoryID INTEGER, isFlour NUM DEFAULT 0, " +
+			"catalogID INTEGER, " +
 			"FOREIGN KEY(inventoryID) REFERENCES inventory(id), " +
+			"FOREIGN KEY(catalogID) REFERENCES ingredientCatalog(id), " +
 			"FOREIGN KEY(quantityUnits) REFERENCES units(id))"
 
 		createQueries["IngInvTable"] = "CREATE TABLE ingredient_inventory( " +
@@ -159,11 +167,490 @@ func initDB(databasePath string) *sql.DB {
 		}
 
 	}
+
+	err = upgradeDB(db)
+	if err != nil {
+		fatalLogger.Panicln("Failed to upgrade database", err)
+	}
 	return db
 
 }
 
-func insertRecipe(db *sql.DB, recipe backend.Recipe) error {
+//addedTables are the tables added to the database since its first version,
+//each after the tables it references. They are created when missing, so
+//databases made by an older version keep working after an upgrade.
+var addedTables = []struct {
+	name  string
+	query string
+}{
+	{"lastMade", "CREATE TABLE IF NOT EXISTS lastMade(id INTEGER NOT NULL PRIMARY KEY, " +
+		"recipe INTEGER NOT NULL, dateMade TEXT, notes TEXT, " +
+		"FOREIGN KEY(recipe) REFERENCES recipes(id))"},
+	{"journal", "CREATE TABLE IF NOT EXISTS journal(lastMadeID INTEGER NOT NULL PRIMARY KEY, " +
+		"rating INTEGER DEFAULT 0, servings NUM DEFAULT 0, cook TEXT, " +
+		"FOREIGN KEY(lastMadeID) REFERENCES lastMade(id))"},
+	{"journalAdjustments", "CREATE TABLE IF NOT EXISTS journalAdjustments(id INTEGER NOT NULL PRIMARY KEY, " +
+		"lastMadeID INTEGER NOT NULL, kind TEXT NOT NULL, ingredient TEXT, quantity NUM, " +
+		"quantityUnits INTEGER, note TEXT, FOREIGN KEY(lastMadeID) REFERENCES lastMade(id), " +
+		"FOREIGN KEY(quantityUnits) REFERENCES units(id))"},
+	{"recipeRevisions", "CREATE TABLE IF NOT EXISTS recipeRevisions(recipeID INTEGER NOT NULL PRIMARY KEY, " +
+		"initialVersion INTEGER NOT NULL, version INTEGER NOT NULL, lastMadeID INTEGER, " +
+		"FOREIGN KEY(recipeID) REFERENCES recipes(id), FOREIGN KEY(initialVersion) REFERENCES recipes(id), " +
+		"FOREIGN KEY(lastMadeID) REFERENCES lastMade(id))"},
+	{"products", "CREATE TABLE IF NOT EXISTS products(GTIN TEXT NOT NULL PRIMARY KEY, " +
+		"name TEXT, description TEXT, packageQuantity NUM, packageQuantityUnits INTEGER, " +
+		"FOREIGN KEY(packageQuantityUnits) REFERENCES units(id))"},
+	{"inventoryLots", "CREATE TABLE IF NOT EXISTS inventoryLots(id INTEGER NOT NULL PRIMARY KEY, " +
+		"inventoryID INTEGER NOT NULL, quantity NUM, purchased TEXT, expires TEXT, location TEXT, " +
+		"FOREIGN KEY(inventoryID) REFERENCES inventory(id))"},
+	{"inventoryUsage", "CREATE TABLE IF NOT EXISTS inventoryUsage(id INTEGER NOT NULL PRIMARY KEY, " +
+		"inventoryID INTEGER NOT NULL, dateUsed TEXT, packages NUM, reason TEXT, lastMadeID INTEGER, " +
+		"FOREIGN KEY(inventoryID) REFERENCES inventory(id), " +
+		"FOREIGN KEY(lastMadeID) REFERENCES lastMade(id))"},
+	{"substitutions", "CREATE TABLE IF NOT EXISTS substitutions(id INTEGER NOT NULL PRIMARY KEY, " +
+		"ingredient TEXT NOT NULL, quantity NUM, quantityUnits INTEGER, notes TEXT, " +
+		"FOREIGN KEY(quantityUnits) REFERENCES units(id))"},
+	{"substitutionIngredients", "CREATE TABLE IF NOT EXISTS substitutionIngredients(id INTEGER NOT NULL PRIMARY KEY, " +
+		"substitutionID INTEGER NOT NULL, name TEXT, quantity NUM, quantityUnits INTEGER, " +
+		"FOREIGN KEY(substitutionID) REFERENCES substitutions(id), " +
+		"FOREIGN KEY(quantityUnits) REFERENCES units(id))"},
+	{"ingredientCatalog", "CREATE TABLE IF NOT EXISTS ingredientCatalog(id INTEGER NOT NULL PRIMARY KEY, " +
+		"name TEXT NOT NULL UNIQUE, plural TEXT, category TEXT, defaultUnit INTEGER, " +
+		"FOREIGN KEY(defaultUnit) REFERENCES units(id))"},
+	{"ingredientCatalogAliases", "CREATE TABLE IF NOT EXISTS ingredientCatalogAliases(alias TEXT NOT NULL PRIMARY KEY, " +
+		"catalogID INTEGER NOT NULL, FOREIGN KEY(catalogID) REFERENCES ingredientCatalog(id))"},
+	{"mealPlan", "CREATE TABLE IF NOT EXISTS mealPlan(id INTEGER NOT NULL PRIMARY KEY, " +
+		"date TEXT NOT NULL, slot TEXT, recipeID INTEGER NOT NULL, servings NUM DEFAULT 0, notes TEXT, " +
+		"FOREIGN KEY(recipeID) REFERENCES recipes(id))"},
+	{"nutritionFoods", "CREATE TABLE IF NOT EXISTS nutritionFoods(fdcID INTEGER NOT NULL PRIMARY KEY, " +
+		"description TEXT, dataType TEXT, calories NUM, protein NUM, fat NUM, saturatedFat NUM, " +
+		"carbohydrate NUM, fiber NUM, sugar NUM, cholesterol NUM, sodium NUM, potassium NUM, " +
+		"calcium NUM, iron NUM, vitaminA NUM, vitaminC NUM, vitaminD NUM)"},
+	{"foodPortions", "CREATE TABLE IF NOT EXISTS foodPortions(id INTEGER NOT NULL PRIMARY KEY, " +
+		"fdcID INTEGER NOT NULL, amount NUM, unit TEXT, grams NUM, " +
+		"FOREIGN KEY(fdcID) REFERENCES nutritionFoods(fdcID))"},
+	{"ingredientFoods", "CREATE TABLE IF NOT EXISTS ingredientFoods(catalogID INTEGER NOT NULL PRIMARY KEY, " +
+		"fdcID INTEGER NOT NULL, FOREIGN KEY(catalogID) REFERENCES ingredientCatalog(id), " +
+		"FOREIGN KEY(fdcID) REFERENCES nutritionFoods(fdcID))"},
+	{"prices", "CREATE TABLE IF NOT EXISTS prices(id INTEGER NOT NULL PRIMARY KEY, " +
+		"inventoryID INTEGER NOT NULL, price NUM NOT NULL, store TEXT, date TEXT NOT NULL, " +
+		"packages NUM DEFAULT 0, FOREIGN KEY(inventoryID) REFERENCES inventory(id))"},
+	{"ingredientContains", "CREATE TABLE IF NOT EXISTS ingredientContains(catalogID INTEGER NOT NULL PRIMARY KEY, " +
+		"contains TEXT, FOREIGN KEY(catalogID) REFERENCES ingredientCatalog(id))"},
+	{"recipeDiets", "CREATE TABLE IF NOT EXISTS recipeDiets(recipeID INTEGER NOT NULL, diet TEXT NOT NULL, " +
+		"FOREIGN KEY(recipeID) REFERENCES recipes(id), PRIMARY KEY(recipeID, diet))"},
+	{"householdMembers", "CREATE TABLE IF NOT EXISTS householdMembers(id INTEGER NOT NULL PRIMARY KEY, " +
+		"name TEXT NOT NULL UNIQUE COLLATE NOCASE)"},
+	{"memberRestrictions", "CREATE TABLE IF NOT EXISTS memberRestrictions(memberID INTEGER NOT NULL, " +
+		"reaction TEXT NOT NULL, item TEXT NOT NULL, FOREIGN KEY(memberID) REFERENCES householdMembers(id), " +
+		"PRIMARY KEY(memberID, reaction, item))"},
+	{"mealEaters", "CREATE TABLE IF NOT EXISTS mealEaters(mealID INTEGER NOT NULL, memberID INTEGER NOT NULL, " +
+		"FOREIGN KEY(mealID) REFERENCES mealPlan(id), FOREIGN KEY(memberID) REFERENCES householdMembers(id), " +
+		"PRIMARY KEY(mealID, memberID))"},
+	{"leftovers", "CREATE TABLE IF NOT EXISTS leftovers(inventoryID INTEGER NOT NULL PRIMARY KEY, " +
+		"recipeID INTEGER NOT NULL, FOREIGN KEY(inventoryID) REFERENCES inventory(id), " +
+		"FOREIGN KEY(recipeID) REFERENCES recipes(id))"},
+	{"menus", "CREATE TABLE IF NOT EXISTS menus(id INTEGER NOT NULL PRIMARY KEY, " +
+		"name TEXT NOT NULL UNIQUE COLLATE NOCASE, date TEXT, guests INTEGER DEFAULT 0, notes TEXT)"},
+	{"menuDishes", "CREATE TABLE IF NOT EXISTS menuDishes(id INTEGER NOT NULL PRIMARY KEY, " +
+		"menuID INTEGER NOT NULL, course TEXT, recipeID INTEGER NOT NULL, perGuest NUM DEFAULT 0, notes TEXT, " +
+		"FOREIGN KEY(menuID) REFERENCES menus(id), FOREIGN KEY(recipeID) REFERENCES recipes(id))"},
+}
 
+//addedColumns are the columns added to the tables of the first version of
+//the database, which are added to older databases on start up
+var addedColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"ingredients", "isFlour", "NUM DEFAULT 0"},
+	{"inventory", "minimumStock", "NUM DEFAULT 0"},
+	{"inventory", "reorderQuantity", "NUM DEFAULT 0"},
+	{"ingredients", "catalogID", "INTEGER REFERENCES ingredientCatalog(id)"},
+}
+
+//columnExists reports whether table has a column called name
+func columnExists(db *sql.DB, table string, name string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, name).Scan(&count)
+	return count > 0, err
+}
+
+//tableExists reports whether the database has a table called name
+func tableExists(db *sql.DB, name string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	return count > 0, err
+}
+
+//upgradeDB creates the tables and columns added since the database was
+//made, normalizes stored barcodes, and adds the default substitutions when
+//their table is new
+func upgradeDB(db *sql.DB) error {
+	seed := false
+	for _, table := range addedTables {
+		exists, err := tableExists(db, table.name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		infoLogger.Printf("adding table %s to database", table.name)
+		if _, err = db.Exec(table.query); err != nil {
+			return fmt.Errorf("could not create table %s: %s", table.name, err)
+		}
+		seed = seed || table.name == "substitutions"
+	}
+	for _, column := range addedColumns {
+		exists, err := columnExists(db, column.table, column.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		infoLogger.Printf("adding column %s to table %s", column.column, column.table)
+		_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", column.table, column.column,
+			column.definition))
+		if err != nil {
+			return fmt.Errorf("could not add column %s to table %s: %s", column.column, column.table, err)
+		}
+	}
+	err := normalizeInventoryGTINs(db)
+	if err != nil {
+		return err
+	}
+	if seed {
+		return seedSubstitutions(db)
+	}
+	return nil
+}
+
+//normalizeInventoryGTINs pads barcodes stored before they were normalized
+//to GTINLength digits, so they are found when looked up. Barcodes that are
+//not valid GTINs are left as they are.
+func normalizeInventoryGTINs(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, EAN FROM inventory WHERE EAN IS NOT NULL AND length(EAN) != ?",
+		backend.GTINLength)
+	if err != nil {
+		return err
+	}
+	stored := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var ean string
+		if err = rows.Scan(&id, &ean); err != nil {
+			rows.Close()
+			return err
+		}
+		stored[id] = ean
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for id, ean := range stored {
+		gtin, gtinErr := backend.NormalizeGTIN(ean)
+		if gtinErr != nil || gtin == ean {
+			continue
+		}
+		infoLogger.Printf("normalizing barcode %s of inventory item %d to %s", ean, id, gtin)
+		if _, err = db.Exec("UPDATE inventory SET EAN = ? WHERE id = ?", gtin, id); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+//insertRecipe stores a new recipe and all of its ingredients, steps and tags
+//in a single transaction
+func insertRecipe(db *sql.DB, recipe backend.Recipe) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = insertRecipeTx(tx, recipe)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+//insertRecipeTx stores a new recipe as part of tx and returns its id
+func insertRecipeTx(tx *sql.Tx, recipe backend.Recipe) (int64, error) {
+	unit, err := unitID(tx, recipe.QuantityMade.Unit)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("INSERT INTO recipes (name, description, comments, source, "+
+		"author, quantity, quantityUnits) VALUES (?, ?, ?, ?, ?, ?, ?)", recipe.Name,
+		recipe.Description, recipe.Comments, recipe.Source, recipe.Author,
+		recipe.QuantityMade.Amount, unit)
+	if err != nil {
+		return 0, err
+	}
+	recipeID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	catalog, err := newCatalogCache(tx)
+	if err != nil {
+		return 0, err
+	}
+	linked := recipe
+	linked.Ingredients = nil
+	for _, ingredient := range recipe.Ingredients {
+		entry, err := catalog.entry(ingredient.Name)
+		if err != nil {
+			return 0, err
+		}
+		ingredient.CatalogID = entry.ID
+		linked.Ingredients = append(linked.Ingredients, ingredient)
+		if ingredient.QuantityNeeded.Unit == "" && !ingredient.QuantityNeeded.IsZero() {
+			ingredient.QuantityNeeded.Unit = entry.DefaultUnit
+		}
+		unit, err := unitID(tx, ingredient.QuantityNeeded.Unit)
+		if err != nil {
+			return 0, err
+		}
+		result, err := tx.Exec("INSERT INTO ingredients (name, quantity, quantityUnits, isFlour, catalogID) "+
+			"VALUES (?, ?, ?, ?, ?)", ingredient.Name, ingredient.QuantityNeeded.Amount, unit,
+			ingredient.IsFlour, entry.ID)
+		if err != nil {
+			return 0, err
+		}
+		ingredientID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec("INSERT INTO ingredient_recipe (ingredientID, recipeID) VALUES (?, ?)",
+			ingredientID, recipeID)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err = saveRecipeDietsTx(tx, recipeID, linked, catalog.entries); err != nil {
+		return 0, err
+	}
+
+	for _, step := range recipe.Steps {
+		var tempUnit sql.NullInt64
+		if step.Temperature.Unit != 0 {
+			tempUnit, err = unitID(tx, string(step.Temperature.Unit))
+			if err != nil {
+				return 0, err
+			}
+		}
+		result, err := tx.Exec("INSERT INTO steps (instructions, time, stepTypeID, temperature, tempUnits) "+
+			"VALUES (?, ?, ?, ?, ?)", step.Instructions, step.TimeNeeded.Seconds(),
+			int(step.StepType), step.Temperature.Value, tempUnit)
+		if err != nil {
+			return 0, err
+		}
+		stepID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec("INSERT INTO step_recipe (stepID, recipeID) VALUES (?, ?)", stepID, recipeID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, tag := range recipe.Tags {
+		var tagID int64
+		err := tx.QueryRow("SELECT id FROM tags WHERE name = ?", tag).Scan(&tagID)
+		if err == sql.ErrNoRows {
+			result, err := tx.Exec("INSERT INTO tags (name) VALUES (?)", tag)
+			if err != nil {
+				return 0, err
+			}
+			tagID, err = result.LastInsertId()
+			if err != nil {
+				return 0, err
+			}
+		} else if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec("INSERT OR IGNORE INTO tag_recipe (tagID, recipeID) VALUES (?, ?)", tagID, recipeID)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return recipeID, nil
+}
+
+//unitID finds the id of the unit called name, adding it to the units table
+//if it does not exist yet. An empty name is stored as NULL.
+func unitID(tx *sql.Tx, name string) (sql.NullInt64, error) {
+	var id sql.NullInt64
+	if name == "" {
+		return id, nil
+	}
+	err := tx.QueryRow("SELECT id FROM units WHERE name = ?", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		result, err := tx.Exec("INSERT INTO units (name) VALUES (?)", name)
+		if err != nil {
+			return id, err
+		}
+		id.Int64, err = result.LastInsertId()
+		id.Valid = err == nil
+		return id, err
+	}
+	return id, err
+}
+
+//selectRecipes reads every recipe named recipeName from the database, along
+//with its ingredients, steps and tags.
+func selectRecipes(db *sql.DB, recipeName string) ([]backend.Recipe, error) {
+	return selectRecipesWhere(db, "WHERE recipes.name = ?", recipeName)
+}
+
+//selectAllRecipes returns every recipe in the database, sorted by name
+func selectAllRecipes(db *sql.DB) ([]backend.Recipe, error) {
+	return selectRecipesWhere(db, "ORDER BY recipes.name")
+}
+
+//selectRecipesWhere returns the recipes, with all of their details, that
+//match a where clause
+func selectRecipesWhere(db *sql.DB, where string, args ...interface{}) ([]backend.Recipe, error) {
+	var recipes []backend.Recipe
+
+	rows, err := db.Query("SELECT recipes.id, recipes.name, recipes.description, "+
+		"recipes.comments, recipes.source, recipes.author, recipes.quantity, "+
+		"IFNULL(units.name, ''), IFNULL(recipeRevisions.initialVersion, 0), "+
+		"IFNULL(recipeRevisions.version, 1) FROM recipes LEFT JOIN units ON "+
+		"recipes.quantityUnits = units.id LEFT JOIN recipeRevisions ON "+
+		"recipes.id = recipeRevisions.recipeID "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var tempRecipe backend.Recipe
+		var description, comments, source, author sql.NullString
+		err = rows.Scan(&tempRecipe.ID, &tempRecipe.Name, &description, &comments,
+			&source, &author, &tempRecipe.QuantityMade.Amount, &tempRecipe.QuantityMade.Unit,
+			&tempRecipe.InitialVersion, &tempRecipe.Version)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tempRecipe.Description = description.String
+		tempRecipe.Comments = comments.String
+		tempRecipe.Source = source.String
+		tempRecipe.Author = author.String
+		recipes = append(recipes, tempRecipe)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range recipes {
+		err = selectRecipeDetails(db, &recipes[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return recipes, nil
+}
+
+//selectRecipeDetails fills in the ingredients, steps, tags and diets of a
+//recipe that has already been read from the recipes table.
+func selectRecipeDetails(db *sql.DB, recipe *backend.Recipe) error {
+	rows, err := db.Query("SELECT ingredients.id, IFNULL(ingredients.catalogID, 0), ingredients.name, "+
+		"ingredients.quantity, IFNULL(units.name, ''), IFNULL(ingredients.isFlour, 0) FROM ingredients "+
+		"INNER JOIN ingredient_recipe ON ingredients.id = ingredient_recipe.ingredientID "+
+		"LEFT JOIN units ON ingredients.quantityUnits = units.id "+
+		"WHERE ingredient_recipe.recipeID = ? ORDER BY ingredients.id", recipe.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var tempIngredient backend.Ingredient
+		err = rows.Scan(&tempIngredient.ID, &tempIngredient.CatalogID, &tempIngredient.Name,
+			&tempIngredient.QuantityNeeded.Amount,
+			&tempIngredient.QuantityNeeded.Unit,
+			&tempIngredient.IsFlour)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		recipe.Ingredients = append(recipe.Ingredients, tempIngredient)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.Query("SELECT steps.instructions, steps.time, steps.stepTypeID, "+
+		"IFNULL(steps.temperature, 0), IFNULL(units.name, '') FROM steps "+
+		"INNER JOIN step_recipe ON steps.id = step_recipe.stepID "+
+		"LEFT JOIN units ON steps.tempUnits = units.id "+
+		"WHERE step_recipe.recipeID = ? ORDER BY steps.id", recipe.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var tempStep backend.Step
+		var instructions sql.NullString
+		var seconds, temperature float64
+		var stepType int
+		var tempUnit string
+		err = rows.Scan(&instructions, &seconds, &stepType, &temperature, &tempUnit)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		tempStep.Instructions = instructions.String
+		tempStep.TimeNeeded = time.Duration(seconds * float64(time.Second))
+		tempStep.StepType = backend.StepType(stepType)
+		tempStep.Temperature.Value = temperature
+		if tempUnit != "" {
+			tempStep.Temperature.Unit = backend.TempUnit(tempUnit[0])
+		}
+		recipe.Steps = append(recipe.Steps, tempStep)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.Query("SELECT tags.name FROM tags "+
+		"INNER JOIN tag_recipe ON tags.id = tag_recipe.tagID "+
+		"WHERE tag_recipe.recipeID = ? ORDER BY tags.name", recipe.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var tag string
+		if err = rows.Scan(&tag); err != nil {
+			rows.Close()
+			return err
+		}
+		recipe.Tags = append(recipe.Tags, tag)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.Query("SELECT diet FROM recipeDiets WHERE recipeID = ?", recipe.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var diet string
+		if err = rows.Scan(&diet); err != nil {
+			return err
+		}
+		recipe.Diets = append(recipe.Diets, diet)
+	}
+	backend.SortDiets(recipe.Diets)
+	return rows.Err()
+}
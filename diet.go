@@ -0,0 +1,434 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//Things an ingredient can contain that some diets or allergies rule out
+const (
+	ContainsMeat      = "meat"
+	ContainsFish      = "fish"
+	ContainsShellfish = "shellfish"
+	ContainsDairy     = "dairy"
+	ContainsEgg       = "egg"
+	ContainsHoney     = "honey"
+	ContainsGluten    = "gluten"
+	ContainsTreeNuts  = "tree nuts"
+	ContainsPeanuts   = "peanuts"
+	ContainsSoy       = "soy"
+	ContainsSesame    = "sesame"
+)
+
+//IngredientClasses lists everything an ingredient can be classified as
+//containing
+var IngredientClasses = []string{ContainsMeat, ContainsFish, ContainsShellfish, ContainsDairy,
+	ContainsEgg, ContainsHoney, ContainsGluten, ContainsTreeNuts, ContainsPeanuts, ContainsSoy,
+	ContainsSesame}
+
+//ingredientClassGroups are words that name more than one ingredient class,
+//so an allergy to nuts rules out peanuts as well as tree nuts
+var ingredientClassGroups = map[string][]string{
+	"nuts":    {ContainsTreeNuts, ContainsPeanuts},
+	"nut":     {ContainsTreeNuts, ContainsPeanuts},
+	"seafood": {ContainsFish, ContainsShellfish},
+}
+
+//ParseIngredientClass reads the name of one ingredient class. Words naming
+//more than one class, such as nuts, are rejected rather than narrowed to
+//one of them; use ParseIngredientClasses to accept them.
+func ParseIngredientClass(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if group, ok := ingredientClassGroups[s]; ok {
+		return "", fmt.Errorf("%s is more than one ingredient class, name %s", s, strings.Join(group, " or "))
+	}
+	switch s {
+	case "tree nut", "treenuts":
+		return ContainsTreeNuts, nil
+	case "peanut":
+		return ContainsPeanuts, nil
+	case "eggs":
+		return ContainsEgg, nil
+	case "milk", "lactose":
+		return ContainsDairy, nil
+	case "wheat":
+		return ContainsGluten, nil
+	}
+	for _, class := range IngredientClasses {
+		if s == class {
+			return class, nil
+		}
+	}
+	return "", fmt.Errorf("unknown ingredient class %q, expected one of %s", s,
+		strings.Join(IngredientClasses, ", "))
+}
+
+//ParseIngredientClasses reads the name of an ingredient class, or of a
+//group of classes such as nuts, which is every class in the group
+func ParseIngredientClasses(s string) ([]string, error) {
+	if group, ok := ingredientClassGroups[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return group, nil
+	}
+	class, err := ParseIngredientClass(s)
+	if err != nil {
+		return nil, err
+	}
+	return []string{class}, nil
+}
+
+//Diets recipes are classified as suiting
+const (
+	DietVegetarian  = "vegetarian"
+	DietVegan       = "vegan"
+	DietPescatarian = "pescatarian"
+	DietGlutenFree  = "gluten-free"
+	DietDairyFree   = "dairy-free"
+	DietEggFree     = "egg-free"
+	DietNutFree     = "nut-free"
+	DietSoyFree     = "soy-free"
+	DietSesameFree  = "sesame-free"
+	DietFishFree    = "fish-free"
+)
+
+//Diets lists every diet recipes are classified as suiting
+var Diets = []string{DietVegetarian, DietVegan, DietPescatarian, DietGlutenFree, DietDairyFree,
+	DietEggFree, DietNutFree, DietSoyFree, DietSesameFree, DietFishFree}
+
+//dietExcludes is what each diet rules out
+var dietExcludes = map[string][]string{
+	DietVegetarian:  {ContainsMeat, ContainsFish, ContainsShellfish},
+	DietVegan:       {ContainsMeat, ContainsFish, ContainsShellfish, ContainsDairy, ContainsEgg, ContainsHoney},
+	DietPescatarian: {ContainsMeat},
+	DietGlutenFree:  {ContainsGluten},
+	DietDairyFree:   {ContainsDairy},
+	DietEggFree:     {ContainsEgg},
+	DietNutFree:     {ContainsTreeNuts, ContainsPeanuts},
+	DietSoyFree:     {ContainsSoy},
+	DietSesameFree:  {ContainsSesame},
+	DietFishFree:    {ContainsFish, ContainsShellfish},
+}
+
+//ParseDiet reads the name of a diet, ie: vegan, gluten free or gf
+func ParseDiet(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.Join(strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == '_' || r == '-' }), "-")
+	switch s {
+	case "veg", "veggie":
+		return DietVegetarian, nil
+	case "gf", "coeliac", "celiac":
+		return DietGlutenFree, nil
+	case "df", "lactose-free", "non-dairy":
+		return DietDairyFree, nil
+	case "peanut-free", "tree-nut-free":
+		return DietNutFree, nil
+	case "seafood-free":
+		return DietFishFree, nil
+	}
+	for _, diet := range Diets {
+		if s == diet {
+			return diet, nil
+		}
+	}
+	return "", fmt.Errorf("unknown diet %q, expected one of %s", s, strings.Join(Diets, ", "))
+}
+
+//ParseDiets reads a comma separated list of diets
+func ParseDiets(s string) ([]string, error) {
+	var diets []string
+	for _, name := range strings.Split(s, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		diet, err := ParseDiet(name)
+		if err != nil {
+			return nil, err
+		}
+		diets = append(diets, diet)
+	}
+	return diets, nil
+}
+
+//A classRule classifies ingredients whose name contains one of words, except
+//where the word is part of one of except, ie: the butter in peanut butter.
+//Names containing one of unless are never classified by the rule, ie: vegan
+//cheese.
+type classRule struct {
+	class  string
+	words  []string
+	except []string
+	unless []string
+}
+
+//plantBased are words marking a product as a plant based version of an
+//animal product
+var plantBased = []string{"vegan", "vegetarian", "veggie", "plant based", "meatless", "imitation"}
+
+//classRules are the default rules used to classify ingredients by their
+//name. Overrides in the catalog take precedence.
+var classRules = []classRule{
+	{ContainsMeat, []string{"beef", "pork", "chicken", "turkey", "lamb", "mutton", "veal", "bacon", "ham",
+		"sausage", "prosciutto", "pancetta", "salami", "pepperoni", "chorizo", "steak", "duck", "goose",
+		"venison", "gelatin", "gelatine", "lard", "suet", "meat", "mince", "hot dog"},
+		nil, plantBased},
+	{ContainsFish, []string{"fish", "salmon", "tuna", "cod", "anchovy", "sardine", "trout", "halibut",
+		"tilapia", "haddock", "mackerel", "herring", "snapper", "swordfish", "worcestershire", "caviar",
+		"roe", "bonito", "dashi"},
+		nil, plantBased},
+	{ContainsShellfish, []string{"shrimp", "prawn", "crab", "lobster", "clam", "mussel", "oyster",
+		"scallop", "crawfish", "crayfish", "squid", "calamari", "octopus"},
+		[]string{"oyster mushroom"}, plantBased},
+	{ContainsDairy, []string{"milk", "butter", "cream", "cheese", "yogurt", "yoghurt", "buttermilk", "ghee",
+		"whey", "casein", "parmesan", "mozzarella", "cheddar", "ricotta", "feta", "mascarpone", "kefir",
+		"creme fraiche", "half and half", "brie", "gouda", "gruyere", "paneer", "custard"},
+		[]string{"peanut butter", "almond butter", "cashew butter", "nut butter", "apple butter",
+			"cocoa butter", "seed butter", "shea butter", "almond milk", "soy milk", "oat milk", "rice milk",
+			"coconut milk", "coconut cream", "coconut yogurt", "cream of tartar", "cashew cream",
+			"butter bean", "butter lettuce"},
+		append([]string{"dairy free", "non dairy"}, plantBased...)},
+	{ContainsEgg, []string{"egg", "egg white", "egg yolk", "mayonnaise", "mayo", "meringue", "aioli"},
+		[]string{"egg replacer", "flax egg"}, append([]string{"egg free"}, plantBased...)},
+	{ContainsHoney, []string{"honey"}, nil, plantBased},
+	{ContainsGluten, []string{"flour", "wheat", "barley", "rye", "bread", "breadcrumb", "bread crumb",
+		"panko", "pasta", "spaghetti", "macaroni", "noodle", "couscous", "semolina", "bulgur", "farro",
+		"spelt", "seitan", "soy sauce", "malt", "beer", "cracker", "crouton", "orzo", "lasagna",
+		"tortellini", "gnocchi", "pita", "bagel", "brioche", "baguette", "udon", "graham", "pastry",
+		"phyllo", "filo", "dough", "wonton", "ramen"},
+		[]string{"rice flour", "almond flour", "coconut flour", "corn flour", "chickpea flour",
+			"potato flour", "tapioca flour", "cassava flour", "buckwheat flour", "oat flour", "rice noodle",
+			"rice pasta", "glass noodle"},
+		[]string{"gluten free"}},
+	{ContainsTreeNuts, []string{"almond", "walnut", "pecan", "cashew", "pistachio", "hazelnut", "macadamia",
+		"brazil nut", "pine nut", "nut", "marzipan", "praline", "nutella", "frangipane", "amaretto"},
+		nil, []string{"nut free"}},
+	{ContainsPeanuts, []string{"peanut", "groundnut", "satay"}, nil, []string{"peanut free", "nut free"}},
+	{ContainsSoy, []string{"soy", "soya", "tofu", "tempeh", "edamame", "miso", "tamari", "soybean"},
+		nil, []string{"soy free"}},
+	{ContainsSesame, []string{"sesame", "tahini", "halva", "halvah", "zaatar"}, nil, []string{"sesame free"}},
+}
+
+//nameWords splits an ingredient name into lowercase words, dropping
+//punctuation so "all-purpose" is "all purpose"
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+//sameWord reports whether word is keyword, or a plural of it
+func sameWord(word string, keyword string) bool {
+	return word == keyword || singularWord(word) == keyword || word == pluralWord(keyword)
+}
+
+//phraseSpans returns the start and end of every place the words of phrase
+//appear one after the other in words
+func phraseSpans(words []string, phrase string) [][2]int {
+	phraseWords := nameWords(phrase)
+	var spans [][2]int
+	if len(phraseWords) == 0 {
+		return spans
+	}
+	for start := 0; start+len(phraseWords) <= len(words); start++ {
+		match := true
+		for i, phraseWord := range phraseWords {
+			if !sameWord(words[start+i], phraseWord) {
+				match = false
+				break
+			}
+		}
+		if match {
+			spans = append(spans, [2]int{start, start + len(phraseWords)})
+		}
+	}
+	return spans
+}
+
+//matches reports whether the rule classifies an ingredient named by words
+func (rule classRule) matches(words []string) bool {
+	for _, phrase := range rule.unless {
+		if len(phraseSpans(words, phrase)) > 0 {
+			return false
+		}
+	}
+	var excepted [][2]int
+	for _, phrase := range rule.except {
+		excepted = append(excepted, phraseSpans(words, phrase)...)
+	}
+	for _, word := range rule.words {
+		for _, span := range phraseSpans(words, word) {
+			covered := false
+			for _, exception := range excepted {
+				if exception[0] <= span[0] && span[1] <= exception[1] {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//ClassifyIngredient guesses what an ingredient contains from its name,
+//ie: "peanut butter" contains peanuts but not dairy
+func ClassifyIngredient(name string) []string {
+	words := nameWords(name)
+	var classes []string
+	for _, rule := range classRules {
+		if rule.matches(words) {
+			classes = append(classes, rule.class)
+		}
+	}
+	return classes
+}
+
+//classifyNames classifies every name, returning everything any of them
+//contains, in the order of IngredientClasses
+func classifyNames(names ...string) []string {
+	found := make(map[string]bool)
+	for _, name := range names {
+		for _, class := range ClassifyIngredient(name) {
+			found[class] = true
+		}
+	}
+	var classes []string
+	for _, class := range IngredientClasses {
+		if found[class] {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+//Classify sets what the ingredient contains from its name and aliases,
+//unless it was overridden
+func (c *CatalogIngredient) Classify() {
+	if c.ContainsOverride {
+		return
+	}
+	c.Contains = classifyNames(append([]string{c.Name}, c.Aliases...)...)
+}
+
+//SetContains overrides what the ingredient contains. A nil classes
+//removes the override and classifies the ingredient by name again.
+func (c *CatalogIngredient) SetContains(classes []string) {
+	if classes == nil {
+		c.ContainsOverride = false
+		c.Classify()
+		return
+	}
+	c.ContainsOverride = true
+	c.Contains = nil
+	for _, class := range IngredientClasses {
+		for _, wanted := range classes {
+			if class == wanted {
+				c.Contains = append(c.Contains, class)
+				break
+			}
+		}
+	}
+}
+
+//ContainsClass reports whether the ingredient contains class
+func (c CatalogIngredient) ContainsClass(class string) bool {
+	for _, contained := range c.Contains {
+		if contained == class {
+			return true
+		}
+	}
+	return false
+}
+
+//A DietConflict is an ingredient that keeps a recipe from suiting a diet
+type DietConflict struct {
+	Diet       string // diet ruled out
+	Ingredient string // ingredient ruling it out
+	Class      string // what the ingredient contains that the diet rules out
+}
+
+func (d DietConflict) String() string {
+	return fmt.Sprintf("not %s: %s contains %s", d.Diet, d.Ingredient, d.Class)
+}
+
+//ingredientContains returns what an ingredient of a recipe contains, from
+//its catalog entry, or from its name if it is not in the catalog
+func ingredientContains(ingredient Ingredient, catalog []CatalogIngredient) []string {
+	for _, entry := range catalog {
+		if ingredient.CatalogID != 0 && entry.ID == ingredient.CatalogID {
+			return entry.Contains
+		}
+	}
+	if entry, ok := FindCatalogIngredient(ingredient.Name, catalog); ok {
+		return entry.Contains
+	}
+	return classifyNames(ingredient.Name)
+}
+
+//RecipeDiets works out which diets a recipe suits from what its ingredients
+//contain, and returns the ingredients that rule out the other diets
+func RecipeDiets(recipe Recipe, catalog []CatalogIngredient) ([]string, []DietConflict) {
+	var diets []string
+	var conflicts []DietConflict
+	// a recipe without ingredients is not known to suit anything
+	if len(recipe.Ingredients) == 0 {
+		return diets, conflicts
+	}
+	contains := make([][]string, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		contains[i] = ingredientContains(ingredient, catalog)
+	}
+	for _, diet := range Diets {
+		suits := true
+		for i, ingredient := range recipe.Ingredients {
+			for _, class := range contains[i] {
+				for _, excluded := range dietExcludes[diet] {
+					if class == excluded {
+						suits = false
+						conflicts = append(conflicts, DietConflict{diet, ingredient.Name, class})
+					}
+				}
+			}
+		}
+		if suits {
+			diets = append(diets, diet)
+		}
+	}
+	return diets, conflicts
+}
+
+//SuitsDiets reports whether the recipe suits every one of diets
+func (r Recipe) SuitsDiets(diets []string) bool {
+	for _, diet := range diets {
+		found := false
+		for _, recipeDiet := range r.Diets {
+			if recipeDiet == diet {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+//SortDiets orders diets as they are listed in Diets
+func SortDiets(diets []string) {
+	sort.SliceStable(diets, func(a, b int) bool {
+		return dietOrder(diets[a]) < dietOrder(diets[b])
+	})
+}
+
+//dietOrder returns the position of diet in Diets
+func dietOrder(diet string) int {
+	for i, known := range Diets {
+		if diet == known {
+			return i
+		}
+	}
+	return len(Diets)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const dietUsage = `usage: cookbook diet <command> [arguments]
+
+commands:
+	list [class]               list catalog ingredients and what they contain,
+	                           or only the ones containing class
+	set <ingredient> <classes>
+		set what an ingredient contains by hand, as a comma separated list of
+		classes or none, ie: set "worcestershire sauce" fish
+	reset <ingredient>         classify an ingredient by its name again
+	show <recipe name>         the diets a recipe suits, and the ingredients
+	                           ruling out the others
+	recompute                  derive the diets of every recipe again
+
+classes: meat, fish, shellfish, dairy, egg, honey, gluten, tree nuts, peanuts,
+soy and sesame, or nuts for tree nuts and peanuts and seafood for fish and
+shellfish
+diets: vegetarian, vegan, pescatarian, gluten-free, dairy-free, egg-free,
+nut-free, soy-free, sesame-free and fish-free
+
+Ingredients are classified from their names and aliases when they are added
+to the catalog. The diets of a recipe are derived again whenever its
+ingredients or the catalog change, and can be filtered on with the -diet flag
+of recipes and canmake.
+`
+
+//dietCommand runs the diet subcommand given on the command line
+func dietCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(dietUsage)
+		return errors.New("no diet command given")
+	}
+	switch args[0] {
+	case "list":
+		var classes []string
+		if len(args) > 1 {
+			var err error
+			if classes, err = backend.ParseIngredientClasses(strings.Join(args[1:], " ")); err != nil {
+				return err
+			}
+		}
+		catalog, err := selectCatalog(db)
+		if err != nil {
+			return err
+		}
+		for _, entry := range catalog {
+			matched := len(classes) == 0
+			for _, class := range classes {
+				matched = matched || entry.ContainsClass(class)
+			}
+			if !matched {
+				continue
+			}
+			contains := strings.Join(entry.Contains, ", ")
+			if contains == "" {
+				contains = "nothing"
+			}
+			if entry.ContainsOverride {
+				contains += " (set by hand)"
+			}
+			fmt.Printf("%s: %s\n", entry.Name, contains)
+		}
+	case "set", "reset":
+		if (args[0] == "set" && len(args) != 3) || (args[0] == "reset" && len(args) != 2) {
+			fmt.Print(dietUsage)
+			return fmt.Errorf("wrong number of arguments to diet %s", args[0])
+		}
+		var classes []string
+		if args[0] == "set" {
+			classes = []string{}
+			if strings.TrimSpace(args[2]) != "none" {
+				for _, name := range splitTags(args[2]) {
+					named, err := backend.ParseIngredientClasses(name)
+					if err != nil {
+						return err
+					}
+					classes = append(classes, named...)
+				}
+			}
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		catalog, err := newCatalogCache(tx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		entry, err := catalog.entry(args[1])
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		entry.SetContains(classes)
+		if err = updateCatalogTx(tx, entry); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err = recomputeDietsTx(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		fmt.Printf("%d) %s", entry.ID, entry)
+	case "show":
+		if len(args) < 2 {
+			return errors.New("usage: cookbook diet show <recipe name>")
+		}
+		recipe, err := chooseRecipe(db, strings.Join(args[1:], " "), bufio.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		catalog, err := selectCatalog(db)
+		if err != nil {
+			return err
+		}
+		diets, conflicts := backend.RecipeDiets(recipe, catalog)
+		fmt.Println(recipe.Name)
+		if len(diets) > 0 {
+			fmt.Printf("Suits: %s\n", strings.Join(diets, ", "))
+		}
+		for _, conflict := range conflicts {
+			fmt.Printf("\t%s\n", conflict)
+		}
+	case "recompute":
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		count, err := recomputeDietsTx(tx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		fmt.Printf("Derived the diets of %d recipes\n", count)
+	default:
+		fmt.Print(dietUsage)
+		return fmt.Errorf("unknown diet command %s", args[0])
+	}
+	return nil
+}
+
+//saveRecipeDietsTx derives the diets a recipe suits from its ingredients
+//and replaces the diets stored for it
+func saveRecipeDietsTx(tx *sql.Tx, recipeID int64, recipe backend.Recipe, catalog []backend.CatalogIngredient) error {
+	_, err := tx.Exec("DELETE FROM recipeDiets WHERE recipeID = ?", recipeID)
+	if err != nil {
+		return err
+	}
+	diets, _ := backend.RecipeDiets(recipe, catalog)
+	for _, diet := range diets {
+		_, err = tx.Exec("INSERT INTO recipeDiets (recipeID, diet) VALUES (?, ?)", recipeID, diet)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//recomputeDietsTx derives the diets of every recipe again, after what
+//catalog ingredients contain has changed, and returns the number of recipes
+func recomputeDietsTx(tx *sql.Tx) (int, error) {
+	catalog, err := selectCatalog(tx)
+	if err != nil {
+		return 0, err
+	}
+	recipes := make(map[int64]*backend.Recipe)
+	var order []int64
+	rows, err := tx.Query("SELECT recipes.id, IFNULL(ingredients.name, ''), IFNULL(ingredients.catalogID, 0) " +
+		"FROM recipes LEFT JOIN ingredient_recipe ON recipes.id = ingredient_recipe.recipeID " +
+		"LEFT JOIN ingredients ON ingredient_recipe.ingredientID = ingredients.id ORDER BY recipes.id")
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var recipeID int64
+		var ingredient backend.Ingredient
+		if err = rows.Scan(&recipeID, &ingredient.Name, &ingredient.CatalogID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if recipes[recipeID] == nil {
+			recipes[recipeID] = &backend.Recipe{ID: int(recipeID)}
+			order = append(order, recipeID)
+		}
+		if ingredient.Name != "" {
+			recipes[recipeID].Ingredients = append(recipes[recipeID].Ingredients, ingredient)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, recipeID := range order {
+		if err = saveRecipeDietsTx(tx, recipeID, *recipes[recipeID], catalog); err != nil {
+			return 0, err
+		}
+	}
+	return len(order), nil
+}
@@ -0,0 +1,63 @@
+package recipeDatabase
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyIngredient(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"all-purpose flour", []string{ContainsGluten}},
+		{"rice flour", nil},
+		{"unsalted butter", []string{ContainsDairy}},
+		{"peanut butter", []string{ContainsPeanuts}},
+		{"almond milk", []string{ContainsTreeNuts}},
+		{"coconut milk", nil},
+		{"large eggs", []string{ContainsEgg}},
+		{"egg replacer", nil},
+		{"chicken thighs", []string{ContainsMeat}},
+		{"vegan sausage", nil},
+		{"oyster mushrooms", nil},
+		{"oysters", []string{ContainsShellfish}},
+		{"worcestershire sauce", []string{ContainsFish}},
+		{"soy sauce", []string{ContainsGluten, ContainsSoy}},
+		{"tahini", []string{ContainsSesame}},
+		{"gluten free pasta", nil},
+		{"water", nil},
+	}
+	for _, test := range tests {
+		if got := ClassifyIngredient(test.name); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ClassifyIngredient(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseIngredientClasses(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"nuts", []string{ContainsTreeNuts, ContainsPeanuts}},
+		{"Nut", []string{ContainsTreeNuts, ContainsPeanuts}},
+		{"seafood", []string{ContainsFish, ContainsShellfish}},
+		{"tree nut", []string{ContainsTreeNuts}},
+		{"peanut", []string{ContainsPeanuts}},
+		{" Milk ", []string{ContainsDairy}},
+		{"sesame", []string{ContainsSesame}},
+	}
+	for _, test := range tests {
+		got, err := ParseIngredientClasses(test.input)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseIngredientClasses(%q) = %v, %v, want %v", test.input, got, err, test.want)
+		}
+	}
+	// a single class is never narrowed from a group
+	for _, input := range []string{"nuts", "seafood", "mushroom"} {
+		if class, err := ParseIngredientClass(input); err == nil {
+			t.Errorf("ParseIngredientClass(%q) = %q, want an error", input, class)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"strings"
+)
+
+//DisplayOptions controls how the String methods in this package render
+//quantities. Stored values are never changed by display options.
+type DisplayOptions struct {
+	RoundMetric bool              // round metric quantities to what a scale or jug can measure
+	System      MeasurementSystem // system to display quantities in, AnySystem shows them as stored
+	TempScale   TempUnit          // scale to display temperatures in, 0 shows them as stored
+}
+
+//Display holds the options used by every String method in this package
+var Display = DisplayOptions{RoundMetric: true}
+
+func (m MeasurementSystem) String() string {
+	switch m {
+	case Imperial:
+		return "imperial"
+	case Metric:
+		return "metric"
+	}
+	return "any"
+}
+
+//ParseMeasurementSystem reads a measurement system name as used in config
+//files and command line flags. An empty string means no preference.
+func ParseMeasurementSystem(s string) (MeasurementSystem, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "any", "stored":
+		return AnySystem, nil
+	case "imperial", "us", "customary":
+		return Imperial, nil
+	case "metric", "si":
+		return Metric, nil
+	}
+	return AnySystem, fmt.Errorf("unknown measurement system %q, expected metric or imperial", s)
+}
+
+//ParseTempUnit reads a temperature scale, either as its letter or its full
+//name. An empty string means no preference.
+func ParseTempUnit(s string) (TempUnit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "any", "stored":
+		return 0, nil
+	case "f", "fahrenheit":
+		return 'F', nil
+	case "c", "celsius":
+		return 'C', nil
+	case "k", "kelvin":
+		return 'K', nil
+	case "r", "rankine":
+		return 'R', nil
+	}
+	return 0, fmt.Errorf("unknown temperature scale %q, expected C, F, K or R", s)
+}
+
+//displayQuantity renders quantity of unit converted to the preferred
+//measurement system. Quantities that cannot be converted are rendered as
+//stored.
+func displayQuantity(quantity Fraction, unit string) string {
+	quantity, unit, _ = ConvertToSystem(quantity, unit, Display.System)
+	return FormatQuantity(quantity, unit)
+}
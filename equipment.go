@@ -2,6 +2,7 @@ package recipeDatabase
 
 import "fmt"
 
+//Equipment is a pot, pan, appliance or tool used to make a recipe
 type Equipment struct {
 	ID    int    // database unique id
 	Name  string // name of equipment
@@ -13,10 +14,7 @@ func (e Equipment) String() string {
 
 	if e.Owned {
 
-		return fmt.Sprintf("Equipment: %s is owned")
-	} else {
-
-		return fmt.Sprintf("Equipment: %s is not owned")
-
+		return fmt.Sprintf("Equipment: %s is owned", e.Name)
 	}
+	return fmt.Sprintf("Equipment: %s is not owned", e.Name)
 }
@@ -0,0 +1,255 @@
+package recipeDatabase
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+//Fraction is an exact rational number used to store quantities so that
+//scaling a recipe by 1/3 gives 1/3 cup instead of 0.333333 cups.
+//The zero value is 0. Fractions are immutable, every operation returns
+//a new Fraction.
+type Fraction struct {
+	rat *big.Rat
+}
+
+//vulgarFractions maps fractions that have a single unicode character to
+//that character, for kitchen friendly rendering.
+var vulgarFractions = map[string]string{
+	"1/2": "½", "1/3": "⅓", "2/3": "⅔", "1/4": "¼", "3/4": "¾",
+	"1/5": "⅕", "2/5": "⅖", "3/5": "⅗", "4/5": "⅘", "1/6": "⅙",
+	"5/6": "⅚", "1/8": "⅛", "3/8": "⅜", "5/8": "⅝", "7/8": "⅞",
+}
+
+//NewFraction returns the fraction num/den. A zero denominator returns 0.
+func NewFraction(num int64, den int64) Fraction {
+	if den == 0 {
+		return Fraction{}
+	}
+	return Fraction{big.NewRat(num, den)}
+}
+
+//FractionFromFloat converts a float to a fraction using its shortest decimal
+//representation, so 0.1 becomes exactly 1/10.
+func FractionFromFloat(f float64) Fraction {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Fraction{}
+	}
+	rat, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	if !ok {
+		return Fraction{}
+	}
+	return Fraction{rat}
+}
+
+//ParseFraction reads a fraction in any of the forms people write quantities
+//in: "2", "1.5", "3/4", "1 1/2", "1-1/2", "½" or "1½".
+func ParseFraction(s string) (Fraction, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Fraction{}, fmt.Errorf("empty quantity")
+	}
+	for ascii, vulgar := range vulgarFractions {
+		s = strings.Replace(s, vulgar, " "+ascii, 1)
+	}
+	// a dash after the whole number separates it from the fraction
+	if dash := strings.LastIndex(s, "-"); dash > 0 {
+		s = s[:dash] + " " + s[dash+1:]
+	}
+	total := new(big.Rat)
+	for _, field := range strings.Fields(s) {
+		part, ok := new(big.Rat).SetString(field)
+		if !ok {
+			return Fraction{}, fmt.Errorf("invalid quantity %q", s)
+		}
+		total.Add(total, part)
+	}
+	return Fraction{total}, nil
+}
+
+func (f Fraction) value() *big.Rat {
+	if f.rat == nil {
+		return new(big.Rat)
+	}
+	return f.rat
+}
+
+//Add returns f + g
+func (f Fraction) Add(g Fraction) Fraction {
+	return Fraction{new(big.Rat).Add(f.value(), g.value())}
+}
+
+//Sub returns f - g
+func (f Fraction) Sub(g Fraction) Fraction {
+	return Fraction{new(big.Rat).Sub(f.value(), g.value())}
+}
+
+//Mul returns f * g
+func (f Fraction) Mul(g Fraction) Fraction {
+	return Fraction{new(big.Rat).Mul(f.value(), g.value())}
+}
+
+//Div returns f / g, or 0 if g is 0
+func (f Fraction) Div(g Fraction) Fraction {
+	if g.IsZero() {
+		return Fraction{}
+	}
+	return Fraction{new(big.Rat).Quo(f.value(), g.value())}
+}
+
+//MulFloat returns f multiplied by the decimal value of x
+func (f Fraction) MulFloat(x float64) Fraction {
+	return f.Mul(FractionFromFloat(x))
+}
+
+//Abs returns the absolute value of f
+func (f Fraction) Abs() Fraction {
+	return Fraction{new(big.Rat).Abs(f.value())}
+}
+
+//Cmp compares f and g and returns -1, 0 or +1
+func (f Fraction) Cmp(g Fraction) int {
+	return f.value().Cmp(g.value())
+}
+
+//Sign returns -1, 0 or +1 depending on the sign of f
+func (f Fraction) Sign() int {
+	return f.value().Sign()
+}
+
+//IsZero reports whether f is 0
+func (f Fraction) IsZero() bool {
+	return f.Sign() == 0
+}
+
+//Float64 returns the nearest float64 to f
+func (f Fraction) Float64() float64 {
+	value, _ := f.value().Float64()
+	return value
+}
+
+//Ceil returns the smallest whole number greater than or equal to f
+func (f Fraction) Ceil() Fraction {
+	num, den := f.value().Num(), f.value().Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return Fraction{new(big.Rat).SetInt(quotient)}
+}
+
+//RoundTo rounds f to the nearest multiple of increment. A non zero value is
+//never rounded down to 0, the smallest increment is used instead.
+func (f Fraction) RoundTo(increment Fraction) Fraction {
+	if increment.Sign() <= 0 || f.IsZero() {
+		return f
+	}
+	steps := f.Div(increment).Add(NewFraction(1, 2))
+	// floor of steps, handling negative values
+	num, den := steps.value().Num(), steps.value().Denom()
+	floor := new(big.Int).Div(num, den)
+	rounded := Fraction{new(big.Rat).SetInt(floor)}.Mul(increment)
+	if rounded.IsZero() {
+		if f.Sign() < 0 {
+			return increment.Mul(NewFraction(-1, 1))
+		}
+		return increment
+	}
+	return rounded
+}
+
+//wholeAndRemainder splits a non negative fraction into its whole number part
+//and the proper fraction that remains.
+func (f Fraction) wholeAndRemainder() (*big.Int, *big.Rat) {
+	num, den := f.value().Num(), f.value().Denom()
+	whole, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	return whole, new(big.Rat).SetFrac(remainder, den)
+}
+
+//String returns f as a mixed number, such as "1 1/2"
+func (f Fraction) String() string {
+	return f.format(false)
+}
+
+//KitchenString returns f as a mixed number using unicode fraction characters
+//where they exist, such as "1 ¾" or "⅓"
+func (f Fraction) KitchenString() string {
+	return f.format(true)
+}
+
+func (f Fraction) format(vulgar bool) string {
+	sign := ""
+	value := f.value()
+	if value.Sign() < 0 {
+		sign = "-"
+		value = new(big.Rat).Neg(value)
+	}
+	whole, remainder := Fraction{value}.wholeAndRemainder()
+	if remainder.Sign() == 0 {
+		return sign + whole.String()
+	}
+	fraction := remainder.RatString()
+	if vulgar {
+		if character, ok := vulgarFractions[fraction]; ok {
+			fraction = character
+		}
+	}
+	if whole.Sign() == 0 {
+		return sign + fraction
+	}
+	return sign + whole.String() + " " + fraction
+}
+
+//DecimalString returns f as a decimal number with at most places digits
+//after the decimal point, with trailing zeros removed.
+func (f Fraction) DecimalString(places int) string {
+	decimal := f.value().FloatString(places)
+	if strings.Contains(decimal, ".") {
+		decimal = strings.TrimRight(strings.TrimRight(decimal, "0"), ".")
+	}
+	if decimal == "-0" {
+		decimal = "0"
+	}
+	return decimal
+}
+
+//Value implements driver.Valuer so fractions can be stored in the database
+//exactly, as either an integer or a num/den string.
+func (f Fraction) Value() (driver.Value, error) {
+	if f.value().IsInt() {
+		return f.value().Num().Int64(), nil
+	}
+	return f.value().RatString(), nil
+}
+
+//Scan implements sql.Scanner for fractions stored by Value, or as plain
+//numbers.
+func (f *Fraction) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*f = Fraction{}
+	case int64:
+		*f = NewFraction(value, 1)
+	case float64:
+		*f = FractionFromFloat(value)
+	case string:
+		parsed, err := ParseFraction(value)
+		if err != nil {
+			return err
+		}
+		*f = parsed
+	case []byte:
+		parsed, err := ParseFraction(string(value))
+		if err != nil {
+			return err
+		}
+		*f = parsed
+	default:
+		return fmt.Errorf("cannot scan %T into Fraction", src)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package recipeDatabase
+
+import "testing"
+
+func TestParseFraction(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Fraction
+		kitchen string
+	}{
+		{"2", NewFraction(2, 1), "2"},
+		{"1.5", NewFraction(3, 2), "1 ½"},
+		{"3/4", NewFraction(3, 4), "¾"},
+		{"1 1/2", NewFraction(3, 2), "1 ½"},
+		{"1-1/2", NewFraction(3, 2), "1 ½"},
+		{"½", NewFraction(1, 2), "½"},
+		{"1½", NewFraction(3, 2), "1 ½"},
+		{" 2 ⅓ ", NewFraction(7, 3), "2 ⅓"},
+		{"0.1", NewFraction(1, 10), "1/10"},
+		{"5/7", NewFraction(5, 7), "5/7"},
+	}
+	for _, test := range tests {
+		got, err := ParseFraction(test.input)
+		if err != nil {
+			t.Errorf("ParseFraction(%q) returned error: %s", test.input, err)
+			continue
+		}
+		if got.Cmp(test.want) != 0 {
+			t.Errorf("ParseFraction(%q) = %s, want %s", test.input, got, test.want)
+		}
+		if kitchen := got.KitchenString(); kitchen != test.kitchen {
+			t.Errorf("ParseFraction(%q).KitchenString() = %q, want %q", test.input, kitchen, test.kitchen)
+		}
+	}
+}
+
+func TestParseFractionInvalid(t *testing.T) {
+	for _, input := range []string{"", "   ", "abc", "1/2/3", "one half"} {
+		if got, err := ParseFraction(input); err == nil {
+			t.Errorf("ParseFraction(%q) = %s, want an error", input, got)
+		}
+	}
+}
+
+func TestFractionString(t *testing.T) {
+	tests := []struct {
+		fraction Fraction
+		plain    string
+		decimal  string
+	}{
+		{Fraction{}, "0", "0"},
+		{NewFraction(3, 2), "1 1/2", "1.5"},
+		{NewFraction(-7, 4), "-1 3/4", "-1.75"},
+		{NewFraction(1, 3), "1/3", "0.33"},
+		{NewFraction(10, 5), "2", "2"},
+		{NewFraction(1, 0), "0", "0"},
+	}
+	for _, test := range tests {
+		if got := test.fraction.String(); got != test.plain {
+			t.Errorf("String() of %s = %q, want %q", test.fraction.DecimalString(4), got, test.plain)
+		}
+		if got := test.fraction.DecimalString(2); got != test.decimal {
+			t.Errorf("DecimalString(2) of %s = %q, want %q", test.fraction, got, test.decimal)
+		}
+	}
+}
+
+func TestFractionRoundTo(t *testing.T) {
+	tests := []struct {
+		fraction  Fraction
+		increment Fraction
+		want      Fraction
+	}{
+		{NewFraction(3, 10), NewFraction(1, 4), NewFraction(1, 4)},
+		{NewFraction(2, 5), NewFraction(1, 4), NewFraction(1, 2)},
+		{NewFraction(7, 8), NewFraction(1, 2), NewFraction(1, 1)},
+		// non zero values are never rounded down to zero
+		{NewFraction(1, 100), NewFraction(1, 4), NewFraction(1, 4)},
+		{NewFraction(-1, 100), NewFraction(1, 4), NewFraction(-1, 4)},
+		{Fraction{}, NewFraction(1, 4), Fraction{}},
+		// a zero increment leaves the value alone
+		{NewFraction(2, 3), Fraction{}, NewFraction(2, 3)},
+	}
+	for _, test := range tests {
+		if got := test.fraction.RoundTo(test.increment); got.Cmp(test.want) != 0 {
+			t.Errorf("%s.RoundTo(%s) = %s, want %s", test.fraction, test.increment, got, test.want)
+		}
+	}
+}
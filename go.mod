@@ -1,3 +1,5 @@
 module github.com/sww1235/recipe-database
 
-go 1.14
+go 1.21
+
+require github.com/mattn/go-sqlite3 v1.14.52
@@ -0,0 +1,60 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"strings"
+)
+
+//GTINLength is the length of a normalized GTIN. Every shorter GTIN, such as
+//a UPC-A or EAN-13, is padded with leading zeros to this length so the same
+//product is stored the same way however its barcode was entered.
+const GTINLength = 14
+
+//NormalizeGTIN checks that code is a valid GTIN-8, GTIN-12 (UPC-A), GTIN-13
+//(EAN-13) or GTIN-14 and returns it padded to GTINLength digits. Spaces and
+//dashes are ignored.
+func NormalizeGTIN(code string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, code)
+	switch len(digits) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("barcode %s has %d digits, expected 8, 12, 13 or 14", code, len(digits))
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("barcode %s contains %q, expected only digits", code, r)
+		}
+	}
+	check := GTINCheckDigit(digits[:len(digits)-1])
+	if int(digits[len(digits)-1]-'0') != check {
+		return "", fmt.Errorf("barcode %s has an invalid check digit, expected %d", code, check)
+	}
+	return strings.Repeat("0", GTINLength-len(digits)) + digits, nil
+}
+
+//ValidGTIN reports whether code is a valid GTIN of any length
+func ValidGTIN(code string) bool {
+	_, err := NormalizeGTIN(code)
+	return err == nil
+}
+
+//GTINCheckDigit returns the check digit for a GTIN without its check digit.
+//digits must only contain the characters 0 to 9.
+func GTINCheckDigit(digits string) int {
+	sum := 0
+	// weights alternate 3, 1, 3... starting from the rightmost digit
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			sum += digit * 3
+		} else {
+			sum += digit
+		}
+	}
+	return (10 - sum%10) % 10
+}
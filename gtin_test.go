@@ -0,0 +1,61 @@
+package recipeDatabase
+
+import "testing"
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"96385074", "00000096385074"},
+		{"036000291452", "00036000291452"},
+		{"0 36000 29145 2", "00036000291452"},
+		{"4006381333931", "04006381333931"},
+		{"400-6381-33393-1", "04006381333931"},
+		{"10012345678902", "10012345678902"},
+	}
+	for _, test := range tests {
+		got, err := NormalizeGTIN(test.input)
+		if err != nil {
+			t.Errorf("NormalizeGTIN(%q) returned error: %s", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("NormalizeGTIN(%q) = %q, want %q", test.input, got, test.want)
+		}
+		// a normalized GTIN normalizes to itself
+		if again, err := NormalizeGTIN(got); err != nil || again != got {
+			t.Errorf("NormalizeGTIN(%q) = %q, %v, want %q", got, again, err, got)
+		}
+	}
+}
+
+func TestNormalizeGTINInvalid(t *testing.T) {
+	for _, input := range []string{"", "036000291453", "4006381333932", "12345", "03600029145a",
+		"036000291452036"} {
+		if got, err := NormalizeGTIN(input); err == nil {
+			t.Errorf("NormalizeGTIN(%q) = %q, want an error", input, got)
+		}
+		if ValidGTIN(input) {
+			t.Errorf("ValidGTIN(%q) = true, want false", input)
+		}
+	}
+}
+
+func TestGTINCheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   int
+	}{
+		{"9638507", 4},
+		{"03600029145", 2},
+		{"400638133393", 1},
+		{"1001234567890", 2},
+		{"0000000", 0},
+	}
+	for _, test := range tests {
+		if got := GTINCheckDigit(test.digits); got != test.want {
+			t.Errorf("GTINCheckDigit(%q) = %d, want %d", test.digits, got, test.want)
+		}
+	}
+}
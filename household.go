@@ -0,0 +1,241 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//How a household member reacts to an ingredient, from most to least serious
+const (
+	ReactionAllergy     = "allergy"
+	ReactionIntolerance = "intolerance"
+	ReactionDislike     = "dislike"
+)
+
+//Reactions lists the reactions from most to least serious
+var Reactions = []string{ReactionAllergy, ReactionIntolerance, ReactionDislike}
+
+//A HouseholdMember is someone meals are cooked for, with what they can not
+//or will not eat. Each entry is either an ingredient class, such as peanuts
+//or dairy, or the name of an ingredient, such as mushroom.
+type HouseholdMember struct {
+	ID           int      // id of member in database
+	Name         string   // name of member
+	Allergies    []string // classes or ingredients they are allergic to
+	Intolerances []string // classes or ingredients they are intolerant of
+	Dislikes     []string // classes or ingredients they will not eat
+}
+
+func (m HouseholdMember) String() string {
+	stringString := m.Name + "\n"
+	if len(m.Allergies) > 0 {
+		stringString += "\tallergies: " + strings.Join(m.Allergies, ", ") + "\n"
+	}
+	if len(m.Intolerances) > 0 {
+		stringString += "\tintolerances: " + strings.Join(m.Intolerances, ", ") + "\n"
+	}
+	if len(m.Dislikes) > 0 {
+		stringString += "\tdislikes: " + strings.Join(m.Dislikes, ", ") + "\n"
+	}
+	return stringString
+}
+
+//Restrictions returns the entries of the member for reaction
+func (m HouseholdMember) Restrictions(reaction string) []string {
+	switch reaction {
+	case ReactionAllergy:
+		return m.Allergies
+	case ReactionIntolerance:
+		return m.Intolerances
+	case ReactionDislike:
+		return m.Dislikes
+	}
+	return nil
+}
+
+//SetRestrictions replaces the entries of the member for reaction. Entries
+//naming an ingredient class are stored as the class name, and entries naming
+//a group of classes, such as nuts, as every class in the group.
+func (m *HouseholdMember) SetRestrictions(reaction string, entries []string) error {
+	var cleaned []string
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		classes, err := ParseIngredientClasses(entry)
+		if err != nil {
+			cleaned = appendIfMissing(cleaned, entry)
+			continue
+		}
+		for _, class := range classes {
+			cleaned = appendIfMissing(cleaned, class)
+		}
+	}
+	switch reaction {
+	case ReactionAllergy:
+		m.Allergies = cleaned
+	case ReactionIntolerance:
+		m.Intolerances = cleaned
+	case ReactionDislike:
+		m.Dislikes = cleaned
+	default:
+		return fmt.Errorf("unknown reaction %s, expected one of %s", reaction, strings.Join(Reactions, ", "))
+	}
+	return nil
+}
+
+//A MemberConflict is an ingredient of a recipe that a household member
+//can not or will not eat
+type MemberConflict struct {
+	Member        string   `json:"member"`
+	Reaction      string   `json:"reaction"`
+	Restriction   string   `json:"restriction"` // class or ingredient the member reacts to
+	Ingredient    string   `json:"ingredient"`  // ingredient of the recipe
+	Substitutions []string `json:"substitutions,omitempty"`
+}
+
+func (c MemberConflict) String() string {
+	var stringString string
+	switch c.Reaction {
+	case ReactionAllergy:
+		stringString = c.Member + " is allergic to " + c.Restriction
+	case ReactionIntolerance:
+		stringString = c.Member + " is intolerant of " + c.Restriction
+	default:
+		stringString = c.Member + " dislikes " + c.Restriction
+	}
+	if CanonicalName(c.Ingredient) != CanonicalName(c.Restriction) {
+		if _, err := ParseIngredientClass(c.Restriction); err == nil {
+			stringString += ": " + c.Ingredient + " contains " + c.Restriction
+		} else {
+			stringString += ": " + c.Ingredient
+		}
+	}
+	if len(c.Substitutions) > 0 {
+		stringString += ", try " + strings.Join(c.Substitutions, " or ")
+	}
+	return stringString
+}
+
+//ConflictsString lists conflicts as warnings, one per line
+func ConflictsString(conflicts []MemberConflict) string {
+	stringString := ""
+	for _, conflict := range conflicts {
+		stringString += "Warning: " + conflict.String() + "\n"
+	}
+	return stringString
+}
+
+//reactsTo returns the entry of the member for reaction that an ingredient
+//containing classes matches, if any
+func (m HouseholdMember) reactsTo(reaction string, ingredient Ingredient, classes []string) (string, bool) {
+	words := nameWords(ingredient.Name)
+	for _, entry := range m.Restrictions(reaction) {
+		if _, err := ParseIngredientClass(entry); err == nil {
+			for _, class := range classes {
+				if class == entry {
+					return entry, true
+				}
+			}
+			continue
+		}
+		if CanonicalName(entry) == CanonicalName(ingredient.Name) || len(phraseSpans(words, entry)) > 0 {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+//safeFor reports whether the member can eat every ingredient of
+//replacement
+func (m HouseholdMember) safeFor(replacement []Ingredient, catalog []CatalogIngredient) bool {
+	for _, ingredient := range replacement {
+		classes := ingredientContains(ingredient, catalog)
+		for _, reaction := range Reactions {
+			if _, ok := m.reactsTo(reaction, ingredient, classes); ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//Conflicts returns the ingredients of recipe the member can not or will not
+//eat, with the substitutions for each that the member can eat
+func (m HouseholdMember) Conflicts(recipe Recipe, catalog []CatalogIngredient,
+	substitutions []Substitution) []MemberConflict {
+	var conflicts []MemberConflict
+	for _, ingredient := range recipe.Ingredients {
+		classes := ingredientContains(ingredient, catalog)
+		for _, reaction := range Reactions {
+			entry, ok := m.reactsTo(reaction, ingredient, classes)
+			if !ok {
+				continue
+			}
+			conflict := MemberConflict{Member: m.Name, Reaction: reaction, Restriction: entry,
+				Ingredient: ingredient.Name}
+			for _, substitution := range SubstitutionsFor(ingredient.Name, substitutions) {
+				replacement, err := substitution.For(ingredient.QuantityNeeded)
+				if err != nil {
+					replacement = substitution.Replacement
+				}
+				if m.safeFor(replacement, catalog) {
+					conflict.Substitutions = appendIfMissing(conflict.Substitutions, replacementString(replacement))
+				}
+			}
+			conflicts = append(conflicts, conflict)
+			// only the most serious reaction to an ingredient is reported
+			break
+		}
+	}
+	return conflicts
+}
+
+//HouseholdConflicts returns the conflicts of recipe with every one of
+//members, most serious first
+func HouseholdConflicts(recipe Recipe, members []HouseholdMember, catalog []CatalogIngredient,
+	substitutions []Substitution) []MemberConflict {
+	var conflicts []MemberConflict
+	for _, member := range members {
+		conflicts = append(conflicts, member.Conflicts(recipe, catalog, substitutions)...)
+	}
+	sort.SliceStable(conflicts, func(a, b int) bool {
+		return reactionOrder(conflicts[a].Reaction) < reactionOrder(conflicts[b].Reaction)
+	})
+	return conflicts
+}
+
+//reactionOrder returns the position of reaction in Reactions
+func reactionOrder(reaction string) int {
+	for i, known := range Reactions {
+		if reaction == known {
+			return i
+		}
+	}
+	return len(Reactions)
+}
+
+//MembersNamed returns the members with the names given, ignoring case, or
+//every member if names is empty
+func MembersNamed(members []HouseholdMember, names []string) ([]HouseholdMember, error) {
+	if len(names) == 0 {
+		return members, nil
+	}
+	var named []HouseholdMember
+	for _, name := range names {
+		found := false
+		for _, member := range members {
+			if strings.EqualFold(member.Name, strings.TrimSpace(name)) {
+				named = append(named, member)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no household member named %s", name)
+		}
+	}
+	return named, nil
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const householdUsage = `usage: cookbook household <command> [arguments]
+
+commands:
+	list                       list household members and what they avoid
+	add [flags] <name>         add a household member
+	set [flags] <name>         change what a household member avoids, only
+	                           the flags given are changed
+	remove <name>              remove a household member
+	check [-for <names>] <recipe name>
+		warn about the ingredients of a recipe that household members, or
+		only the comma separated members given, can not or will not eat
+
+flags of add and set:
+	-allergies <list>          comma separated classes or ingredients the
+	                           member is allergic to, ie: peanuts,shellfish
+	-intolerances <list>       classes or ingredients the member is
+	                           intolerant of, ie: dairy
+	-dislikes <list>           classes or ingredients the member will not
+	                           eat, ie: mushroom,olive
+
+classes are meat, fish, shellfish, dairy, egg, honey, gluten, tree nuts,
+peanuts, soy and sesame. nuts is both tree nuts and peanuts, and seafood is
+both fish and shellfish. Any other entry matches ingredients by name.
+
+Viewing a recipe with -r warns about every household member. Meals planned
+with plan add -for only warn about the members eating them, and canmake
+takes -for to rank recipes for some members.
+`
+
+//householdCommand runs the household subcommand given on the command line
+func householdCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(householdUsage)
+		return errors.New("no household command given")
+	}
+	switch args[0] {
+	case "list":
+		members, err := selectHousehold(db)
+		if err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			fmt.Println("No household members")
+		}
+		for _, member := range members {
+			fmt.Print(member)
+		}
+	case "add", "set":
+		flags := flag.NewFlagSet("household "+args[0], flag.ContinueOnError)
+		allergies := flags.String("allergies", "", "Comma separated classes or ingredients the member is allergic to")
+		intolerances := flags.String("intolerances", "", "Comma separated classes or ingredients the member is intolerant of")
+		dislikes := flags.String("dislikes", "", "Comma separated classes or ingredients the member will not eat")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			fmt.Print(householdUsage)
+			return fmt.Errorf("household %s needs the name of a member", args[0])
+		}
+		member := backend.HouseholdMember{Name: strings.TrimSpace(flags.Arg(0))}
+		if args[0] == "set" {
+			var err error
+			if member, err = findMember(db, member.Name); err != nil {
+				return err
+			}
+		}
+		var err error
+		flags.Visit(func(f *flag.Flag) {
+			var entries string
+			var reaction string
+			switch f.Name {
+			case "allergies":
+				entries, reaction = *allergies, backend.ReactionAllergy
+			case "intolerances":
+				entries, reaction = *intolerances, backend.ReactionIntolerance
+			case "dislikes":
+				entries, reaction = *dislikes, backend.ReactionDislike
+			}
+			if err == nil {
+				err = member.SetRestrictions(reaction, splitTags(entries))
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if err = saveMember(db, &member); err != nil {
+			return err
+		}
+		fmt.Print(member)
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: cookbook household remove <name>")
+		}
+		member, err := findMember(db, args[1])
+		if err != nil {
+			return err
+		}
+		if err = deleteMember(db, member.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", member.Name)
+	case "check":
+		flags := flag.NewFlagSet("household check", flag.ContinueOnError)
+		eaters := flags.String("for", "", "Comma separated household members eating, default everyone")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: cookbook household check [-for <names>] <recipe name>")
+		}
+		recipe, err := chooseRecipe(db, flags.Arg(0), bufio.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		check, err := newHouseholdCheck(db)
+		if err != nil {
+			return err
+		}
+		conflicts, err := check.conflicts(recipe, splitTags(*eaters))
+		if err != nil {
+			return err
+		}
+		if len(conflicts) == 0 {
+			fmt.Printf("%s suits everyone eating\n", recipe.Name)
+			return nil
+		}
+		fmt.Print(recipe.Name + "\n" + backend.ConflictsString(conflicts))
+	default:
+		fmt.Print(householdUsage)
+		return fmt.Errorf("unknown household command %s", args[0])
+	}
+	return nil
+}
+
+//selectHousehold reads every household member with what they avoid,
+//ordered by name
+func selectHousehold(q queryer) ([]backend.HouseholdMember, error) {
+	rows, err := q.Query("SELECT householdMembers.id, householdMembers.name, " +
+		"IFNULL(memberRestrictions.reaction, ''), IFNULL(memberRestrictions.item, '') FROM householdMembers " +
+		"LEFT JOIN memberRestrictions ON householdMembers.id = memberRestrictions.memberID " +
+		"ORDER BY householdMembers.name COLLATE NOCASE, householdMembers.id, memberRestrictions.rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var members []backend.HouseholdMember
+	for rows.Next() {
+		var member backend.HouseholdMember
+		var reaction, item string
+		if err = rows.Scan(&member.ID, &member.Name, &reaction, &item); err != nil {
+			return nil, err
+		}
+		if len(members) == 0 || members[len(members)-1].ID != member.ID {
+			members = append(members, member)
+		}
+		last := &members[len(members)-1]
+		switch reaction {
+		case backend.ReactionAllergy:
+			last.Allergies = append(last.Allergies, item)
+		case backend.ReactionIntolerance:
+			last.Intolerances = append(last.Intolerances, item)
+		case backend.ReactionDislike:
+			last.Dislikes = append(last.Dislikes, item)
+		}
+	}
+	return members, rows.Err()
+}
+
+//findMember returns the household member with name, ignoring case
+func findMember(db *sql.DB, name string) (backend.HouseholdMember, error) {
+	members, err := selectHousehold(db)
+	if err != nil {
+		return backend.HouseholdMember{}, err
+	}
+	found, err := backend.MembersNamed(members, []string{name})
+	if err != nil {
+		return backend.HouseholdMember{}, err
+	}
+	return found[0], nil
+}
+
+//saveMember stores a new household member, or replaces what an existing
+//one avoids, and sets the id of a new member
+func saveMember(db *sql.DB, member *backend.HouseholdMember) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if member.ID == 0 {
+		result, err := tx.Exec("INSERT INTO householdMembers (name) VALUES (?)", member.Name)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not add %s, household member names must be unique: %s", member.Name, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		member.ID = int(id)
+	} else if _, err = tx.Exec("DELETE FROM memberRestrictions WHERE memberID = ?", member.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, reaction := range backend.Reactions {
+		for _, item := range member.Restrictions(reaction) {
+			_, err = tx.Exec("INSERT INTO memberRestrictions (memberID, reaction, item) VALUES (?, ?, ?)",
+				member.ID, reaction, item)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+//deleteMember removes a household member, and them from the meals they
+//were planned to eat
+func deleteMember(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, query := range []string{"DELETE FROM mealEaters WHERE memberID = ?",
+		"DELETE FROM memberRestrictions WHERE memberID = ?", "DELETE FROM householdMembers WHERE id = ?"} {
+		if _, err = tx.Exec(query, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+//householdCheck holds the household, catalog and substitutions used to
+//warn about recipes, so they are read once when checking many recipes
+type householdCheck struct {
+	members       []backend.HouseholdMember
+	catalog       []backend.CatalogIngredient
+	substitutions []backend.Substitution
+}
+
+//newHouseholdCheck reads the household, and the catalog and substitutions
+//once there is anyone to check for
+func newHouseholdCheck(db *sql.DB) (householdCheck, error) {
+	var check householdCheck
+	var err error
+	if check.members, err = selectHousehold(db); err != nil || len(check.members) == 0 {
+		return check, err
+	}
+	if check.catalog, err = selectCatalog(db); err != nil {
+		return check, err
+	}
+	check.substitutions, err = selectSubstitutions(db)
+	return check, err
+}
+
+//conflicts returns the ingredients of recipe that the members named, or
+//every member if names is empty, can not or will not eat
+func (h householdCheck) conflicts(recipe backend.Recipe, names []string) ([]backend.MemberConflict, error) {
+	members, err := backend.MembersNamed(h.members, names)
+	if err != nil {
+		return nil, err
+	}
+	return backend.HouseholdConflicts(recipe, members, h.catalog, h.substitutions), nil
+}
+
+//checkMealPlan sets the warnings of every meal in plan for the members
+//eating it
+func checkMealPlan(db *sql.DB, plan *backend.MealPlan) error {
+	check, err := newHouseholdCheck(db)
+	if err != nil || len(check.members) == 0 {
+		return err
+	}
+	for i := range plan.Meals {
+		plan.Meals[i].Check(check.members, check.catalog, check.substitutions)
+	}
+	return nil
+}
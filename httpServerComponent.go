@@ -1,5 +1,87 @@
 package main
 
-func startHTTPServer() error {
-	return nil
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+//defaultServerPort is used when the server address has no port
+const defaultServerPort = "8080"
+
+func startHTTPServer(db *sql.DB) error {
+	address := httpServerFlagIP
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultServerPort)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/canmake", func(w http.ResponseWriter, r *http.Request) {
+		canMakeHTTP(db, w, r)
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		statsHTTP(db, w, r)
+	})
+	mux.HandleFunc("/suggest", func(w http.ResponseWriter, r *http.Request) {
+		suggestHTTP(db, w, r)
+	})
+
+	infoLogger.Printf("Starting HTTP server on http://%s", address)
+	return http.ListenAndServe(address, mux)
+}
+
+//wantsJSON reports whether a request asked for JSON with ?format=json
+func wantsJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "json"
+}
+
+//writeJSON sends v as the JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	bytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
+//canMakeHTTP ranks recipes by inventory coverage. Query parameters match
+//the canmake command: staples, tags, diet, missing, for and format.
+func canMakeHTTP(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	options := backend.CoverageOptions{Tags: splitTags(query.Get("tags")), MaxMissing: 2}
+	options.IgnoreStaples, _ = strconv.ParseBool(query.Get("staples"))
+	var err error
+	if options.Diets, err = backend.ParseDiets(query.Get("diet")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if missing := query.Get("missing"); missing != "" {
+		options.MaxMissing, err = strconv.Atoi(missing)
+		if err != nil {
+			http.Error(w, "missing must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+	ranked, err := rankRecipes(db, options, splitTags(query.Get("for")))
+	if err != nil {
+		infoLogger.Println("Error ranking recipes:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if wantsJSON(r) {
+		if ranked == nil {
+			ranked = []backend.RecipeCoverage{}
+		}
+		writeJSON(w, ranked)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, canMakeString(ranked))
 }
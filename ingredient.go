@@ -4,35 +4,167 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"strconv"
+	"strings"
 )
 
 //The Ingredient struct stores data for a particular Ingredient
 //used in a recipe
 type Ingredient struct {
+	ID                 int // id of ingredient in database
+	CatalogID          int // id of ingredient in the ingredient catalog, 0 if not linked
 	Name               string
 	UPC                string
-	QuantityNeeded     float64
-	IngredientUnit     ingredientUnit
+	QuantityNeeded     Quantity
 	InDatabase         bool
 	QuantityInDatabase int
 	Conversions        []conversion
+	ScaleNote          string // set when ingredient may not scale linearly
+	IsFlour            bool   // ingredient is part of the flour base for baker's percentages
 }
 
-//Type ingredientUnit can be any value but only certain values are
-//able to be converted at present. Values that are unable to be converted
-//will not be able to be selected.
-type ingredientUnit string
-
 func (i Ingredient) String() string {
-	stringString := fmt.Sprintf("%s: %G %s(s)\n", i.Name, i.QuantityNeeded, i.IngredientUnit)
+	stringString := fmt.Sprintf("%s: %s\n", i.Name, i.QuantityNeeded.Format())
+	if i.ScaleNote != "" {
+		stringString += fmt.Sprintf("\t\t(%s)\n", i.ScaleNote)
+	}
 
 	return stringString
 }
 
-//ConvertString acts like String() but allows for conversion between units
+//CanonicalName returns the name used to decide whether two ingredients are
+//the same thing, ignoring case, extra spaces, plurals and aliases, so "Eggs"
+//and "egg" match, as do "plain flour" and "all purpose flour".
+func CanonicalName(name string) string {
+	normalized := normalizeName(name)
+	if alias, ok := ingredientAliases[normalized]; ok {
+		return alias
+	}
+	return normalized
+}
+
+//normalizeName lowercases name, collapses spaces and makes the last word
+//singular
+func normalizeName(name string) string {
+	words := strings.Fields(strings.ToLower(name))
+	if len(words) == 0 {
+		return ""
+	}
+	words[len(words)-1] = singularWord(words[len(words)-1])
+	return strings.Join(words, " ")
+}
+
+//ingredientAliases maps other names for an ingredient to the name used for
+//it. Both are in the form returned by normalizeName.
+var ingredientAliases = map[string]string{
+	"flour":                "all purpose flour",
+	"all-purpose flour":    "all purpose flour",
+	"ap flour":             "all purpose flour",
+	"plain flour":          "all purpose flour",
+	"caster sugar":         "superfine sugar",
+	"icing sugar":          "powdered sugar",
+	"confectioners sugar":  "powdered sugar",
+	"white sugar":          "sugar",
+	"granulated sugar":     "sugar",
+	"bicarbonate of soda":  "baking soda",
+	"double cream":         "heavy cream",
+	"heavy whipping cream": "heavy cream",
+	"scallion":             "green onion",
+	"spring onion":         "green onion",
+	"coriander leaf":       "cilantro",
+	"courgette":            "zucchini",
+	"aubergine":            "eggplant",
+	"garbanzo bean":        "chickpea",
+	"capsicum":             "bell pepper",
+}
+
+//AddIngredientAlias makes alias another name for the ingredient name, so
+//recipes and inventory using either are matched
+func AddIngredientAlias(alias string, name string) {
+	name = CanonicalName(name)
+	alias = normalizeName(alias)
+	if alias != name {
+		ingredientAliases[alias] = name
+	}
+}
+
+//nonLinearIngredients are words of ingredient names that do not scale
+//linearly with the rest of a recipe, mostly leaveners, salt and spices.
+var nonLinearIngredients = []string{
+	"baking powder", "baking soda", "yeast", "salt", "pepper", "spice",
+	"cinnamon", "nutmeg", "clove", "cumin", "paprika", "cayenne", "chili",
+	"chilli", "ginger", "allspice", "cardamom", "turmeric", "oregano",
+	"thyme", "rosemary", "vanilla", "extract",
+}
+
+//linearIngredients are ingredients named with one of nonLinearIngredients
+//that still scale linearly, such as bell peppers
+var linearIngredients = []string{"bell pepper", "sweet pepper", "garlic clove", "clove of garlic"}
+
+//scalesLinearly reports whether an ingredient named name can be scaled with
+//the rest of a recipe. Whole words are matched, so unsalted butter is not
+//salt.
+func scalesLinearly(name string) bool {
+	words := nameWords(name)
+	for _, phrase := range linearIngredients {
+		if len(phraseSpans(words, phrase)) > 0 {
+			return true
+		}
+	}
+	for _, phrase := range nonLinearIngredients {
+		if len(phraseSpans(words, phrase)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//ParseIngredient reads an ingredient written as it would be in a recipe,
+//such as "1 1/2 cups milk" or "2 eggs". The first word after the amount is
+//the unit only when it is a known unit.
+func ParseIngredient(s string) (Ingredient, error) {
+	quantity, err := ParseQuantity(s)
+	if err != nil {
+		return Ingredient{}, err
+	}
+	name := quantity.Unit
+	quantity.Unit = ""
+	fields := strings.Fields(name)
+	if len(fields) > 1 {
+		if _, ok := lookupUnit(fields[0]); ok {
+			quantity.Unit = fields[0]
+			name = strings.Join(fields[1:], " ")
+		}
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Ingredient{}, fmt.Errorf("no ingredient name in %q", s)
+	}
+	return Ingredient{Name: name, QuantityNeeded: quantity}, nil
+}
+
+//Scale returns a copy of the ingredient with QuantityNeeded multiplied by
+//factor. The result is normalized into a sensible unit where a standard
+//conversion exists, and ScaleNote is set if the ingredient is one that does
+//not scale linearly.
+func (i Ingredient) Scale(factor float64) Ingredient {
+	scaled := i
+	scaled.QuantityNeeded = i.QuantityNeeded.Mul(FractionFromFloat(factor)).Normalize()
+
+	if factor != 1 && !scalesLinearly(i.Name) {
+		scaled.ScaleNote = fmt.Sprintf("scaled %Gx, may not scale linearly, adjust to taste", factor)
+	}
+	return scaled
+}
+
+//ConvertString acts like String() but allows for conversion between units.
+//If there is no way to convert to toUnit, the unconverted String() is
+//returned.
 func (i Ingredient) ConvertString(toUnit string) string {
-	return "fixme" //TODO: implement
+	converted, err := i.convertQuantity(toUnit)
+	if err != nil {
+		return i.String()
+	}
+	return fmt.Sprintf("%s: %s\n", i.Name, FormatQuantity(converted, toUnit))
 }
 
 //AddConversion adds a conversion factor to an ingredient
@@ -51,31 +183,51 @@ type conversion struct {
 
 // ReadIngredient creates an ingredient struct by prompting user for input
 func ReadIngredient() (Ingredient, error) {
+	return readIngredient(bufio.NewReader(os.Stdin))
+}
+
+//readIngredient prompts for an ingredient using an existing reader, so
+//input buffered while reading a recipe is not lost
+func readIngredient(reader *bufio.Reader) (Ingredient, error) {
 	var tempIngredient Ingredient
 
-	reader := bufio.NewReader(os.Stdin)
 	fmt.Print("Enter ingredient Name: ")
 	tempString, err := reader.ReadString('\n')
 	if err != nil {
 		return tempIngredient, err
 	}
-	tempIngredient.Name = tempString
+	tempIngredient.Name = strings.TrimSpace(tempString)
 
 	fmt.Print("Enter ingredient UPC: ")
 	tempString, err = reader.ReadString('\n')
 	if err != nil {
 		return tempIngredient, err
 	}
-	tempIngredient.UPC = tempString
+	tempIngredient.UPC = strings.TrimSpace(tempString)
+	if tempIngredient.UPC != "" {
+		tempIngredient.UPC, err = NormalizeGTIN(tempIngredient.UPC)
+		if err != nil {
+			return tempIngredient, err
+		}
+	}
 
 	fmt.Print("Enter ingredient Quantity: ")
 	tempString, err = reader.ReadString('\n')
 	if err != nil {
 		return tempIngredient, err
 	}
-	tempQty, err := strconv.ParseFloat(tempString, 64)
+	tempQty, err := ParseFraction(tempString)
+	if err != nil {
+		return tempIngredient, err
+	}
+	tempIngredient.QuantityNeeded.Amount = tempQty
 
-	tempIngredient.QuantityNeeded = tempQty
+	fmt.Print("Enter ingredient Unit: ")
+	tempString, err = reader.ReadString('\n')
+	if err != nil {
+		return tempIngredient, err
+	}
+	tempIngredient.QuantityNeeded.Unit = strings.TrimSpace(tempString)
 
 	return tempIngredient, nil
 
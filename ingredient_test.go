@@ -0,0 +1,26 @@
+package recipeDatabase
+
+import "testing"
+
+func TestScalesLinearly(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"flour", true},
+		{"unsalted butter", true},
+		{"red bell pepper", true},
+		{"garlic cloves", true},
+		{"kosher salt", false},
+		{"black pepper", false},
+		{"instant yeast", false},
+		{"ground cloves", false},
+		{"vanilla extract", false},
+		{"pumpkin pie spices", false},
+	}
+	for _, test := range tests {
+		if got := scalesLinearly(test.name); got != test.want {
+			t.Errorf("scalesLinearly(%q) = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package recipeDatabase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//DateFormat is the layout used for dates stored in the database and given
+//on the command line
+const DateFormat = "2006-01-02"
+
+//Standard storage locations for inventory lots. Any other location name can
+//also be used.
+const (
+	LocationPantry  = "pantry"
+	LocationFridge  = "fridge"
+	LocationFreezer = "freezer"
+)
+
+//ParseLocation cleans up a storage location name. Empty locations are the
+//pantry.
+func ParseLocation(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return LocationPantry
+	case "refrigerator", "fridge":
+		return LocationFridge
+	}
+	return s
+}
+
+//An InventoryLot is part of the stock of an inventory item bought at the
+//same time and kept in the same place, such as one bag of flour in the
+//freezer.
+type InventoryLot struct {
+	ID        int       // id of lot in database
+	Quantity  Fraction  // number of packages in lot
+	Purchased time.Time // date lot was bought, zero if unknown
+	Expires   time.Time // best before date, zero if it does not expire
+	Location  string    // where lot is stored, ie: pantry
+}
+
+func (lot InventoryLot) String() string {
+	stringString := fmt.Sprintf("%s packages in %s", lot.Quantity.DecimalString(2), lot.Location)
+	if !lot.Purchased.IsZero() {
+		stringString += ", bought " + lot.Purchased.Format(DateFormat)
+	}
+	if !lot.Expires.IsZero() {
+		stringString += ", expires " + lot.Expires.Format(DateFormat)
+	}
+	return stringString
+}
+
+//ExpiresBefore reports whether the lot expires before date
+func (lot InventoryLot) ExpiresBefore(date time.Time) bool {
+	return !lot.Expires.IsZero() && lot.Expires.Before(date)
+}
+
+//An InventoryItem is a product kept in the kitchen, such as a 5 lb bag of
+//flour. Quantity is the number of packages on hand, which can be partial
+//once some of a package has been used.
+type InventoryItem struct {
+	ID              int      // id of item in database
+	EAN             string   // barcode data
+	Name            string   // short name of item
+	Description     string   // description of item
+	Quantity        Fraction // number of packages on hand
+	PackageQuantity Quantity // amount of item in one package
+	MinimumStock    Fraction // packages to keep on hand, zero if not tracked
+	ReorderQuantity Fraction // packages to buy when below MinimumStock
+	// lots the packages on hand are split into. Packages not in any lot,
+	// such as stock from before lots were tracked, are used last.
+	Lots []InventoryLot
+}
+
+func (item InventoryItem) String() string {
+	stringString := fmt.Sprintf("%d) %s", item.ID, item.Name)
+	if item.EAN != "" {
+		stringString += fmt.Sprintf(" [%s]", item.EAN)
+	}
+	stringString += fmt.Sprintf(": %s packages", item.Quantity.DecimalString(2))
+	if !item.PackageQuantity.IsZero() {
+		stringString += fmt.Sprintf(" of %s (%s on hand)", item.PackageQuantity.Format(),
+			item.OnHand().Format())
+	}
+	stringString += "\n"
+	if item.Description != "" {
+		stringString += "\t" + item.Description + "\n"
+	}
+	for _, lot := range item.Lots {
+		if lot.Quantity.Sign() > 0 {
+			stringString += "\t" + lot.String() + "\n"
+		}
+	}
+	if item.MinimumStock.Sign() > 0 {
+		stringString += fmt.Sprintf("\tKeep %s packages", item.MinimumStock.DecimalString(2))
+		if item.ReorderQuantity.Sign() > 0 {
+			stringString += fmt.Sprintf(", reorder %s", item.ReorderQuantity.DecimalString(2))
+		}
+		stringString += "\n"
+	}
+	return stringString
+}
+
+//AddLot adds a lot of newly bought packages to the item
+func (item *InventoryItem) AddLot(lot InventoryLot) {
+	lot.Location = ParseLocation(lot.Location)
+	item.Lots = append(item.Lots, lot)
+	item.Quantity = item.Quantity.Add(lot.Quantity)
+}
+
+//SetQuantity sets the number of packages on hand, as after counting them.
+//Packages that are gone are taken from the lots first expiring first, and
+//extra packages are not put in any lot.
+func (item *InventoryItem) SetQuantity(packages Fraction) {
+	if packages.Cmp(item.Quantity) < 0 {
+		item.takeFromLots(item.Quantity.Sub(packages))
+	}
+	item.Quantity = packages
+}
+
+//sortLots orders the lots first expiring first out. Lots that do not
+//expire come last, and lots expiring on the same day are used oldest first.
+func (item *InventoryItem) sortLots() {
+	sort.SliceStable(item.Lots, func(a, b int) bool {
+		lotA, lotB := item.Lots[a], item.Lots[b]
+		if !lotA.Expires.Equal(lotB.Expires) {
+			if lotA.Expires.IsZero() || lotB.Expires.IsZero() {
+				return lotB.Expires.IsZero()
+			}
+			return lotA.Expires.Before(lotB.Expires)
+		}
+		return lotA.Purchased.Before(lotB.Purchased)
+	})
+}
+
+//takeFromLots removes packages from the lots, first expiring first out.
+//Whatever the lots do not cover comes from packages not in any lot, so
+//item.Quantity is left for the caller to update.
+func (item *InventoryItem) takeFromLots(packages Fraction) {
+	item.sortLots()
+	for i := range item.Lots {
+		if packages.Sign() <= 0 {
+			return
+		}
+		lot := &item.Lots[i]
+		taken := lot.Quantity
+		if packages.Cmp(taken) < 0 {
+			taken = packages
+		}
+		lot.Quantity = lot.Quantity.Sub(taken)
+		packages = packages.Sub(taken)
+	}
+}
+
+//OnHand returns the total amount of the item in stock, in the units of its
+//package
+func (item InventoryItem) OnHand() Quantity {
+	if item.PackageQuantity.IsZero() {
+		return NewQuantity(item.Quantity, "")
+	}
+	return item.PackageQuantity.Mul(item.Quantity)
+}
+
+//PackagesOf returns how many packages of the item amount is. amount must be
+//convertable to the unit of the package, directly or through the density of
+//the item. Items without a package size are counted in packages, so amount
+//must be a plain count.
+func (item InventoryItem) PackagesOf(amount Quantity) (Fraction, error) {
+	if item.PackageQuantity.IsZero() {
+		if amount.Unit != "" {
+			return Fraction{}, fmt.Errorf("%s has no package size to convert %s to", item.Name, amount.Unit)
+		}
+		return amount.Amount, nil
+	}
+	// converting as an ingredient allows cups of flour to be taken from
+	// a package sold by weight
+	converted, err := Ingredient{Name: item.Name, QuantityNeeded: amount}.convertQuantity(item.PackageQuantity.Unit)
+	if err != nil {
+		return Fraction{}, err
+	}
+	return converted.Div(item.PackageQuantity.Amount), nil
+}
+
+//Consume removes amount from the packages on hand, first expiring first
+//out, and returns the part of amount that was not in stock. The quantity on
+//hand never goes below zero.
+func (item *InventoryItem) Consume(amount Quantity) (Quantity, error) {
+	if amount.Amount.Sign() < 0 {
+		return Quantity{}, errors.New("cannot consume a negative amount")
+	}
+	packages, err := item.PackagesOf(amount)
+	if err != nil {
+		return Quantity{}, err
+	}
+	remaining := item.Quantity.Sub(packages)
+	if remaining.Sign() >= 0 {
+		item.SetQuantity(remaining)
+		return NewQuantity(Fraction{}, amount.Unit), nil
+	}
+	item.SetQuantity(Fraction{})
+	short := remaining.Abs()
+	if item.PackageQuantity.IsZero() {
+		return NewQuantity(short, ""), nil
+	}
+	return item.PackageQuantity.Mul(short), nil
+}
+
+//An ExpiringLot is a lot that expires soon, with the recipes that could use
+//it up
+type ExpiringLot struct {
+	Item    InventoryItem
+	Lot     InventoryLot
+	Recipes []string
+}
+
+//ExpiringLots returns every lot with packages left that expires before
+//date, soonest first
+func ExpiringLots(items []InventoryItem, date time.Time) []ExpiringLot {
+	var expiring []ExpiringLot
+	for _, item := range items {
+		for _, lot := range item.Lots {
+			if lot.Quantity.Sign() > 0 && lot.ExpiresBefore(date) {
+				expiring = append(expiring, ExpiringLot{Item: item, Lot: lot})
+			}
+		}
+	}
+	sort.SliceStable(expiring, func(a, b int) bool {
+		return expiring[a].Lot.Expires.Before(expiring[b].Lot.Expires)
+	})
+	return expiring
+}
+
+//Report describes the lot and when it expires relative to now
+func (e ExpiringLot) Report(now time.Time) string {
+	days := int(e.Lot.Expires.Sub(now).Hours() / 24)
+	when := fmt.Sprintf("expires in %d days", days)
+	switch {
+	case e.Lot.Expires.Before(now):
+		when = "EXPIRED"
+	case days == 0:
+		when = "expires today"
+	case days == 1:
+		when = "expires tomorrow"
+	}
+	stringString := fmt.Sprintf("%s %s: %s, %s\n", e.Lot.Expires.Format(DateFormat), e.Item.Name,
+		when, e.Lot.String())
+	if len(e.Recipes) > 0 {
+		stringString += "\tUse in: " + strings.Join(e.Recipes, ", ") + "\n"
+	}
+	return stringString
+}
@@ -0,0 +1,809 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const inventoryUsage = `usage: cookbook inventory <command> [arguments]
+
+commands:
+	new -name <name> [-ean <barcode>] [-desc <description>] [-package <size>] [-count <packages>] [lot flags]
+		create a new inventory item, ie: -name flour -package "5 lb"
+		fields not given are filled in from the product catalog by barcode
+	add [lot flags] <item> [packages]
+		add purchased packages of item as a new lot, default 1
+	use <item> <quantity>      record using part of item, ie: use flour "2 cups"
+	adjust <item> <packages>   set the number of packages of item on hand
+	list                       list all inventory items
+	search <term>              list inventory items with term in their name or description
+	link <item> <ingredient>   link item to every ingredient named ingredient
+	expiring [-days <days>]    list lots expiring in the next days, default 7,
+	                           with the recipes that use them
+	threshold <item> <minimum> [reorder]
+		set the packages of item to keep on hand, and how many to buy when below it
+	low [-predict] [-history <days>]
+		list items below their minimum, and with -predict when every used item
+		will run out at the rate it was used over the last days, default 60
+	label <item>               print a freezer label for every lot of item on hand,
+	                           listing the contents of leftovers kept by cook -leftovers
+
+lot flags:
+	-purchased <date>   date packages were bought, default today
+	-expires <date>     best before date of packages
+	-location <place>   where packages are kept: pantry, fridge, freezer or any other place
+	-price <price>      price paid for one package, recorded in the item's price history
+	-store <store>      store the packages were bought at
+
+<item> is either the id or the barcode of an inventory item
+Packages are used first expiring first out. Dates are given as YYYY-MM-DD.
+`
+
+//inventorySelect is the column list used by every query that reads
+//inventory items
+const inventorySelect = "SELECT inventory.id, IFNULL(inventory.EAN, ''), " +
+	"IFNULL(inventory.name, ''), IFNULL(inventory.description, ''), " +
+	"inventory.quantity, inventory.packageQuantity, IFNULL(units.name, ''), " +
+	"inventory.minimumStock, inventory.reorderQuantity " +
+	"FROM inventory LEFT JOIN units ON inventory.packageQuantityUnits = units.id "
+
+//inventoryCommand runs the inventory subcommand given on the command line
+func inventoryCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(inventoryUsage)
+		return errors.New("no inventory command given")
+	}
+	switch args[0] {
+	case "new":
+		flags := flag.NewFlagSet("inventory new", flag.ContinueOnError)
+		name := flags.String("name", "", "Name of item")
+		ean := flags.String("ean", "", "Barcode of item")
+		description := flags.String("desc", "", "Description of item")
+		packageSize := flags.String("package", "", "Amount in one package, ie: \"5 lb\"")
+		count := flags.String("count", "0", "Number of packages on hand")
+		lotFlags := addLotFlags(flags)
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		item := backend.InventoryItem{Name: *name, Description: *description}
+		var err error
+		if *ean != "" {
+			item, err = prefillInventoryItem(db, item, *ean)
+			if err != nil {
+				return err
+			}
+		}
+		if item.Name == "" {
+			return errors.New("inventory item needs a name")
+		}
+		if *packageSize != "" {
+			item.PackageQuantity, err = backend.ParseQuantity(*packageSize)
+			if err != nil {
+				return err
+			}
+		}
+		packages, err := backend.ParseFraction(*count)
+		if err != nil {
+			return err
+		}
+		lot, err := lotFlags.lot(packages)
+		if err != nil {
+			return err
+		}
+		if packages.Sign() > 0 {
+			item.AddLot(lot)
+		}
+		id, err := insertInventoryItem(db, item)
+		if err != nil {
+			return err
+		}
+		item.ID = int(id)
+		if err = lotFlags.recordPrice(db, item, lot); err != nil {
+			return err
+		}
+		fmt.Print(item.String())
+	case "add", "adjust":
+		flags := flag.NewFlagSet("inventory "+args[0], flag.ContinueOnError)
+		var lotFlags lotOptions
+		if args[0] == "add" {
+			lotFlags = addLotFlags(flags)
+		}
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		positional := flags.Args()
+		if len(positional) < 1 {
+			return fmt.Errorf("usage: cookbook inventory %s <item> <packages>", args[0])
+		}
+		packages := backend.NewFraction(1, 1)
+		if len(positional) > 1 {
+			var err error
+			packages, err = backend.ParseFraction(positional[1])
+			if err != nil {
+				return err
+			}
+		} else if args[0] == "adjust" {
+			return errors.New("usage: cookbook inventory adjust <item> <packages>")
+		}
+		item, err := findInventoryItem(db, positional[0])
+		if err != nil {
+			return err
+		}
+		before := item.Quantity
+		var lot backend.InventoryLot
+		if args[0] == "add" {
+			lot, err = lotFlags.lot(packages)
+			if err != nil {
+				return err
+			}
+			item.AddLot(lot)
+		} else {
+			item.SetQuantity(packages)
+		}
+		if err = saveInventoryItem(db, &item, before.Sub(item.Quantity), args[0]); err != nil {
+			return err
+		}
+		if err = lotFlags.recordPrice(db, item, lot); err != nil {
+			return err
+		}
+		fmt.Print(item.String())
+	case "use":
+		if len(args) < 3 {
+			return errors.New("usage: cookbook inventory use <item> <quantity>")
+		}
+		item, err := findInventoryItem(db, args[1])
+		if err != nil {
+			return err
+		}
+		amount, err := backend.ParseQuantity(strings.Join(args[2:], " "))
+		if err != nil {
+			return err
+		}
+		before := item.Quantity
+		short, err := item.Consume(amount)
+		if err != nil {
+			return err
+		}
+		if err = saveInventoryItem(db, &item, before.Sub(item.Quantity), "use"); err != nil {
+			return err
+		}
+		if !short.IsZero() {
+			fmt.Printf("Warning: only had enough %s for part of %s, %s short\n",
+				item.Name, amount.Format(), short.Format())
+		}
+		fmt.Print(item.String())
+	case "list", "search":
+		term := ""
+		if args[0] == "search" {
+			if len(args) < 2 {
+				return errors.New("usage: cookbook inventory search <term>")
+			}
+			term = strings.Join(args[1:], " ")
+		}
+		items, err := searchInventory(db, term)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			fmt.Print(item.String())
+		}
+	case "link":
+		if len(args) < 3 {
+			return errors.New("usage: cookbook inventory link <item> <ingredient>")
+		}
+		item, err := findInventoryItem(db, args[1])
+		if err != nil {
+			return err
+		}
+		ingredientName := strings.Join(args[2:], " ")
+		linked, err := linkIngredient(db, item.ID, ingredientName)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Linked %s to %d ingredients named %s\n", item.Name, linked, ingredientName)
+	case "threshold":
+		if len(args) < 3 {
+			return errors.New("usage: cookbook inventory threshold <item> <minimum packages> [reorder packages]")
+		}
+		item, err := findInventoryItem(db, args[1])
+		if err != nil {
+			return err
+		}
+		if item.MinimumStock, err = backend.ParseFraction(args[2]); err != nil {
+			return err
+		}
+		item.ReorderQuantity = backend.Fraction{}
+		if len(args) > 3 {
+			if item.ReorderQuantity, err = backend.ParseFraction(args[3]); err != nil {
+				return err
+			}
+		}
+		_, err = db.Exec("UPDATE inventory SET minimumStock = ?, reorderQuantity = ? WHERE id = ?",
+			item.MinimumStock, item.ReorderQuantity, item.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Print(item.String())
+	case "low":
+		flags := flag.NewFlagSet("inventory low", flag.ContinueOnError)
+		predict := flags.Bool("predict", false, "Predict when items will run out from their usage")
+		history := flags.Int("history", 60, "Days of usage history to predict from")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		report, err := lowStockReport(db, *predict, *history)
+		if err != nil {
+			return err
+		}
+		fmt.Print(report)
+	case "label":
+		if len(args) < 2 {
+			return errors.New("usage: cookbook inventory label <item>")
+		}
+		item, err := findInventoryItem(db, args[1])
+		if err != nil {
+			return err
+		}
+		labels, err := inventoryLabels(db, item)
+		if err != nil {
+			return err
+		}
+		for _, label := range labels {
+			fmt.Print(label.String())
+		}
+	case "expiring":
+		flags := flag.NewFlagSet("inventory expiring", flag.ContinueOnError)
+		days := flags.Int("days", 7, "Number of days ahead to look for expiring lots")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		report, err := expiringReport(db, *days)
+		if err != nil {
+			return err
+		}
+		if report == "" {
+			fmt.Printf("Nothing expires in the next %d days\n", *days)
+		}
+		fmt.Print(report)
+	default:
+		fmt.Print(inventoryUsage)
+		return fmt.Errorf("unknown inventory command %s", args[0])
+	}
+	return nil
+}
+
+//queryer is implemented by both *sql.DB and *sql.Tx, so items can be read
+//inside or outside a transaction
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+//selectInventory returns the inventory items, with their lots, matching the
+//joins and where clause appended to inventorySelect
+func selectInventory(q queryer, where string, args ...interface{}) ([]backend.InventoryItem, error) {
+	rows, err := q.Query(inventorySelect+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	items, err := scanInventoryItems(rows)
+	if err != nil || len(items) == 0 {
+		return items, err
+	}
+	if err = selectLots(q, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+//scanInventoryItems reads every row returned by a query using
+//inventorySelect
+func scanInventoryItems(rows *sql.Rows) ([]backend.InventoryItem, error) {
+	defer rows.Close()
+	var items []backend.InventoryItem
+	for rows.Next() {
+		var item backend.InventoryItem
+		err := rows.Scan(&item.ID, &item.EAN, &item.Name, &item.Description, &item.Quantity,
+			&item.PackageQuantity.Amount, &item.PackageQuantity.Unit, &item.MinimumStock,
+			&item.ReorderQuantity)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+//selectLots fills in the lots of items that still have packages in them
+func selectLots(q queryer, items []backend.InventoryItem) error {
+	index := make(map[int]int)
+	for i, item := range items {
+		index[item.ID] = i
+	}
+	rows, err := q.Query("SELECT id, inventoryID, quantity, IFNULL(purchased, ''), " +
+		"IFNULL(expires, ''), IFNULL(location, '') FROM inventoryLots ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var lot backend.InventoryLot
+		var inventoryID int
+		var purchased, expires string
+		err = rows.Scan(&lot.ID, &inventoryID, &lot.Quantity, &purchased, &expires, &lot.Location)
+		if err != nil {
+			return err
+		}
+		i, ok := index[inventoryID]
+		if !ok || lot.Quantity.Sign() <= 0 {
+			continue
+		}
+		if lot.Purchased, err = parseDate(purchased); err != nil {
+			return err
+		}
+		if lot.Expires, err = parseDate(expires); err != nil {
+			return err
+		}
+		items[i].Lots = append(items[i].Lots, lot)
+	}
+	return rows.Err()
+}
+
+//parseDate reads a date stored as YYYY-MM-DD, where empty is the zero time
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(backend.DateFormat, s)
+}
+
+//nullDate stores a date as YYYY-MM-DD, or NULL for the zero time
+func nullDate(date time.Time) sql.NullString {
+	if date.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: date.Format(backend.DateFormat), Valid: true}
+}
+
+//findInventoryItem looks up an inventory item by its id, or by its barcode
+func findInventoryItem(db *sql.DB, ref string) (backend.InventoryItem, error) {
+	var items []backend.InventoryItem
+	var err error
+	if id, convErr := strconv.Atoi(ref); convErr == nil && len(ref) < 8 {
+		items, err = selectInventory(db, "WHERE inventory.id = ?", id)
+	} else {
+		gtin, gtinErr := backend.NormalizeGTIN(ref)
+		if gtinErr != nil {
+			return backend.InventoryItem{}, gtinErr
+		}
+		items, err = selectInventory(db, "WHERE inventory.EAN = ?", gtin)
+	}
+	if err != nil {
+		return backend.InventoryItem{}, err
+	}
+	if len(items) == 0 {
+		return backend.InventoryItem{}, fmt.Errorf("no inventory item %s", ref)
+	}
+	return items[0], nil
+}
+
+//searchInventory returns every inventory item with term in its name or
+//description, or every item if term is empty
+func searchInventory(db *sql.DB, term string) ([]backend.InventoryItem, error) {
+	return selectInventory(db, "WHERE inventory.name LIKE ? "+
+		"OR inventory.description LIKE ? ORDER BY inventory.name",
+		"%"+term+"%", "%"+term+"%")
+}
+
+//insertInventoryItem stores a new inventory item and returns its id
+func insertInventoryItem(db *sql.DB, item backend.InventoryItem) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	id, err := insertInventoryItemTx(tx, item)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+//insertInventoryItemTx stores a new inventory item as part of a larger
+//transaction and returns its id
+func insertInventoryItemTx(tx *sql.Tx, item backend.InventoryItem) (int64, error) {
+	unit, err := unitID(tx, item.PackageQuantity.Unit)
+	if err != nil {
+		return 0, err
+	}
+	var ean sql.NullString
+	if item.EAN != "" {
+		ean = sql.NullString{String: item.EAN, Valid: true}
+	}
+	result, err := tx.Exec("INSERT INTO inventory (EAN, name, description, quantity, "+
+		"packageQuantity, packageQuantityUnits, minimumStock, reorderQuantity) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?)", ean, item.Name, item.Description, item.Quantity,
+		item.PackageQuantity.Amount, unit, item.MinimumStock, item.ReorderQuantity)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	item.ID = int(id)
+	return id, saveLotsTx(tx, &item)
+}
+
+//saveInventoryItem stores the packages on hand and the lots of an item,
+//and records the packages used, if any, in its usage history
+func saveInventoryItem(db *sql.DB, item *backend.InventoryItem, used backend.Fraction, reason string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = saveInventoryItemTx(tx, item); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if used.Sign() > 0 {
+		err = recordUsageTx(tx, item.ID, backend.UsageRecord{Date: today(), Packages: used, Reason: reason}, 0)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+//recordUsageTx adds a use of an inventory item to its usage history.
+//lastMadeID is the lastMade entry of the recipe cooked, or 0.
+func recordUsageTx(tx *sql.Tx, inventoryID int, usage backend.UsageRecord, lastMadeID int64) error {
+	var lastMade sql.NullInt64
+	if lastMadeID != 0 {
+		lastMade = sql.NullInt64{Int64: lastMadeID, Valid: true}
+	}
+	_, err := tx.Exec("INSERT INTO inventoryUsage (inventoryID, dateUsed, packages, reason, lastMadeID) "+
+		"VALUES (?, ?, ?, ?, ?)", inventoryID, nullDate(usage.Date), usage.Packages, usage.Reason, lastMade)
+	return err
+}
+
+//selectUsage returns the usage history of every inventory item since a
+//date, keyed by inventory id
+func selectUsage(db *sql.DB, since time.Time) (map[int][]backend.UsageRecord, error) {
+	rows, err := db.Query("SELECT inventoryID, dateUsed, packages, IFNULL(reason, '') "+
+		"FROM inventoryUsage WHERE dateUsed >= ? ORDER BY dateUsed", since.Format(backend.DateFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	usage := make(map[int][]backend.UsageRecord)
+	for rows.Next() {
+		var inventoryID int
+		var date string
+		var record backend.UsageRecord
+		if err = rows.Scan(&inventoryID, &date, &record.Packages, &record.Reason); err != nil {
+			return nil, err
+		}
+		if record.Date, err = parseDate(date); err != nil {
+			return nil, err
+		}
+		usage[inventoryID] = append(usage[inventoryID], record)
+	}
+	return usage, rows.Err()
+}
+
+//saveInventoryItemTx stores the packages on hand and the lots of an item
+//as part of a larger transaction
+func saveInventoryItemTx(tx *sql.Tx, item *backend.InventoryItem) error {
+	_, err := tx.Exec("UPDATE inventory SET quantity = ? WHERE id = ?", item.Quantity, item.ID)
+	if err != nil {
+		return err
+	}
+	return saveLotsTx(tx, item)
+}
+
+//saveLotsTx inserts new lots of an item and updates the quantity of the
+//rest. Empty lots are kept so that using them can be undone.
+func saveLotsTx(tx *sql.Tx, item *backend.InventoryItem) error {
+	for i := range item.Lots {
+		lot := &item.Lots[i]
+		if lot.ID != 0 {
+			_, err := tx.Exec("UPDATE inventoryLots SET quantity = ? WHERE id = ?", lot.Quantity, lot.ID)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		result, err := tx.Exec("INSERT INTO inventoryLots (inventoryID, quantity, purchased, "+
+			"expires, location) VALUES (?, ?, ?, ?, ?)", item.ID, lot.Quantity,
+			nullDate(lot.Purchased), nullDate(lot.Expires), lot.Location)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		lot.ID = int(id)
+	}
+	return nil
+}
+
+//prefillInventoryItem sets the barcode of a new inventory item, and fills
+//in its name, description and package size from the product catalog where
+//they were not given
+func prefillInventoryItem(db *sql.DB, item backend.InventoryItem, barcode string) (backend.InventoryItem, error) {
+	gtin, err := backend.NormalizeGTIN(barcode)
+	if err != nil {
+		return item, err
+	}
+	item.EAN = gtin
+	product, found, err := lookupProduct(db, gtin)
+	if err != nil {
+		return item, err
+	}
+	if !found {
+		return item, nil
+	}
+	if item.Name == "" {
+		item.Name = product.Name
+	}
+	if item.Description == "" {
+		item.Description = product.Description
+	}
+	if item.PackageQuantity.IsZero() {
+		item.PackageQuantity = product.PackageQuantity
+	}
+	return item, nil
+}
+
+//lotOptions are the flags describing a new lot of packages
+type lotOptions struct {
+	purchased *string
+	expires   *string
+	location  *string
+	price     *string
+	store     *string
+}
+
+//addLotFlags adds the lot flags to flags
+func addLotFlags(flags *flag.FlagSet) lotOptions {
+	return lotOptions{
+		purchased: flags.String("purchased", "", "Date packages were bought as YYYY-MM-DD, default today"),
+		expires:   flags.String("expires", "", "Best before date of packages as YYYY-MM-DD"),
+		location:  flags.String("location", backend.LocationPantry, "Where packages are kept: pantry, fridge, freezer or any other place"),
+		price:     flags.String("price", "", "Price paid for one package"),
+		store:     flags.String("store", "", "Store the packages were bought at"),
+	}
+}
+
+//lot returns a new lot of packages described by the flags
+func (o lotOptions) lot(packages backend.Fraction) (backend.InventoryLot, error) {
+	lot := backend.InventoryLot{Quantity: packages, Purchased: today(), Location: *o.location}
+	var err error
+	if *o.purchased != "" {
+		if lot.Purchased, err = time.Parse(backend.DateFormat, *o.purchased); err != nil {
+			return lot, err
+		}
+	}
+	if *o.expires != "" {
+		if lot.Expires, err = time.Parse(backend.DateFormat, *o.expires); err != nil {
+			return lot, err
+		}
+	}
+	return lot, nil
+}
+
+//recordPrice stores the price paid for the packages of lot, if one was
+//given
+func (o lotOptions) recordPrice(db *sql.DB, item backend.InventoryItem, lot backend.InventoryLot) error {
+	if o.price == nil || *o.price == "" {
+		return nil
+	}
+	price, err := backend.ParseMoney(*o.price)
+	if err != nil {
+		return err
+	}
+	return insertPrice(db, backend.PricePoint{ItemID: item.ID, Item: item.Name, Price: price,
+		Store: *o.store, Date: lot.Purchased, Packages: lot.Quantity})
+}
+
+//today returns the current date, at midnight so it compares equal to dates
+//read from the database
+func today() time.Time {
+	date, _ := time.Parse(backend.DateFormat, time.Now().Format(backend.DateFormat))
+	return date
+}
+
+//linkIngredient links an inventory item to every ingredient called
+//ingredientName, ignoring case, and returns how many were linked
+func linkIngredient(db *sql.DB, inventoryID int, ingredientName string) (int64, error) {
+	result, err := db.Exec("INSERT OR IGNORE INTO ingredient_inventory (ingredientID, inventoryID) "+
+		"SELECT id, ? FROM ingredients WHERE name = ? COLLATE NOCASE", inventoryID, ingredientName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+//inventoryByIngredient maps canonical ingredient names to the inventory
+//items that can be used for them. Items linked to an ingredient through
+//ingredient_inventory come first, then items with the same name as the
+//ingredient.
+func inventoryByIngredient(db *sql.DB) (map[string][]backend.InventoryItem, error) {
+	byIngredient := make(map[string][]backend.InventoryItem)
+	seen := make(map[string]map[int]bool)
+	add := func(name string, item backend.InventoryItem) {
+		name = backend.CanonicalName(name)
+		if seen[name] == nil {
+			seen[name] = make(map[int]bool)
+		}
+		if !seen[name][item.ID] {
+			seen[name][item.ID] = true
+			byIngredient[name] = append(byIngredient[name], item)
+		}
+	}
+
+	items, err := searchInventory(db, "")
+	if err != nil {
+		return nil, err
+	}
+	itemsByID := make(map[int]backend.InventoryItem)
+	for _, item := range items {
+		itemsByID[item.ID] = item
+	}
+
+	rows, err := db.Query("SELECT DISTINCT ingredients.name, ingredient_inventory.inventoryID " +
+		"FROM ingredient_inventory INNER JOIN ingredients ON " +
+		"ingredient_inventory.ingredientID = ingredients.id ORDER BY ingredient_inventory.inventoryID")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var inventoryID int
+		if err = rows.Scan(&name, &inventoryID); err != nil {
+			return nil, err
+		}
+		if item, ok := itemsByID[inventoryID]; ok {
+			add(name, item)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		add(item.Name, item)
+	}
+	return byIngredient, nil
+}
+
+//expiringReport lists every lot expiring in the next days, or already
+//expired, with the recipes that use the item. Recipes use an item when one
+//of their ingredients is linked to it or has the same name.
+func expiringReport(db *sql.DB, days int) (string, error) {
+	items, err := searchInventory(db, "")
+	if err != nil {
+		return "", err
+	}
+	now := today()
+	expiring := backend.ExpiringLots(items, now.AddDate(0, 0, days+1))
+	if len(expiring) == 0 {
+		return "", nil
+	}
+
+	rows, err := db.Query("SELECT DISTINCT recipes.name, ingredients.name, " +
+		"IFNULL(ingredient_inventory.inventoryID, 0) FROM recipes " +
+		"INNER JOIN ingredient_recipe ON recipes.id = ingredient_recipe.recipeID " +
+		"INNER JOIN ingredients ON ingredient_recipe.ingredientID = ingredients.id " +
+		"LEFT JOIN ingredient_inventory ON ingredients.id = ingredient_inventory.ingredientID " +
+		"ORDER BY recipes.name")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	recipesByName := make(map[string][]string)
+	recipesByItem := make(map[int][]string)
+	for rows.Next() {
+		var recipeName, ingredientName string
+		var inventoryID int
+		if err = rows.Scan(&recipeName, &ingredientName, &inventoryID); err != nil {
+			return "", err
+		}
+		name := backend.CanonicalName(ingredientName)
+		recipesByName[name] = appendUnique(recipesByName[name], recipeName)
+		if inventoryID != 0 {
+			recipesByItem[inventoryID] = appendUnique(recipesByItem[inventoryID], recipeName)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return "", err
+	}
+
+	stringString := ""
+	for _, lot := range expiring {
+		lot.Recipes = recipesByItem[lot.Item.ID]
+		for _, recipe := range recipesByName[backend.CanonicalName(lot.Item.Name)] {
+			lot.Recipes = appendUnique(lot.Recipes, recipe)
+		}
+		stringString += lot.Report(now)
+	}
+	return stringString, nil
+}
+
+//appendUnique appends s to list unless it is already in it
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+//lowStockItems returns every inventory item below its minimum stock level.
+//Fractions are stored as text, so the minimum is compared once scanned.
+func lowStockItems(db *sql.DB) ([]backend.InventoryItem, error) {
+	items, err := selectInventory(db, "ORDER BY inventory.name")
+	if err != nil {
+		return nil, err
+	}
+	var low []backend.InventoryItem
+	for _, item := range items {
+		if item.BelowMinimum() {
+			low = append(low, item)
+		}
+	}
+	return low, nil
+}
+
+//lowStockReport lists items below their minimum with the packages to
+//reorder. With predict, every item used in the last history days is listed
+//with when it will run out.
+func lowStockReport(db *sql.DB, predict bool, history int) (string, error) {
+	low, err := lowStockItems(db)
+	if err != nil {
+		return "", err
+	}
+	stringString := ""
+	for _, item := range low {
+		stringString += fmt.Sprintf("%s: %s packages, minimum %s, reorder %s\n", item.Name,
+			item.Quantity.DecimalString(2), item.MinimumStock.DecimalString(2),
+			item.ReorderPackages().DecimalString(2))
+	}
+	if len(low) == 0 {
+		stringString += "Nothing is below its minimum\n"
+	}
+	if !predict {
+		return stringString, nil
+	}
+
+	now := today()
+	usage, err := selectUsage(db, now.AddDate(0, 0, -history))
+	if err != nil {
+		return "", err
+	}
+	items, err := searchInventory(db, "")
+	if err != nil {
+		return "", err
+	}
+	stringString += "\nPredicted from the last " + strconv.Itoa(history) + " days:\n"
+	for _, item := range items {
+		if len(usage[item.ID]) == 0 {
+			continue
+		}
+		prediction := backend.PredictRunOut(item, usage[item.ID], now)
+		stringString += fmt.Sprintf("%s: %s packages, %s\n", item.Name, item.Quantity.DecimalString(2),
+			prediction.String())
+	}
+	return stringString, nil
+}
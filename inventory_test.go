@@ -0,0 +1,161 @@
+package recipeDatabase
+
+import (
+	"testing"
+	"time"
+)
+
+//testDate returns midnight UTC of a YYYY-MM-DD date
+func testDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	date, err := time.Parse(DateFormat, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return date
+}
+
+//testFlour returns two 5 lb bags of flour, one in each of two lots, plus
+//half a bag from before lots were tracked
+func testFlour(t *testing.T) InventoryItem {
+	item := InventoryItem{Name: "flour", PackageQuantity: NewQuantity(NewFraction(5, 1), "lb")}
+	item.AddLot(InventoryLot{ID: 1, Quantity: NewFraction(1, 1), Purchased: testDate(t, "2026-03-01"),
+		Expires: testDate(t, "2026-12-01"), Location: "Pantry "})
+	item.AddLot(InventoryLot{ID: 2, Quantity: NewFraction(1, 1), Purchased: testDate(t, "2026-01-01"),
+		Expires: testDate(t, "2026-06-01"), Location: "refrigerator"})
+	item.Quantity = item.Quantity.Add(NewFraction(1, 2))
+	return item
+}
+
+//lotQuantities returns the packages left in each lot, keyed by lot id
+func lotQuantities(item InventoryItem) map[int]Fraction {
+	quantities := make(map[int]Fraction)
+	for _, lot := range item.Lots {
+		quantities[lot.ID] = lot.Quantity
+	}
+	return quantities
+}
+
+func TestAddLot(t *testing.T) {
+	item := testFlour(t)
+	if item.Quantity.Cmp(NewFraction(5, 2)) != 0 {
+		t.Errorf("quantity is %s, want 2 1/2", item.Quantity)
+	}
+	if item.Lots[0].Location != LocationPantry || item.Lots[1].Location != LocationFridge {
+		t.Errorf("lot locations are %q and %q, want %q and %q", item.Lots[0].Location,
+			item.Lots[1].Location, LocationPantry, LocationFridge)
+	}
+}
+
+func TestConsumeFirstExpiringFirst(t *testing.T) {
+	tests := []struct {
+		amount    Quantity
+		lots      map[int]Fraction
+		remaining Fraction
+	}{
+		// the lot expiring in June is used before the one bought later
+		{NewQuantity(NewFraction(5, 2), "lb"), map[int]Fraction{1: NewFraction(1, 1), 2: NewFraction(1, 2)},
+			NewFraction(2, 1)},
+		{NewQuantity(NewFraction(15, 2), "lb"), map[int]Fraction{1: NewFraction(1, 2), 2: {}},
+			NewFraction(1, 1)},
+		// packages not in any lot are used last
+		{NewQuantity(NewFraction(10, 1), "lb"), map[int]Fraction{1: {}, 2: {}}, NewFraction(1, 2)},
+		{NewQuantity(NewFraction(80, 1), "oz"), map[int]Fraction{1: NewFraction(1, 1), 2: {}},
+			NewFraction(3, 2)},
+	}
+	for _, test := range tests {
+		item := testFlour(t)
+		short, err := item.Consume(test.amount)
+		if err != nil {
+			t.Errorf("Consume(%s) returned error: %s", test.amount, err)
+			continue
+		}
+		if !short.IsZero() {
+			t.Errorf("Consume(%s) was short %s, want nothing", test.amount, short)
+		}
+		if item.Quantity.Cmp(test.remaining) != 0 {
+			t.Errorf("Consume(%s) left %s packages, want %s", test.amount, item.Quantity, test.remaining)
+		}
+		for id, quantity := range lotQuantities(item) {
+			if quantity.Cmp(test.lots[id]) != 0 {
+				t.Errorf("Consume(%s) left %s packages in lot %d, want %s", test.amount, quantity, id,
+					test.lots[id])
+			}
+		}
+	}
+}
+
+func TestConsumeShort(t *testing.T) {
+	item := testFlour(t)
+	short, err := item.Consume(NewQuantity(NewFraction(15, 1), "lb"))
+	if err != nil {
+		t.Fatalf("Consume(15 lb) returned error: %s", err)
+	}
+	if short.Amount.Cmp(NewFraction(5, 2)) != 0 || short.Unit != "lb" {
+		t.Errorf("Consume(15 lb) was short %s, want 2 1/2 lb", short)
+	}
+	if !item.Quantity.IsZero() {
+		t.Errorf("Consume(15 lb) left %s packages, want 0", item.Quantity)
+	}
+	if _, err = item.Consume(NewQuantity(NewFraction(-1, 1), "lb")); err == nil {
+		t.Error("Consume(-1 lb) did not return an error")
+	}
+	if _, err = item.Consume(NewQuantity(NewFraction(1, 1), "handful")); err == nil {
+		t.Error("Consume(1 handful) of flour did not return an error")
+	}
+}
+
+func TestSetQuantity(t *testing.T) {
+	item := testFlour(t)
+	item.SetQuantity(NewFraction(1, 1))
+	if got := lotQuantities(item); got[1].Cmp(NewFraction(1, 2)) != 0 || !got[2].IsZero() {
+		t.Errorf("SetQuantity(1) left lots %v, want 1: 1/2 and 2: 0", got)
+	}
+	// counting more packages than the lots hold does not change the lots
+	item.SetQuantity(NewFraction(4, 1))
+	got := lotQuantities(item)
+	if got[1].Cmp(NewFraction(1, 2)) != 0 || item.Quantity.Cmp(NewFraction(4, 1)) != 0 {
+		t.Errorf("SetQuantity(4) left %s packages and lots %v", item.Quantity, got)
+	}
+}
+
+func TestPackagesOf(t *testing.T) {
+	eggs := InventoryItem{Name: "eggs"}
+	if packages, err := eggs.PackagesOf(NewQuantity(NewFraction(3, 1), "")); err != nil ||
+		packages.Cmp(NewFraction(3, 1)) != 0 {
+		t.Errorf("PackagesOf(3) of eggs = %s, %v, want 3", packages, err)
+	}
+	if packages, err := eggs.PackagesOf(NewQuantity(NewFraction(1, 1), "cup")); err == nil {
+		t.Errorf("PackagesOf(1 cup) of eggs without a package size = %s, want an error", packages)
+	}
+	milk := InventoryItem{Name: "milk", PackageQuantity: NewQuantity(NewFraction(1, 2), "gallon")}
+	if packages, err := milk.PackagesOf(NewQuantity(NewFraction(1, 1), "cup")); err != nil ||
+		packages.Cmp(NewFraction(1, 8)) != 0 {
+		t.Errorf("PackagesOf(1 cup) of milk = %s, %v, want 1/8", packages, err)
+	}
+}
+
+func TestExpiringLots(t *testing.T) {
+	flour := testFlour(t)
+	milk := InventoryItem{Name: "milk"}
+	milk.AddLot(InventoryLot{ID: 3, Quantity: NewFraction(1, 1), Expires: testDate(t, "2026-05-20")})
+	milk.AddLot(InventoryLot{ID: 4, Quantity: Fraction{}, Expires: testDate(t, "2026-05-10")})
+	expiring := ExpiringLots([]InventoryItem{flour, milk}, testDate(t, "2026-07-01"))
+	if len(expiring) != 2 || expiring[0].Lot.ID != 3 || expiring[1].Lot.ID != 2 {
+		t.Fatalf("ExpiringLots returned %v, want lots 3 and 2", expiring)
+	}
+	tests := []struct {
+		now  string
+		want string
+	}{
+		{"2026-05-01", "2026-05-20 milk: expires in 19 days, 1 packages in pantry, expires 2026-05-20\n"},
+		{"2026-05-19", "2026-05-20 milk: expires tomorrow, 1 packages in pantry, expires 2026-05-20\n"},
+		{"2026-05-20", "2026-05-20 milk: expires today, 1 packages in pantry, expires 2026-05-20\n"},
+		{"2026-05-21", "2026-05-20 milk: EXPIRED, 1 packages in pantry, expires 2026-05-20\n"},
+	}
+	for _, test := range tests {
+		if got := expiring[0].Report(testDate(t, test.now)); got != test.want {
+			t.Errorf("Report(%s) = %q, want %q", test.now, got, test.want)
+		}
+	}
+}
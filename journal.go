@@ -0,0 +1,235 @@
+package recipeDatabase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//MaxRating is the best rating a cook can be given
+const MaxRating = 5
+
+//Kinds of adjustment made to a recipe while cooking it
+const (
+	AdjustChange = "change" // use a different amount of an ingredient
+	AdjustAdd    = "add"    // add an ingredient
+	AdjustRemove = "remove" // leave an ingredient out
+	AdjustNote   = "note"   // any other change, ie: bake 5 minutes longer
+)
+
+//An Adjustment is a change made to a recipe while cooking it
+type Adjustment struct {
+	Kind       string   // AdjustChange, AdjustAdd, AdjustRemove or AdjustNote
+	Ingredient string   // ingredient changed, added or removed
+	Quantity   Quantity // amount changed to or added
+	Note       string   // text of a note
+}
+
+//ParseAdjustment reads an adjustment written as "sugar: 3/4 cup" to change
+//the amount of an ingredient, "add 1 tsp vanilla" or "+1 tsp vanilla" to add
+//one, "remove walnuts" or "-walnuts" to leave one out, or any other text as
+//a note.
+func ParseAdjustment(s string) (Adjustment, error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+	switch {
+	case s == "":
+		return Adjustment{}, errors.New("empty adjustment")
+	case strings.HasPrefix(lower, "add ") || strings.HasPrefix(s, "+"):
+		rest := strings.TrimPrefix(s, "+")
+		if strings.HasPrefix(lower, "add ") {
+			rest = s[len("add "):]
+		}
+		ingredient, err := ParseIngredient(rest)
+		if err != nil {
+			return Adjustment{}, fmt.Errorf("invalid adjustment %q: %s", s, err)
+		}
+		return Adjustment{Kind: AdjustAdd, Ingredient: ingredient.Name, Quantity: ingredient.QuantityNeeded}, nil
+	case strings.HasPrefix(lower, "remove ") || strings.HasPrefix(s, "-"):
+		name := strings.TrimSpace(strings.TrimPrefix(s, "-"))
+		if strings.HasPrefix(lower, "remove ") {
+			name = strings.TrimSpace(s[len("remove "):])
+		}
+		if name == "" {
+			return Adjustment{}, fmt.Errorf("invalid adjustment %q: no ingredient to remove", s)
+		}
+		return Adjustment{Kind: AdjustRemove, Ingredient: name}, nil
+	}
+	if colon := strings.Index(s, ":"); colon > 0 {
+		name := strings.TrimSpace(s[:colon])
+		if quantity, err := ParseQuantity(s[colon+1:]); err == nil {
+			return Adjustment{Kind: AdjustChange, Ingredient: name, Quantity: quantity}, nil
+		}
+	}
+	return Adjustment{Kind: AdjustNote, Note: s}, nil
+}
+
+func (a Adjustment) String() string {
+	switch a.Kind {
+	case AdjustChange:
+		return a.Ingredient + ": " + a.Quantity.Format()
+	case AdjustAdd:
+		if a.Quantity.IsZero() {
+			return "add " + a.Ingredient
+		}
+		return "add " + a.Quantity.Format() + " " + a.Ingredient
+	case AdjustRemove:
+		return "remove " + a.Ingredient
+	}
+	return a.Note
+}
+
+//Adjust returns a copy of the recipe with adjustments made to its
+//ingredients. Notes are added to the comments of the copy. The stored
+//recipe is not changed.
+func (r Recipe) Adjust(adjustments []Adjustment) (Recipe, error) {
+	adjusted := r
+	adjusted.Ingredients = append([]Ingredient{}, r.Ingredients...)
+	var notes []string
+	for _, adjustment := range adjustments {
+		switch adjustment.Kind {
+		case AdjustNote:
+			notes = append(notes, adjustment.Note)
+			continue
+		case AdjustAdd:
+			adjusted.Ingredients = append(adjusted.Ingredients,
+				Ingredient{Name: adjustment.Ingredient, QuantityNeeded: adjustment.Quantity})
+			continue
+		}
+		found := -1
+		for i, ingredient := range adjusted.Ingredients {
+			if CanonicalName(ingredient.Name) == CanonicalName(adjustment.Ingredient) {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return r, fmt.Errorf("%s has no ingredient %s to %s", r.Name, adjustment.Ingredient, adjustment.Kind)
+		}
+		if adjustment.Kind == AdjustRemove {
+			adjusted.Ingredients = append(adjusted.Ingredients[:found], adjusted.Ingredients[found+1:]...)
+			continue
+		}
+		adjusted.Ingredients[found].QuantityNeeded = adjustment.Quantity
+	}
+	if len(notes) > 0 {
+		if adjusted.Comments != "" {
+			adjusted.Comments += "\n"
+		}
+		adjusted.Comments += strings.Join(notes, "\n")
+	}
+	return adjusted, nil
+}
+
+//ParseRating reads a rating from 1 to MaxRating written as "4" or "4/5"
+func ParseRating(s string) (int, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "/"+strconv.Itoa(MaxRating))
+	rating, err := strconv.Atoi(s)
+	if err != nil || rating < 1 || rating > MaxRating {
+		return 0, fmt.Errorf("invalid rating %q, expected 1 to %d", s, MaxRating)
+	}
+	return rating, nil
+}
+
+//A JournalEntry is one time a recipe was made
+type JournalEntry struct {
+	ID          int          // id of lastMade entry in database
+	RecipeID    int          // id of recipe made
+	Recipe      string       // name of recipe made
+	Date        time.Time    // day recipe was made
+	Rating      int          // from 1 to MaxRating, 0 if not rated
+	Servings    Fraction     // servings made, zero if unknown
+	Cook        string       // who cooked
+	Adjustments []Adjustment // changes made to the recipe
+	Notes       string       // notes from cooking
+}
+
+func (j JournalEntry) String() string {
+	stringString := fmt.Sprintf("%d) %s", j.ID, j.Date.Format(DateFormat))
+	if j.Recipe != "" {
+		stringString += " " + j.Recipe
+	}
+	if j.Rating > 0 {
+		stringString += fmt.Sprintf(" rated %d/%d", j.Rating, MaxRating)
+	}
+	if !j.Servings.IsZero() {
+		stringString += fmt.Sprintf(", %s servings", j.Servings.KitchenString())
+	}
+	if j.Cook != "" {
+		stringString += ", cooked by " + j.Cook
+	}
+	stringString += "\n"
+	if len(j.Adjustments) > 0 {
+		var adjustments []string
+		for _, adjustment := range j.Adjustments {
+			adjustments = append(adjustments, adjustment.String())
+		}
+		stringString += "\tadjusted: " + strings.Join(adjustments, "; ") + "\n"
+	}
+	if j.Notes != "" {
+		stringString += "\tnotes: " + j.Notes + "\n"
+	}
+	return stringString
+}
+
+//A Journal is the times recipes were made
+type Journal []JournalEntry
+
+//Sort orders the journal most recent first
+func (j Journal) Sort() {
+	sort.SliceStable(j, func(a, b int) bool {
+		if !j[a].Date.Equal(j[b].Date) {
+			return j[a].Date.After(j[b].Date)
+		}
+		return j[a].ID > j[b].ID
+	})
+}
+
+//AverageRating returns the average of the rated entries, and the number
+//of them
+func (j Journal) AverageRating() (float64, int) {
+	total, rated := 0, 0
+	for _, entry := range j {
+		if entry.Rating > 0 {
+			total += entry.Rating
+			rated++
+		}
+	}
+	if rated == 0 {
+		return 0, 0
+	}
+	return float64(total) / float64(rated), rated
+}
+
+//Summary describes how often and how recently the recipes were made, and
+//how they were rated, on one line
+func (j Journal) Summary() string {
+	if len(j) == 0 {
+		return "Never made\n"
+	}
+	last := j[0].Date
+	for _, entry := range j {
+		if entry.Date.After(last) {
+			last = entry.Date
+		}
+	}
+	stringString := fmt.Sprintf("Made %d times, last on %s", len(j), last.Format(DateFormat))
+	if len(j) == 1 {
+		stringString = "Made once on " + last.Format(DateFormat)
+	}
+	if average, rated := j.AverageRating(); rated > 0 {
+		stringString += fmt.Sprintf(", rated %.1f/%d", average, MaxRating)
+	}
+	return stringString + "\n"
+}
+
+func (j Journal) String() string {
+	stringString := j.Summary()
+	for _, entry := range j {
+		stringString += entry.String()
+	}
+	return stringString
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const journalUsage = `usage: cookbook journal <command> [arguments]
+
+commands:
+	log [flags] <recipe name>  record making a recipe without using inventory,
+	                           run with -h for flags
+	history <recipe name>      every time a recipe was made, most recent first
+	recent [count]             the last count recipes made, default 10
+	rate <id> <rating>         rate an entry from 1 to 5
+	remove <id>                remove an entry
+	promote [-name <name>] <id>
+		save the recipe with the adjustments of an entry as a new revision
+
+Adjustments are separated by semicolons, ie:
+	-adjust "sugar: 3/4 cup; add 1 tsp vanilla; remove walnuts; bake 5 minutes longer"
+changes the amount of sugar, adds vanilla, leaves out walnuts and notes a
+change to the steps. Making a recipe with cook records an entry as well, and
+takes the same flags.
+`
+
+//journalCommand runs the journal subcommand given on the command line
+func journalCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(journalUsage)
+		return errors.New("no journal command given")
+	}
+	switch args[0] {
+	case "log":
+		flags := flag.NewFlagSet("journal log", flag.ContinueOnError)
+		journalFlags := addJournalFlags(flags)
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() == 0 {
+			return errors.New("usage: cookbook journal log [flags] <recipe name>")
+		}
+		recipe, err := chooseRecipe(db, strings.Join(flags.Args(), " "), bufio.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		entry, err := journalFlags.entry(recipe)
+		if err != nil {
+			return err
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err = insertJournalTx(tx, &entry); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		fmt.Print(entry)
+	case "history":
+		if len(args) < 2 {
+			return errors.New("usage: cookbook journal history <recipe name>")
+		}
+		recipe, err := chooseRecipe(db, strings.Join(args[1:], " "), bufio.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		journal, err := selectJournal(db, "WHERE lastMade.recipe = ?", recipe.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Print(recipe.Name + "\n" + journal.String())
+	case "recent":
+		count := 10
+		if len(args) > 1 {
+			var err error
+			if count, err = strconv.Atoi(args[1]); err != nil || count < 1 {
+				return fmt.Errorf("invalid count %q", args[1])
+			}
+		}
+		journal, err := selectJournal(db, "")
+		if err != nil {
+			return err
+		}
+		if len(journal) == 0 {
+			fmt.Println("Nothing has been made yet")
+		}
+		if len(journal) > count {
+			journal = journal[:count]
+		}
+		for _, entry := range journal {
+			fmt.Print(entry)
+		}
+	case "rate":
+		if len(args) != 3 {
+			return errors.New("usage: cookbook journal rate <id> <rating>")
+		}
+		entry, err := findJournalEntry(db, args[1])
+		if err != nil {
+			return err
+		}
+		if entry.Rating, err = backend.ParseRating(args[2]); err != nil {
+			return err
+		}
+		_, err = db.Exec("INSERT OR REPLACE INTO journal (lastMadeID, rating, servings, cook) VALUES (?, ?, ?, ?)",
+			entry.ID, entry.Rating, entry.Servings, entry.Cook)
+		if err != nil {
+			return err
+		}
+		fmt.Print(entry)
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: cookbook journal remove <id>")
+		}
+		entry, err := findJournalEntry(db, args[1])
+		if err != nil {
+			return err
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err = deleteJournalTx(tx, entry.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s on %s\n", entry.Recipe, entry.Date.Format(backend.DateFormat))
+	case "promote":
+		flags := flag.NewFlagSet("journal promote", flag.ContinueOnError)
+		name := flags.String("name", "", "Name of the new revision, default the name of the recipe")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: cookbook journal promote [-name <name>] <id>")
+		}
+		entry, err := findJournalEntry(db, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		revision, err := promoteJournalEntry(db, entry, *name)
+		if err != nil {
+			return err
+		}
+		fmt.Print(revision.String())
+	default:
+		fmt.Print(journalUsage)
+		return fmt.Errorf("unknown journal command %s", args[0])
+	}
+	return nil
+}
+
+//journalOptions are the flags describing a time a recipe was made
+type journalOptions struct {
+	date     *string
+	rating   *string
+	servings *string
+	cook     *string
+	adjust   *string
+	notes    *string
+}
+
+//addJournalFlags adds the journal flags to flags
+func addJournalFlags(flags *flag.FlagSet) journalOptions {
+	return journalOptions{
+		date:     flags.String("date", "", "Date recipe was made as YYYY-MM-DD, default today"),
+		rating:   flags.String("rating", "", "Rating from 1 to 5"),
+		servings: flags.String("servings", "", "Servings made, default the servings of the recipe made"),
+		cook:     flags.String("cook", "", "Who cooked"),
+		adjust:   flags.String("adjust", "", "Semicolon separated changes made, ie: \"sugar: 3/4 cup; add 1 tsp vanilla\""),
+		notes:    flags.String("notes", "", "Notes from cooking"),
+	}
+}
+
+//entry returns the journal entry described by the flags for making recipe
+func (o journalOptions) entry(recipe backend.Recipe) (backend.JournalEntry, error) {
+	entry := backend.JournalEntry{RecipeID: recipe.ID, Recipe: recipe.Name, Date: today(),
+		Servings: recipe.Servings(), Cook: strings.TrimSpace(*o.cook), Notes: *o.notes}
+	var err error
+	if *o.date != "" {
+		if entry.Date, err = parsePlanDate(*o.date, today()); err != nil {
+			return entry, err
+		}
+	}
+	if *o.rating != "" {
+		if entry.Rating, err = backend.ParseRating(*o.rating); err != nil {
+			return entry, err
+		}
+	}
+	if *o.servings != "" {
+		if entry.Servings, err = backend.ParseFraction(*o.servings); err != nil {
+			return entry, fmt.Errorf("invalid servings %q: %s", *o.servings, err)
+		}
+	}
+	for _, text := range strings.Split(*o.adjust, ";") {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		adjustment, err := backend.ParseAdjustment(text)
+		if err != nil {
+			return entry, err
+		}
+		entry.Adjustments = append(entry.Adjustments, adjustment)
+	}
+	// catch adjustments to ingredients the recipe does not have now, not
+	// when they are promoted
+	if _, err = recipe.Adjust(entry.Adjustments); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+//insertJournalTx records making a recipe in lastMade, with the rest of the
+//entry in the journal tables, and sets the id of entry
+func insertJournalTx(tx *sql.Tx, entry *backend.JournalEntry) error {
+	result, err := tx.Exec("INSERT INTO lastMade (recipe, dateMade, notes) VALUES (?, ?, ?)",
+		entry.RecipeID, entry.Date.Format(backend.DateFormat), entry.Notes)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.ID = int(id)
+	_, err = tx.Exec("INSERT INTO journal (lastMadeID, rating, servings, cook) VALUES (?, ?, ?, ?)",
+		entry.ID, entry.Rating, entry.Servings, entry.Cook)
+	if err != nil {
+		return err
+	}
+	for _, adjustment := range entry.Adjustments {
+		unit, err := unitID(tx, adjustment.Quantity.Unit)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO journalAdjustments (lastMadeID, kind, ingredient, quantity, "+
+			"quantityUnits, note) VALUES (?, ?, ?, ?, ?, ?)", entry.ID, adjustment.Kind, adjustment.Ingredient,
+			adjustment.Quantity.Amount, unit, adjustment.Note)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//deleteJournalTx removes a lastMade entry and the rest of its journal
+//entry. Inventory used making it is not put back.
+func deleteJournalTx(tx *sql.Tx, id int) error {
+	for _, query := range []string{"DELETE FROM journalAdjustments WHERE lastMadeID = ?",
+		"DELETE FROM journal WHERE lastMadeID = ?", "UPDATE inventoryUsage SET lastMadeID = NULL WHERE lastMadeID = ?",
+		"UPDATE recipeRevisions SET lastMadeID = NULL WHERE lastMadeID = ?", "DELETE FROM lastMade WHERE id = ?"} {
+		if _, err := tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//selectJournal reads the lastMade entries matching a where clause, with the
+//rest of their journal entries, most recent first. The where clause can use
+//the lastMade, recipes and journal tables, but not order or limit the rows.
+//Entries made before the journal existed have only a date and notes.
+func selectJournal(q queryer, where string, args ...interface{}) (backend.Journal, error) {
+	rows, err := q.Query("SELECT lastMade.id, lastMade.recipe, IFNULL(recipes.name, ''), "+
+		"IFNULL(lastMade.dateMade, ''), IFNULL(lastMade.notes, ''), IFNULL(journal.rating, 0), "+
+		"IFNULL(journal.servings, 0), IFNULL(journal.cook, '') FROM lastMade "+
+		"LEFT JOIN recipes ON lastMade.recipe = recipes.id "+
+		"LEFT JOIN journal ON lastMade.id = journal.lastMadeID "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	var journal backend.Journal
+	entries := make(map[int]int)
+	for rows.Next() {
+		var entry backend.JournalEntry
+		var date string
+		err = rows.Scan(&entry.ID, &entry.RecipeID, &entry.Recipe, &date, &entry.Notes, &entry.Rating,
+			&entry.Servings, &entry.Cook)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if entry.Date, err = parseDate(date); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		entries[entry.ID] = len(journal)
+		journal = append(journal, entry)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(journal) == 0 {
+		return journal, nil
+	}
+
+	// only the adjustments of the entries read, by joining to the same where
+	rows, err = q.Query("SELECT journalAdjustments.lastMadeID, journalAdjustments.kind, "+
+		"IFNULL(journalAdjustments.ingredient, ''), IFNULL(journalAdjustments.quantity, 0), "+
+		"IFNULL(units.name, ''), IFNULL(journalAdjustments.note, '') FROM journalAdjustments "+
+		"INNER JOIN lastMade ON journalAdjustments.lastMadeID = lastMade.id "+
+		"LEFT JOIN recipes ON lastMade.recipe = recipes.id "+
+		"LEFT JOIN journal ON lastMade.id = journal.lastMadeID "+
+		"LEFT JOIN units ON journalAdjustments.quantityUnits = units.id "+where+
+		" ORDER BY journalAdjustments.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var adjustment backend.Adjustment
+		err = rows.Scan(&id, &adjustment.Kind, &adjustment.Ingredient, &adjustment.Quantity.Amount,
+			&adjustment.Quantity.Unit, &adjustment.Note)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := entries[id]; ok {
+			journal[i].Adjustments = append(journal[i].Adjustments, adjustment)
+		}
+	}
+	journal.Sort()
+	return journal, rows.Err()
+}
+
+//findJournalEntry returns the journal entry with the id given as text
+func findJournalEntry(db *sql.DB, ref string) (backend.JournalEntry, error) {
+	id, err := strconv.Atoi(ref)
+	if err != nil {
+		return backend.JournalEntry{}, fmt.Errorf("invalid journal entry id %q", ref)
+	}
+	journal, err := selectJournal(db, "WHERE lastMade.id = ?", id)
+	if err != nil {
+		return backend.JournalEntry{}, err
+	}
+	if len(journal) == 0 {
+		return backend.JournalEntry{}, fmt.Errorf("no journal entry with id %d", id)
+	}
+	return journal[0], nil
+}
+
+//addJournal fills in the times a recipe was made for display
+func addJournal(db *sql.DB, recipe *backend.Recipe) error {
+	var err error
+	recipe.Journal, err = selectJournal(db, "WHERE lastMade.recipe = ?", recipe.ID)
+	return err
+}
+
+//promoteJournalEntry saves the recipe of entry, with the adjustments of
+//entry made to it, as the next revision of the recipe. The revision is named
+//name, or the same as the recipe if name is empty.
+func promoteJournalEntry(db *sql.DB, entry backend.JournalEntry, name string) (backend.Recipe, error) {
+	found, err := selectRecipesWhere(db, "WHERE recipes.id = ?", entry.RecipeID)
+	if err != nil {
+		return backend.Recipe{}, err
+	}
+	if len(found) == 0 {
+		return backend.Recipe{}, fmt.Errorf("journal entry %d is for a recipe that does not exist", entry.ID)
+	}
+	original := found[0]
+	if len(entry.Adjustments) == 0 {
+		return original, fmt.Errorf("journal entry %d has no adjustments to promote", entry.ID)
+	}
+	var promoted int
+	err = db.QueryRow("SELECT IFNULL(MAX(version), 0) FROM recipeRevisions WHERE lastMadeID = ?",
+		entry.ID).Scan(&promoted)
+	if err != nil {
+		return original, err
+	}
+	if promoted != 0 {
+		return original, fmt.Errorf("journal entry %d was already promoted to revision %d", entry.ID, promoted)
+	}
+	revision, err := original.Adjust(entry.Adjustments)
+	if err != nil {
+		return original, err
+	}
+	if name = strings.TrimSpace(name); name != "" {
+		revision.Name = name
+	}
+	revision.InitialVersion = original.ID
+	if original.InitialVersion != 0 {
+		revision.InitialVersion = original.InitialVersion
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return revision, err
+	}
+	// recipes without a row are the first version of themselves
+	err = tx.QueryRow("SELECT IFNULL(MAX(version), 1) + 1 FROM recipeRevisions WHERE initialVersion = ?",
+		revision.InitialVersion).Scan(&revision.Version)
+	if err != nil {
+		tx.Rollback()
+		return revision, err
+	}
+	id, err := insertRecipeTx(tx, revision)
+	if err != nil {
+		tx.Rollback()
+		return revision, err
+	}
+	revision.ID = int(id)
+	_, err = tx.Exec("INSERT INTO recipeRevisions (recipeID, initialVersion, version, lastMadeID) "+
+		"VALUES (?, ?, ?, ?)", revision.ID, revision.InitialVersion, revision.Version, entry.ID)
+	if err != nil {
+		tx.Rollback()
+		return revision, err
+	}
+	if err = tx.Commit(); err != nil {
+		return revision, err
+	}
+	infoLogger.Printf("Promoted journal entry %d to revision %d of %s", entry.ID, revision.Version, original.Name)
+	// read it back for the diets derived when it was stored
+	found, err = selectRecipesWhere(db, "WHERE recipes.id = ?", revision.ID)
+	if err != nil || len(found) == 0 {
+		return revision, err
+	}
+	return found[0], nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+//leftoverOptions are the flags for keeping the output of a recipe as
+//leftovers in inventory
+type leftoverOptions struct {
+	amount   *string
+	location *string
+	expires  *string
+	label    *bool
+}
+
+//addLeftoverFlags adds the leftover flags to flags
+func addLeftoverFlags(flags *flag.FlagSet) leftoverOptions {
+	return leftoverOptions{
+		amount: flags.String("leftovers", "", "Amount made to keep in inventory as leftovers, ie: \"4 portions\", "+
+			"or all for everything made"),
+		location: flags.String("location", backend.LocationFridge, fmt.Sprintf("Where leftovers are kept, "+
+			"which decides how long they keep: fridge (%d days), freezer (%d days), pantry (%d day, only for "+
+			"food safe at room temperature) or any other place (as the fridge)",
+			backend.LeftoverDays[backend.LocationFridge], backend.LeftoverDays[backend.LocationFreezer],
+			backend.LeftoverDays[backend.LocationPantry])),
+		expires: flags.String("expires", "", "Date leftovers should be used by as YYYY-MM-DD or a day of the "+
+			"week, default by where they are kept"),
+		label: flags.Bool("label", false, "Print a freezer label for the leftovers"),
+	}
+}
+
+//leftoverRequest is what to keep of a recipe being cooked as leftovers
+type leftoverRequest struct {
+	Amount   backend.Quantity // zero to keep nothing
+	Expires  time.Time        // zero for the default of Location
+	Location string
+}
+
+//request returns the leftovers of recipe to keep described by the flags.
+//An amount without a unit is in the units recipe makes.
+func (o leftoverOptions) request(recipe backend.Recipe) (leftoverRequest, error) {
+	request := leftoverRequest{Location: *o.location}
+	var err error
+	if *o.expires != "" {
+		if request.Expires, err = parsePlanDate(*o.expires, today()); err != nil {
+			return request, err
+		}
+	}
+	text := strings.TrimSpace(*o.amount)
+	switch text {
+	case "":
+		return request, nil
+	case "all":
+		if recipe.QuantityMade.IsZero() {
+			return request, fmt.Errorf("%s does not say how much it makes, give the amount of leftovers",
+				recipe.Name)
+		}
+		request.Amount = recipe.QuantityMade
+		return request, nil
+	}
+	if request.Amount, err = backend.ParseQuantity(text); err != nil {
+		return request, fmt.Errorf("invalid leftovers %q: %s", text, err)
+	}
+	if request.Amount.Unit == "" {
+		request.Amount.Unit = recipe.QuantityMade.Unit
+	}
+	return request, nil
+}
+
+//leftoverChange records leftovers put in inventory by cooking a recipe so
+//that it can be undone
+type leftoverChange struct {
+	InventoryID int
+	LotID       int
+	NewItem     bool // the inventory item was made for these leftovers
+	Label       backend.FreezerLabel
+}
+
+//storeLeftoversTx adds the leftovers of recipe, made on made, to the
+//inventory as a new lot of the leftover item of recipe, creating the item
+//if recipe has none in the units of the leftovers
+func storeLeftoversTx(tx *sql.Tx, recipe backend.Recipe, request leftoverRequest,
+	made time.Time) (leftoverChange, error) {
+	items, err := selectInventory(tx, "INNER JOIN leftovers ON inventory.id = leftovers.inventoryID "+
+		"WHERE leftovers.recipeID = ? ORDER BY inventory.id", recipe.ID)
+	if err != nil {
+		return leftoverChange{}, err
+	}
+	for _, item := range items {
+		lot, err := backend.LeftoverLot(item, request.Amount, made, request.Expires, request.Location)
+		if err != nil {
+			continue
+		}
+		item.AddLot(lot)
+		if err = saveInventoryItemTx(tx, &item); err != nil {
+			return leftoverChange{}, err
+		}
+		lot = item.Lots[len(item.Lots)-1]
+		return leftoverChange{InventoryID: item.ID, LotID: lot.ID,
+			Label: backend.NewFreezerLabel(recipe, item, lot)}, nil
+	}
+
+	item := backend.NewLeftoverItem(recipe, request.Amount)
+	lot, err := backend.LeftoverLot(item, request.Amount, made, request.Expires, request.Location)
+	if err != nil {
+		return leftoverChange{}, err
+	}
+	item.AddLot(lot)
+	id, err := insertInventoryItemTx(tx, item)
+	if err != nil {
+		return leftoverChange{}, err
+	}
+	item.ID = int(id)
+	_, err = tx.Exec("INSERT INTO leftovers (inventoryID, recipeID) VALUES (?, ?)", item.ID, recipe.ID)
+	if err != nil {
+		return leftoverChange{}, err
+	}
+	// undoing removes the whole item, so the id of its only lot is not needed
+	return leftoverChange{InventoryID: item.ID, NewItem: true,
+		Label: backend.NewFreezerLabel(recipe, item, lot)}, nil
+}
+
+//undoLeftoversTx takes leftovers stored by storeLeftoversTx back out of the
+//inventory. Only what is left of their lot is taken out, as some may have
+//been used since they were kept.
+func undoLeftoversTx(tx *sql.Tx, change leftoverChange) error {
+	if change.NewItem {
+		for _, query := range []string{"DELETE FROM inventoryLots WHERE inventoryID = ?",
+			"DELETE FROM leftovers WHERE inventoryID = ?", "DELETE FROM inventory WHERE id = ?"} {
+			if _, err := tx.Exec(query, change.InventoryID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var remaining backend.Fraction
+	err := tx.QueryRow("SELECT quantity FROM inventoryLots WHERE id = ?", change.LotID).Scan(&remaining)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	var quantity backend.Fraction
+	err = tx.QueryRow("SELECT quantity FROM inventory WHERE id = ?", change.InventoryID).Scan(&quantity)
+	if err != nil {
+		return err
+	}
+	quantity = quantity.Sub(remaining)
+	if quantity.Sign() < 0 {
+		quantity = backend.Fraction{}
+	}
+	_, err = tx.Exec("UPDATE inventory SET quantity = ? WHERE id = ?", quantity, change.InventoryID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM inventoryLots WHERE id = ?", change.LotID)
+	return err
+}
+
+//leftoverInventory returns the leftover items named name, so leftovers of
+//one recipe can be used as an ingredient of another without linking them
+func leftoverInventory(tx *sql.Tx, name string) ([]backend.InventoryItem, error) {
+	items, err := selectInventory(tx, "INNER JOIN leftovers ON inventory.id = leftovers.inventoryID "+
+		"ORDER BY inventory.id")
+	if err != nil {
+		return nil, err
+	}
+	var named []backend.InventoryItem
+	for _, item := range items {
+		if backend.CanonicalName(item.Name) == backend.CanonicalName(name) {
+			named = append(named, item)
+		}
+	}
+	return named, nil
+}
+
+//inventoryLabels returns a freezer label for every lot on hand of item.
+//Labels for leftovers list the ingredients of the recipe they came from.
+func inventoryLabels(db *sql.DB, item backend.InventoryItem) ([]backend.FreezerLabel, error) {
+	recipe := backend.Recipe{Name: item.Name}
+	recipes, err := selectRecipesWhere(db, "WHERE recipes.id = (SELECT recipeID FROM leftovers "+
+		"WHERE inventoryID = ?)", item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipes) > 0 {
+		recipe = recipes[0]
+	}
+	var labels []backend.FreezerLabel
+	for _, lot := range item.Lots {
+		if lot.Quantity.Sign() > 0 {
+			labels = append(labels, backend.NewFreezerLabel(recipe, item, lot))
+		}
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("none of %s is on hand to label", item.Name)
+	}
+	return labels, nil
+}
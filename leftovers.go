@@ -0,0 +1,130 @@
+package recipeDatabase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//LeftoverDays is how many days leftovers keep in each storage location.
+//Leftovers kept anywhere else keep as long as in the fridge. Cooked food
+//should not sit out, so leftovers in the pantry only keep until the next
+//day.
+var LeftoverDays = map[string]int{
+	LocationPantry:  1,
+	LocationFridge:  4,
+	LocationFreezer: 90,
+}
+
+//LeftoverExpiry returns the day leftovers made on made and kept in location
+//should be used by
+func LeftoverExpiry(made time.Time, location string) time.Time {
+	days, ok := LeftoverDays[ParseLocation(location)]
+	if !ok {
+		days = LeftoverDays[LocationFridge]
+	}
+	return made.AddDate(0, 0, days)
+}
+
+//NewLeftoverItem returns an inventory item for the leftovers of recipe,
+//with one unit of amount, ie: 1 portion, in each package. Leftovers
+//counted without a unit have no package size. It has no lots; add the
+//leftovers with LeftoverLot.
+func NewLeftoverItem(recipe Recipe, amount Quantity) InventoryItem {
+	item := InventoryItem{Name: recipe.Name, Description: "leftovers of " + recipe.Name}
+	if amount.Unit != "" {
+		item.PackageQuantity = NewQuantity(NewFraction(1, 1), singularWord(amount.Unit))
+	}
+	return item
+}
+
+//LeftoverLot returns the lot of packages of item holding amount of
+//leftovers made on made and kept in location. A zero expires uses the
+//LeftoverDays of the location.
+func LeftoverLot(item InventoryItem, amount Quantity, made time.Time, expires time.Time,
+	location string) (InventoryLot, error) {
+	if amount.Amount.Sign() <= 0 {
+		return InventoryLot{}, errors.New("no leftovers to keep")
+	}
+	packages, err := item.PackagesOf(amount)
+	if err != nil {
+		return InventoryLot{}, err
+	}
+	if expires.IsZero() {
+		expires = LeftoverExpiry(made, location)
+	}
+	return InventoryLot{Quantity: packages, Purchased: made, Expires: expires,
+		Location: ParseLocation(location)}, nil
+}
+
+//A FreezerLabel is what is written on a container of leftovers
+type FreezerLabel struct {
+	Name     string
+	Made     time.Time
+	UseBy    time.Time // zero if it does not expire
+	Amount   Quantity
+	Contents []string // ingredients of the recipe
+}
+
+//NewFreezerLabel returns the label for a lot of leftovers of recipe kept as
+//inventory item
+func NewFreezerLabel(recipe Recipe, item InventoryItem, lot InventoryLot) FreezerLabel {
+	label := FreezerLabel{Name: recipe.Name, Made: lot.Purchased, UseBy: lot.Expires,
+		Amount: NewQuantity(lot.Quantity, "")}
+	if !item.PackageQuantity.IsZero() {
+		label.Amount = NewQuantity(lot.Quantity.Mul(item.PackageQuantity.Amount), item.PackageQuantity.Unit)
+	}
+	for _, ingredient := range recipe.Ingredients {
+		label.Contents = appendIfMissing(label.Contents, ingredient.Name)
+	}
+	return label
+}
+
+//labelWidth is the number of characters inside the border of a label
+const labelWidth = 36
+
+//wrapWords splits text into lines no longer than width, breaking between
+//words. Words longer than width are put on their own line.
+func wrapWords(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+//String draws the label in a box ready to print and cut out
+func (l FreezerLabel) String() string {
+	lines := wrapWords(strings.ToUpper(l.Name), labelWidth)
+	lines = append(lines, "")
+	if !l.Amount.IsZero() {
+		lines = append(lines, l.Amount.Format())
+	}
+	if !l.Made.IsZero() {
+		lines = append(lines, "Made:   "+l.Made.Format(DateFormat))
+	}
+	if !l.UseBy.IsZero() {
+		lines = append(lines, "Use by: "+l.UseBy.Format(DateFormat))
+	}
+	if len(l.Contents) > 0 {
+		lines = append(lines, wrapWords("Contents: "+strings.Join(l.Contents, ", "), labelWidth)...)
+	}
+	border := "+" + strings.Repeat("-", labelWidth+2) + "+\n"
+	stringString := border
+	for _, line := range lines {
+		stringString += fmt.Sprintf("| %-*s |\n", labelWidth, line)
+	}
+	return stringString + border
+}
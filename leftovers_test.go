@@ -0,0 +1,109 @@
+package recipeDatabase
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLeftoverExpiry(t *testing.T) {
+	made := testDate(t, "2026-01-30")
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{"pantry", "2026-01-31"},
+		{"", "2026-01-31"},
+		{"Fridge", "2026-02-03"},
+		{"refrigerator", "2026-02-03"},
+		{"freezer", "2026-04-30"},
+		// other places keep as long as the fridge
+		{"cooler", "2026-02-03"},
+	}
+	for _, test := range tests {
+		if got := LeftoverExpiry(made, test.location).Format(DateFormat); got != test.want {
+			t.Errorf("LeftoverExpiry(2026-01-30, %q) = %s, want %s", test.location, got, test.want)
+		}
+	}
+}
+
+func TestLeftoverLot(t *testing.T) {
+	recipe := Recipe{Name: "chili"}
+	made := testDate(t, "2026-01-30")
+	item := NewLeftoverItem(recipe, NewQuantity(NewFraction(3, 1), "portions"))
+	if item.PackageQuantity.Unit != "portion" || item.PackageQuantity.Amount.Cmp(NewFraction(1, 1)) != 0 {
+		t.Errorf("leftover package is %s, want 1 portion", item.PackageQuantity)
+	}
+	lot, err := LeftoverLot(item, NewQuantity(NewFraction(3, 1), "portions"), made, testDate(t, "2026-02-10"),
+		"Freezer")
+	if err != nil {
+		t.Fatalf("LeftoverLot returned error: %s", err)
+	}
+	if lot.Quantity.Cmp(NewFraction(3, 1)) != 0 || lot.Location != LocationFreezer ||
+		!lot.Purchased.Equal(made) || lot.Expires.Format(DateFormat) != "2026-02-10" {
+		t.Errorf("LeftoverLot = %s, want 3 packages in freezer, bought 2026-01-30, expires 2026-02-10", lot)
+	}
+	// without an expiry date the location decides it
+	lot, err = LeftoverLot(item, NewQuantity(NewFraction(1, 1), "portion"), made, time.Time{},
+		"fridge")
+	if err != nil || lot.Expires.Format(DateFormat) != "2026-02-03" {
+		t.Errorf("LeftoverLot in the fridge = %s, %v, want it to expire 2026-02-03", lot, err)
+	}
+
+	for _, amount := range []Quantity{NewQuantity(Fraction{}, "portions"),
+		NewQuantity(NewFraction(-1, 1), "portions"), NewQuantity(NewFraction(1, 1), "handful")} {
+		if lot, err = LeftoverLot(item, amount, made, time.Time{}, "fridge"); err == nil {
+			t.Errorf("LeftoverLot(%s) = %s, want an error", amount, lot)
+		}
+	}
+
+	// leftovers counted without a unit are counted in packages
+	counted := NewLeftoverItem(recipe, NewQuantity(NewFraction(2, 1), ""))
+	if !counted.PackageQuantity.IsZero() {
+		t.Errorf("leftover package counted without a unit is %s, want none", counted.PackageQuantity)
+	}
+	if lot, err = LeftoverLot(counted, NewQuantity(NewFraction(2, 1), ""), made, time.Time{},
+		""); err != nil || lot.Quantity.Cmp(NewFraction(2, 1)) != 0 {
+		t.Errorf("LeftoverLot(2) = %s, %v, want 2 packages", lot, err)
+	}
+}
+
+func TestFreezerLabel(t *testing.T) {
+	recipe := Recipe{Name: "chili", Ingredients: []Ingredient{{Name: "beans"}, {Name: "onion"}, {Name: "beans"}}}
+	item := NewLeftoverItem(recipe, NewQuantity(NewFraction(2, 1), "portions"))
+	lot := InventoryLot{Quantity: NewFraction(2, 1), Purchased: testDate(t, "2026-01-30"),
+		Expires: testDate(t, "2026-04-30")}
+	label := NewFreezerLabel(recipe, item, lot)
+	if !reflect.DeepEqual(label.Contents, []string{"beans", "onion"}) {
+		t.Errorf("label contents are %v, want [beans onion]", label.Contents)
+	}
+	want := "+--------------------------------------+\n" +
+		"| CHILI                                |\n" +
+		"|                                      |\n" +
+		"| 2 portions                           |\n" +
+		"| Made:   2026-01-30                   |\n" +
+		"| Use by: 2026-04-30                   |\n" +
+		"| Contents: beans, onion               |\n" +
+		"+--------------------------------------+\n"
+	if got := label.String(); got != want {
+		t.Errorf("label is\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWrapWords(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"one two three", 7, []string{"one two", "three"}},
+		{"one two three", 20, []string{"one two three"}},
+		{"a supercalifragilistic word", 5, []string{"a", "supercalifragilistic", "word"}},
+		{"", 5, nil},
+	}
+	for _, test := range tests {
+		if got := wrapWords(test.text, test.width); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("wrapWords(%q, %d) = %q, want %q", test.text, test.width, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,558 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+	backend "github.com/sww1235/recipe-database"
+)
+
+const mealPlanUsage = `usage: cookbook plan <command> [arguments]
+
+commands:
+	add [-slot <slot>] [-servings <servings>] [-notes <notes>] [-for <names>] <date> <recipe name>
+		plan a recipe for a meal, ie: add -servings 6 friday "Chili", eaten
+		by the comma separated household members given, default everyone
+	remove <id>                remove a planned meal
+	week [date]                show the week a date is in, default this week
+	list [from] [to]           list meals planned from from to to, default
+	                           the next 7 days
+	shop [flags] [from] [to]   shopping list for the meals planned, minus
+	                           inventory on hand, run with -h for flags
+	ics [-o <file>] [from] [to]
+		export the meals planned as an iCalendar file
+
+Slots are breakfast, lunch, snack, dinner, dessert or any other name, default
+dinner. Dates are given as YYYY-MM-DD, today, tomorrow or a day of the week
+for the next one, ie: friday. Meals are shown with a warning for each
+ingredient that someone eating them can not or will not eat.
+`
+
+//mealPlanCommand runs the plan subcommand given on the command line
+func mealPlanCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(mealPlanUsage)
+		return errors.New("no plan command given")
+	}
+	switch args[0] {
+	case "add":
+		flags := flag.NewFlagSet("plan add", flag.ContinueOnError)
+		slot := flags.String("slot", backend.SlotDinner, "Meal slot, ie: breakfast, lunch or dinner")
+		servings := flags.String("servings", "", "Servings to make, default the amount the recipe makes")
+		notes := flags.String("notes", "", "Notes about the meal")
+		eaters := flags.String("for", "", "Comma separated household members eating, default everyone")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 2 {
+			fmt.Print(mealPlanUsage)
+			return errors.New("plan add needs a date and a recipe name")
+		}
+		meal := backend.PlannedMeal{Slot: backend.ParseMealSlot(*slot), Notes: *notes}
+		var err error
+		check, err := newHouseholdCheck(db)
+		if err != nil {
+			return err
+		}
+		eating, err := backend.MembersNamed(check.members, splitTags(*eaters))
+		if err != nil {
+			return err
+		}
+		if *eaters != "" {
+			for _, member := range eating {
+				meal.Eaters = append(meal.Eaters, member.Name)
+			}
+		}
+		meal.Date, err = parsePlanDate(flags.Arg(0), today())
+		if err != nil {
+			return err
+		}
+		if *servings != "" {
+			meal.Servings, err = backend.ParseFraction(*servings)
+			if err != nil {
+				return fmt.Errorf("invalid servings %q: %s", *servings, err)
+			}
+		}
+		meal.Recipe, err = chooseRecipe(db, flags.Arg(1), bufio.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		id, err := insertMeal(db, meal)
+		if err != nil {
+			return err
+		}
+		meal.ID = int(id)
+		meal.Check(check.members, check.catalog, check.substitutions)
+		fmt.Print(meal)
+		return nil
+	case "remove":
+		if len(args) < 2 {
+			return errors.New("usage: plan remove <id>")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid meal id %q", args[1])
+		}
+		return deleteMeal(db, id)
+	case "week":
+		date := today()
+		if len(args) > 1 {
+			var err error
+			date, err = parsePlanDate(args[1], date)
+			if err != nil {
+				return err
+			}
+		}
+		start := backend.WeekStart(date)
+		plan, err := selectMealPlan(db, start, start.AddDate(0, 0, 6))
+		if err != nil {
+			return err
+		}
+		if err = checkMealPlan(db, &plan); err != nil {
+			return err
+		}
+		fmt.Print(plan.WeekString(start))
+		return nil
+	case "list":
+		from, to, err := planRange(args[1:])
+		if err != nil {
+			return err
+		}
+		plan, err := selectMealPlan(db, from, to)
+		if err != nil {
+			return err
+		}
+		if err = checkMealPlan(db, &plan); err != nil {
+			return err
+		}
+		for _, meal := range plan.Meals {
+			fmt.Print(meal)
+		}
+		return nil
+	case "shop":
+		flags := flag.NewFlagSet("plan shop", flag.ContinueOnError)
+		format := flags.String("format", "text", "Output format: text, md, json or csv")
+		output := flags.String("o", "", "File to write shopping list to, default stdout")
+		ignoreInventory := flags.Bool("all", false, "Do not subtract inventory on hand")
+		noSubstitutes := flags.Bool("nosubs", false, "Do not use substitutions for ingredients that are short")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		from, to, err := planRange(flags.Args())
+		if err != nil {
+			return err
+		}
+		plan, err := selectMealPlan(db, from, to)
+		if err != nil {
+			return err
+		}
+		list, err := plan.ShoppingList()
+		if err != nil {
+			return err
+		}
+		if !*ignoreInventory {
+			if err = subtractInventory(db, &list, !*noSubstitutes); err != nil {
+				return err
+			}
+		}
+		exported, err := list.Export(*format)
+		if err != nil {
+			return err
+		}
+		if *output != "" {
+			return ioutil.WriteFile(*output, []byte(exported), 0644)
+		}
+		fmt.Print(exported)
+		return nil
+	case "ics":
+		flags := flag.NewFlagSet("plan ics", flag.ContinueOnError)
+		output := flags.String("o", "", "File to write calendar to, default stdout")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		from, to, err := planRange(flags.Args())
+		if err != nil {
+			return err
+		}
+		plan, err := selectMealPlan(db, from, to)
+		if err != nil {
+			return err
+		}
+		calendar := plan.ICS(time.Now())
+		if *output != "" {
+			return ioutil.WriteFile(*output, []byte(calendar), 0644)
+		}
+		fmt.Print(calendar)
+		return nil
+	}
+	fmt.Print(mealPlanUsage)
+	return errors.New("unknown plan command " + args[0])
+}
+
+//parsePlanDate reads a date as YYYY-MM-DD, today, tomorrow, yesterday or
+//the name of a day of the week, which is the next such day from now
+func parsePlanDate(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "today":
+		return now, nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return now.AddDate(0, 0, (int(day)-int(now.Weekday())+7)%7), nil
+		}
+	}
+	date, err := time.Parse(backend.DateFormat, s)
+	if err != nil {
+		return date, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or a day", s)
+	}
+	return date, nil
+}
+
+//planRange reads the optional from and to dates of a plan command. from
+//defaults to today and to to the 6 days after from.
+func planRange(args []string) (time.Time, time.Time, error) {
+	from := today()
+	var err error
+	if len(args) > 0 {
+		if from, err = parsePlanDate(args[0], from); err != nil {
+			return from, from, err
+		}
+	}
+	to := from.AddDate(0, 0, 6)
+	if len(args) > 1 {
+		if to, err = parsePlanDate(args[1], from); err != nil {
+			return from, to, err
+		}
+	}
+	if to.Before(from) {
+		return from, to, errors.New("plan range ends before it starts")
+	}
+	return from, to, nil
+}
+
+//selectMealPlan reads the meals planned from from to to, including both
+//days, with their recipes
+func selectMealPlan(db *sql.DB, from time.Time, to time.Time) (backend.MealPlan, error) {
+	var plan backend.MealPlan
+	rows, err := db.Query("SELECT id, date, IFNULL(slot, ''), recipeID, IFNULL(servings, 0), "+
+		"IFNULL(notes, '') FROM mealPlan WHERE date >= ? AND date <= ? ORDER BY date, id",
+		from.Format(backend.DateFormat), to.Format(backend.DateFormat))
+	if err != nil {
+		return plan, err
+	}
+	var recipeIDs []int
+	for rows.Next() {
+		var meal backend.PlannedMeal
+		var date string
+		var recipeID int
+		err = rows.Scan(&meal.ID, &date, &meal.Slot, &recipeID, &meal.Servings, &meal.Notes)
+		if err != nil {
+			rows.Close()
+			return plan, err
+		}
+		if meal.Date, err = parseDate(date); err != nil {
+			rows.Close()
+			return plan, err
+		}
+		plan.Meals = append(plan.Meals, meal)
+		recipeIDs = append(recipeIDs, recipeID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return plan, err
+	}
+	if err = selectMealEaters(db, &plan); err != nil {
+		return plan, err
+	}
+
+	recipes := make(map[int]backend.Recipe)
+	for i, recipeID := range recipeIDs {
+		recipe, ok := recipes[recipeID]
+		if !ok {
+			found, err := selectRecipesWhere(db, "WHERE recipes.id = ?", recipeID)
+			if err != nil {
+				return plan, err
+			}
+			if len(found) == 0 {
+				return plan, fmt.Errorf("meal %d is planned for a recipe that does not exist", plan.Meals[i].ID)
+			}
+			recipe = found[0]
+			recipes[recipeID] = recipe
+		}
+		plan.Meals[i].Recipe = recipe
+	}
+	plan.Sort()
+	return plan, nil
+}
+
+//selectMealEaters reads the household members eating each meal of plan,
+//by name
+func selectMealEaters(db *sql.DB, plan *backend.MealPlan) error {
+	rows, err := db.Query("SELECT mealEaters.mealID, householdMembers.name FROM mealEaters " +
+		"INNER JOIN householdMembers ON mealEaters.memberID = householdMembers.id " +
+		"ORDER BY householdMembers.name COLLATE NOCASE")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	meals := make(map[int]*backend.PlannedMeal)
+	for i := range plan.Meals {
+		meals[plan.Meals[i].ID] = &plan.Meals[i]
+	}
+	for rows.Next() {
+		var mealID int
+		var name string
+		if err = rows.Scan(&mealID, &name); err != nil {
+			return err
+		}
+		if meal, ok := meals[mealID]; ok {
+			meal.Eaters = append(meal.Eaters, name)
+		}
+	}
+	return rows.Err()
+}
+
+//insertMeal stores a planned meal, with the household members eating it,
+//and returns its id
+func insertMeal(db *sql.DB, meal backend.PlannedMeal) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("INSERT INTO mealPlan (date, slot, recipeID, servings, notes) VALUES (?, ?, ?, ?, ?)",
+		meal.Date.Format(backend.DateFormat), meal.Slot, meal.Recipe.ID, meal.Servings, meal.Notes)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	for _, name := range meal.Eaters {
+		_, err = tx.Exec("INSERT INTO mealEaters (mealID, memberID) SELECT ?, id FROM householdMembers "+
+			"WHERE name = ?", id, name)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+//deleteMeal removes a planned meal
+func deleteMeal(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM mealEaters WHERE mealID = ?", id)
+	if err != nil {
+		return err
+	}
+	result, err := db.Exec("DELETE FROM mealPlan WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if count, err := result.RowsAffected(); err == nil && count == 0 {
+		return fmt.Errorf("no planned meal with id %d", id)
+	}
+	return nil
+}
+
+//mealPlanState is the open meal plan screen of the CUI
+type mealPlanState struct {
+	db      *sql.DB
+	start   time.Time // first day of the week shown
+	week    string    // meals planned for the week
+	message string    // result of the last entry
+}
+
+//refresh reads the week shown again after it changes
+func (m *mealPlanState) refresh() {
+	plan, err := selectMealPlan(m.db, m.start, m.start.AddDate(0, 0, 6))
+	if err != nil {
+		m.week = fmt.Sprintln("Error reading meal plan:", err)
+		return
+	}
+	if err = checkMealPlan(m.db, &plan); err != nil {
+		m.week = fmt.Sprintln("Error checking meal plan:", err)
+		return
+	}
+	m.week = plan.WeekString(m.start)
+}
+
+//enter adds a meal typed as "<date> [slot] <recipe name>[:servings]", or
+//removes one typed as "rm <id>"
+func (m *mealPlanState) enter(text string) error {
+	fields := strings.Fields(text)
+	if len(fields) == 2 && (fields[0] == "rm" || fields[0] == "remove") {
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid meal id %q", fields[1])
+		}
+		if err = deleteMeal(m.db, id); err != nil {
+			return err
+		}
+		m.message = fmt.Sprintf("Removed meal %d", id)
+		return nil
+	}
+	if len(fields) < 2 {
+		return errors.New("enter <date> [slot] <recipe name>[:servings] or rm <id>")
+	}
+	meal := backend.PlannedMeal{Slot: backend.SlotDinner}
+	var err error
+	meal.Date, err = parsePlanDate(fields[0], today())
+	if err != nil {
+		return err
+	}
+	fields = fields[1:]
+	for _, slot := range backend.MealSlots {
+		if len(fields) > 1 && strings.EqualFold(fields[0], slot) {
+			meal.Slot = slot
+			fields = fields[1:]
+			break
+		}
+	}
+	name, scale := strings.Join(fields, " "), ""
+	if colon := strings.LastIndex(name, ":"); colon >= 0 {
+		name, scale = strings.TrimSpace(name[:colon]), strings.TrimSpace(name[colon+1:])
+	}
+	if scale != "" {
+		if meal.Servings, err = backend.ParseFraction(scale); err != nil {
+			return fmt.Errorf("invalid servings %q: %s", scale, err)
+		}
+	}
+	recipes, err := selectRecipes(m.db, name)
+	if err != nil {
+		return err
+	}
+	if len(recipes) == 0 {
+		return fmt.Errorf("no recipe named %s", name)
+	}
+	meal.Recipe = recipes[0]
+	id, err := insertMeal(m.db, meal)
+	if err != nil {
+		return err
+	}
+	meal.ID = int(id)
+	m.message = "Planned " + strings.TrimSpace(meal.String())
+	m.start = backend.WeekStart(meal.Date)
+	return nil
+}
+
+//mealPlan is the open meal plan screen, nil when it is closed
+var mealPlan *mealPlanState
+
+//meal plan view names
+const (
+	mealPlanInputView = "mealPlanInput"
+	mealPlanWeekView  = "mealPlanWeek"
+)
+
+//openMealPlan shows the meals planned for this week over the main view
+func openMealPlan(gui *gocui.Gui, db *sql.DB) error {
+	if screenOpen() {
+		return nil
+	}
+	mealPlan = &mealPlanState{db: db, start: backend.WeekStart(today())}
+	mealPlan.refresh()
+	if err := mealPlanLayout(gui); err != nil {
+		return err
+	}
+	bindings := []struct {
+		key     interface{}
+		handler func(*gocui.Gui, *gocui.View) error
+	}{
+		{gocui.KeyEnter, mealPlanEnter},
+		{gocui.KeyCtrlN, mealPlanNextWeek},
+		{gocui.KeyCtrlP, mealPlanPreviousWeek},
+		{gocui.KeyEsc, closeMealPlan},
+	}
+	for _, binding := range bindings {
+		if err := gui.SetKeybinding(mealPlanInputView, binding.key, gocui.ModNone, binding.handler); err != nil {
+			return err
+		}
+	}
+	gui.Cursor = true
+	_, err := gui.SetCurrentView(mealPlanInputView)
+	return err
+}
+
+//mealPlanLayout creates or resizes the meal plan views
+func mealPlanLayout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	inputView, err := gui.SetView(mealPlanInputView, 0, 0, maxX-1, 2, 0)
+	if err != nil {
+		if !gocui.IsUnknownView(err) {
+			return err
+		}
+		inputView.Editable = true
+	}
+	inputView.Title = "<date> [slot] <recipe>[:servings] or rm <id>"
+	if mealPlan.message != "" {
+		inputView.Title = mealPlan.message
+	}
+
+	weekView, err := gui.SetView(mealPlanWeekView, 0, 3, maxX-1, maxY-3, 0)
+	if err != nil {
+		if !gocui.IsUnknownView(err) {
+			return err
+		}
+		weekView.Wrap = true
+	}
+	weekView.Title = "Meal plan"
+	weekView.Clear()
+	fmt.Fprint(weekView, mealPlan.week)
+	return nil
+}
+
+func mealPlanEnter(gui *gocui.Gui, view *gocui.View) error {
+	text := viewText(view)
+	if err := clearInput(view); err != nil {
+		return err
+	}
+	if err := mealPlan.enter(text); err != nil {
+		mealPlan.message = err.Error()
+	}
+	mealPlan.refresh()
+	return mealPlanLayout(gui)
+}
+
+func mealPlanNextWeek(gui *gocui.Gui, _ *gocui.View) error {
+	mealPlan.start = mealPlan.start.AddDate(0, 0, 7)
+	mealPlan.refresh()
+	return mealPlanLayout(gui)
+}
+
+func mealPlanPreviousWeek(gui *gocui.Gui, _ *gocui.View) error {
+	mealPlan.start = mealPlan.start.AddDate(0, 0, -7)
+	mealPlan.refresh()
+	return mealPlanLayout(gui)
+}
+
+//closeMealPlan returns to the main view
+func closeMealPlan(gui *gocui.Gui, _ *gocui.View) error {
+	for _, name := range []string{mealPlanInputView, mealPlanWeekView} {
+		gui.DeleteKeybindings(name)
+		if err := gui.DeleteView(name); err != nil {
+			return err
+		}
+	}
+	mealPlan = nil
+	gui.Cursor = false
+	_, err := gui.SetCurrentView("main")
+	return err
+}
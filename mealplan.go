@@ -0,0 +1,256 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Meal slots a recipe can be planned for. Any other slot name can also be
+//used, and is planned after the standard ones.
+const (
+	SlotBreakfast = "breakfast"
+	SlotLunch     = "lunch"
+	SlotSnack     = "snack"
+	SlotDinner    = "dinner"
+	SlotDessert   = "dessert"
+)
+
+//MealSlots lists the standard meal slots in the order they are eaten
+var MealSlots = []string{SlotBreakfast, SlotLunch, SlotSnack, SlotDinner, SlotDessert}
+
+//slotTimes is when each standard meal starts, as hours and minutes from
+//midnight, used for calendar events
+var slotTimes = map[string]time.Duration{
+	SlotBreakfast: 8 * time.Hour,
+	SlotLunch:     12 * time.Hour,
+	SlotSnack:     15 * time.Hour,
+	SlotDinner:    18 * time.Hour,
+	SlotDessert:   19*time.Hour + 30*time.Minute,
+}
+
+//ParseMealSlot cleans up a meal slot name. Empty slots are dinner.
+func ParseMealSlot(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return SlotDinner
+	case "supper", "tea":
+		return SlotDinner
+	case "brunch":
+		return SlotBreakfast
+	}
+	return s
+}
+
+//slotOrder returns the position of slot in MealSlots, with other slots last
+func slotOrder(slot string) int {
+	for i, standard := range MealSlots {
+		if slot == standard {
+			return i
+		}
+	}
+	return len(MealSlots)
+}
+
+//A PlannedMeal is a recipe planned for a meal on a date
+type PlannedMeal struct {
+	ID       int       // id of meal in database
+	Date     time.Time // day of meal
+	Slot     string    // meal slot, ie: dinner
+	Recipe   Recipe    // recipe to make
+	Servings Fraction  // servings to make, zero for the amount the recipe makes
+	Notes    string    // notes about the meal
+	Eaters   []string  // household members eating the meal, everyone if empty
+	// ingredients the people eating the meal can not or will not eat
+	Warnings []MemberConflict
+}
+
+func (m PlannedMeal) String() string {
+	stringString := fmt.Sprintf("%d) %s %s: %s", m.ID, m.Date.Format(DateFormat), m.Slot, m.Recipe.Name)
+	if !m.Servings.IsZero() {
+		stringString += fmt.Sprintf(" (%s servings)", m.Servings.KitchenString())
+	}
+	if len(m.Eaters) > 0 {
+		stringString += " for " + strings.Join(m.Eaters, ", ")
+	}
+	if m.Notes != "" {
+		stringString += " - " + m.Notes
+	}
+	stringString += "\n"
+	for _, warning := range m.Warnings {
+		stringString += "\twarning: " + warning.String() + "\n"
+	}
+	return stringString
+}
+
+//Check sets the warnings of the meal for the household members eating it,
+//or every member if no eaters are given
+func (m *PlannedMeal) Check(members []HouseholdMember, catalog []CatalogIngredient,
+	substitutions []Substitution) {
+	eating := members
+	if len(m.Eaters) > 0 {
+		eating = nil
+		for _, member := range members {
+			for _, name := range m.Eaters {
+				if strings.EqualFold(member.Name, name) {
+					eating = append(eating, member)
+				}
+			}
+		}
+	}
+	m.Warnings = HouseholdConflicts(m.Recipe, eating, catalog, substitutions)
+}
+
+//Scale returns the factor to scale the recipe by to make the servings
+//planned, 1 when either the servings planned or the servings the recipe
+//makes are unknown
+func (m PlannedMeal) Scale() float64 {
+	servings := m.Recipe.Servings()
+	if m.Servings.IsZero() || servings.IsZero() {
+		return 1
+	}
+	return m.Servings.Div(servings).Float64()
+}
+
+//A MealPlan is the meals planned over a period
+type MealPlan struct {
+	Meals []PlannedMeal
+}
+
+//Sort orders the meals by date, then slot
+func (p *MealPlan) Sort() {
+	sort.SliceStable(p.Meals, func(a, b int) bool {
+		if !p.Meals[a].Date.Equal(p.Meals[b].Date) {
+			return p.Meals[a].Date.Before(p.Meals[b].Date)
+		}
+		return slotOrder(p.Meals[a].Slot) < slotOrder(p.Meals[b].Slot)
+	})
+}
+
+//Between returns the meals planned from from to to, including both days
+func (p MealPlan) Between(from time.Time, to time.Time) MealPlan {
+	var between MealPlan
+	for _, meal := range p.Meals {
+		if !meal.Date.Before(from) && !meal.Date.After(to) {
+			between.Meals = append(between.Meals, meal)
+		}
+	}
+	return between
+}
+
+//WeekStart returns the monday of the week date is in
+func WeekStart(date time.Time) time.Time {
+	offset := (int(date.Weekday()) + 6) % 7
+	return date.AddDate(0, 0, -offset)
+}
+
+//WeekString shows the meals planned for the seven days from start, one
+//day per line with its meals beneath it
+func (p MealPlan) WeekString(start time.Time) string {
+	end := start.AddDate(0, 0, 6)
+	week := p.Between(start, end)
+	week.Sort()
+	stringString := fmt.Sprintf("Week of %s\n", start.Format(DateFormat))
+	for day := 0; day < 7; day++ {
+		date := start.AddDate(0, 0, day)
+		stringString += fmt.Sprintf("%s %s\n", date.Format("Mon"), date.Format(DateFormat))
+		for _, meal := range week.Meals {
+			if meal.Date.Equal(date) {
+				// indent the warnings beneath the meal as well
+				stringString += "\t" + strings.Replace(strings.TrimSuffix(meal.String(), "\n"), "\n", "\n\t", -1) + "\n"
+			}
+		}
+	}
+	return stringString
+}
+
+//ShoppingList adds every planned recipe, scaled to the servings planned,
+//to a shopping list
+func (p MealPlan) ShoppingList() (ShoppingList, error) {
+	var list ShoppingList
+	for _, meal := range p.Meals {
+		if err := list.AddRecipe(meal.Recipe, meal.Scale()); err != nil {
+			return list, fmt.Errorf("%s on %s: %s", meal.Recipe.Name, meal.Date.Format(DateFormat), err)
+		}
+	}
+	return list, nil
+}
+
+//ICS returns the plan as an iCalendar file with one event per meal. Events
+//use floating local times, so they show at the time of the meal in any time
+//zone. now is the time stamp of every event.
+func (p MealPlan) ICS(now time.Time) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//recipe-database//cookbook//EN",
+		"CALSCALE:GREGORIAN"}
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, meal := range p.Meals {
+		start, ok := slotTimes[meal.Slot]
+		if !ok {
+			start = 12 * time.Hour
+		}
+		length := meal.Recipe.TotalTime()
+		if length <= 0 {
+			length = time.Hour
+		}
+		begin := meal.Date.Add(start)
+		summary := meal.Recipe.Name
+		if meal.Slot != "" {
+			summary = strings.ToUpper(meal.Slot[:1]) + meal.Slot[1:] + ": " + summary
+		}
+		if !meal.Servings.IsZero() {
+			summary += fmt.Sprintf(" (%s servings)", meal.Servings.KitchenString())
+		}
+		description := ""
+		if meal.Notes != "" {
+			description = meal.Notes + "\n"
+		}
+		scaled, err := meal.Recipe.Scale(meal.Scale())
+		if err != nil {
+			scaled = meal.Recipe
+		}
+		for _, ingredient := range scaled.Ingredients {
+			description += ingredient.String()
+		}
+		lines = append(lines, "BEGIN:VEVENT",
+			fmt.Sprintf("UID:meal-%d-%s@recipe-database", meal.ID, meal.Date.Format("20060102")),
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+begin.Format("20060102T150405"),
+			"DTEND:"+begin.Add(length).Format("20060102T150405"),
+			"SUMMARY:"+icsEscape(summary),
+			"DESCRIPTION:"+icsEscape(strings.TrimSpace(description)),
+			"CATEGORIES:"+icsEscape(meal.Slot),
+			"END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	var folded []string
+	for _, line := range lines {
+		folded = append(folded, icsFold(line))
+	}
+	return strings.Join(folded, "\r\n") + "\r\n"
+}
+
+//icsEscape escapes text for an iCalendar property value
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+//icsFold splits a content line longer than 75 octets into continuation
+//lines, without splitting a utf-8 character
+func icsFold(line string) string {
+	const limit = 75
+	var folded strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > limit {
+			folded.WriteString("\r\n ")
+			// the leading space of a continuation line counts towards it
+			length = 1
+		}
+		folded.WriteRune(r)
+		length += size
+	}
+	return folded.String()
+}
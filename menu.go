@@ -0,0 +1,336 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Courses of a menu, in the order they are served. Any other course name can
+//also be used, and is served after the standard ones.
+const (
+	CourseAppetizer = "appetizer"
+	CourseSoup      = "soup"
+	CourseSalad     = "salad"
+	CourseMain      = "main"
+	CourseSide      = "side"
+	CourseDessert   = "dessert"
+	CourseDrink     = "drink"
+)
+
+//Courses lists the standard courses in the order they are served
+var Courses = []string{CourseAppetizer, CourseSoup, CourseSalad, CourseMain, CourseSide, CourseDessert,
+	CourseDrink}
+
+//ParseCourse cleans up a course name. Empty courses are the main course.
+func ParseCourse(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "entree", "entrée":
+		return CourseMain
+	case "starter", "appetiser", "hors d'oeuvre":
+		return CourseAppetizer
+	case "sides":
+		return CourseSide
+	case "drinks", "beverage":
+		return CourseDrink
+	}
+	return s
+}
+
+//courseOrder returns the position of course in Courses, with other courses
+//last
+func courseOrder(course string) int {
+	for i, standard := range Courses {
+		if course == standard {
+			return i
+		}
+	}
+	return len(Courses)
+}
+
+//A MenuDish is a recipe served as one course of a menu
+type MenuDish struct {
+	ID       int      // id of dish in database
+	Course   string   // course the dish is served in, ie: main
+	Recipe   Recipe   // recipe to make
+	PerGuest Fraction // servings for each guest, zero for one
+	Notes    string   // notes about the dish
+}
+
+//Servings returns the servings of the dish needed for guests
+func (d MenuDish) Servings(guests int) Fraction {
+	perGuest := d.PerGuest
+	if perGuest.IsZero() {
+		perGuest = NewFraction(1, 1)
+	}
+	return perGuest.Mul(NewFraction(int64(guests), 1))
+}
+
+//Scale returns the factor to scale the recipe by to serve guests, 1 when
+//there are no guests or the servings the recipe makes are unknown
+func (d MenuDish) Scale(guests int) float64 {
+	servings := d.Recipe.Servings()
+	if guests <= 0 || servings.IsZero() {
+		return 1
+	}
+	return d.Servings(guests).Div(servings).Float64()
+}
+
+//A Menu groups recipes into courses for one meal, such as a holiday dinner
+type Menu struct {
+	ID     int        // id of menu in database
+	Name   string     // name of menu, ie: Thanksgiving
+	Date   time.Time  // day the menu is served, zero if not set
+	Guests int        // number of guests, zero to make each recipe as written
+	Notes  string     // notes about the menu
+	Dishes []MenuDish // dishes in the order they are served
+}
+
+//Sort orders the dishes by course, keeping the order they were added
+//within each course
+func (m *Menu) Sort() {
+	sort.SliceStable(m.Dishes, func(a, b int) bool {
+		return courseOrder(m.Dishes[a].Course) < courseOrder(m.Dishes[b].Course)
+	})
+}
+
+//courses returns the courses of the menu with dishes, in order
+func (m Menu) courses() []string {
+	var courses []string
+	for _, dish := range m.Dishes {
+		courses = appendIfMissing(courses, dish.Course)
+	}
+	sort.SliceStable(courses, func(a, b int) bool {
+		return courseOrder(courses[a]) < courseOrder(courses[b])
+	})
+	return courses
+}
+
+func (m Menu) String() string {
+	stringString := fmt.Sprintf("%d) %s", m.ID, m.Name)
+	if !m.Date.IsZero() {
+		stringString += " on " + m.Date.Format(DateFormat)
+	}
+	if m.Guests > 0 {
+		stringString += fmt.Sprintf(" for %d guests", m.Guests)
+	}
+	stringString += "\n"
+	if m.Notes != "" {
+		stringString += "\t" + m.Notes + "\n"
+	}
+	for _, course := range m.courses() {
+		stringString += "\t" + strings.Title(course) + ":\n"
+		for _, dish := range m.Dishes {
+			if dish.Course != course {
+				continue
+			}
+			stringString += fmt.Sprintf("\t\t%d) %s", dish.ID, dish.Recipe.Name)
+			if m.Guests > 0 && !dish.Recipe.Servings().IsZero() {
+				stringString += fmt.Sprintf(", %s servings (x%s)", dish.Servings(m.Guests).KitchenString(),
+					FractionFromFloat(dish.Scale(m.Guests)).DecimalString(2))
+			}
+			if dish.Notes != "" {
+				stringString += " - " + dish.Notes
+			}
+			stringString += "\n"
+		}
+	}
+	return stringString
+}
+
+//ShoppingList adds every dish, scaled to the guests, to a shopping list
+func (m Menu) ShoppingList() (ShoppingList, error) {
+	var list ShoppingList
+	for _, dish := range m.Dishes {
+		if err := list.AddRecipe(dish.Recipe, dish.Scale(m.Guests)); err != nil {
+			return list, fmt.Errorf("%s: %s", dish.Recipe.Name, err)
+		}
+	}
+	return list, nil
+}
+
+//equipmentWords are the words in the instructions of a step that show it
+//uses a piece of equipment
+var equipmentWords = map[string][]string{
+	"oven":            {"oven", "preheat", "bake", "baked", "baking", "roast", "roasted", "roasting", "broil", "broiling"},
+	"stovetop":        {"stove", "stovetop", "burner", "skillet", "saucepan", "frying pan", "simmer", "boil", "saute", "sauté", "fry"},
+	"grill":           {"grill", "grilled", "grilling", "barbecue"},
+	"slow cooker":     {"slow cooker", "crock pot", "crockpot"},
+	"pressure cooker": {"pressure cooker", "instant pot"},
+	"microwave":       {"microwave"},
+	"stand mixer":     {"stand mixer", "mixer"},
+	"food processor":  {"food processor"},
+	"blender":         {"blender", "blend"},
+}
+
+//stepEquipment returns the equipment a step uses, found from the words of
+//its instructions. A temperature alone does not mean the oven, as it can be
+//of oil, a fridge or the food itself.
+func stepEquipment(step Step) []string {
+	words := nameWords(step.Instructions)
+	var equipment []string
+	for name, phrases := range equipmentWords {
+		for _, phrase := range phrases {
+			if len(phraseSpans(words, phrase)) > 0 {
+				equipment = append(equipment, name)
+				break
+			}
+		}
+	}
+	sort.Strings(equipment)
+	return equipment
+}
+
+//A MenuEquipment is a piece of equipment needed by the dishes of a menu
+type MenuEquipment struct {
+	Name    string   `json:"name"`
+	Recipes []string `json:"recipes"` // recipes using it
+	// temperature each recipe needs it at, for the oven
+	Temperatures map[string]string `json:"temperatures,omitempty"`
+}
+
+//An EquipmentConflict is equipment needed by more dishes at once than the
+//kitchen has, such as one oven at two temperatures
+type EquipmentConflict struct {
+	Equipment string `json:"equipment"`
+	Problem   string `json:"problem"`
+}
+
+func (c EquipmentConflict) String() string {
+	return c.Equipment + ": " + c.Problem
+}
+
+//Equipment lists the equipment needed by every dish of the menu, and the
+//conflicts between dishes needing the oven at different temperatures when
+//there are fewer ovens than temperatures. Equipment listed for a recipe is
+//included with the equipment found from its steps.
+func (m Menu) Equipment(ovens int) ([]MenuEquipment, []EquipmentConflict) {
+	byName := make(map[string]*MenuEquipment)
+	var names []string
+	use := func(name string, recipe string) *MenuEquipment {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if byName[name] == nil {
+			byName[name] = &MenuEquipment{Name: name}
+			names = append(names, name)
+		}
+		byName[name].Recipes = appendIfMissing(byName[name].Recipes, recipe)
+		return byName[name]
+	}
+	// oven temperatures in degrees fahrenheit, rounded so that 180 C and
+	// 350 F count as the same, with the recipes needing each
+	ovenTemperatures := make(map[int][]string)
+	var temperatureOrder []int
+	for _, dish := range m.Dishes {
+		for _, equipment := range dish.Recipe.EquipmentNeeded {
+			use(equipment.Name, dish.Recipe.Name)
+		}
+		for _, step := range dish.Recipe.Steps {
+			for _, name := range stepEquipment(step) {
+				equipment := use(name, dish.Recipe.Name)
+				if name != "oven" || step.Temperature.Value == 0 {
+					continue
+				}
+				degrees := step.Temperature.Value
+				if step.Temperature.Unit.valid() {
+					degrees = step.Temperature.Convert('F')
+				}
+				rounded := int(math.Round(degrees/25) * 25)
+				if _, seen := ovenTemperatures[rounded]; !seen {
+					temperatureOrder = append(temperatureOrder, rounded)
+				}
+				ovenTemperatures[rounded] = appendIfMissing(ovenTemperatures[rounded], dish.Recipe.Name)
+				if equipment.Temperatures == nil {
+					equipment.Temperatures = make(map[string]string)
+				}
+				equipment.Temperatures[dish.Recipe.Name] = step.Temperature.String()
+			}
+		}
+	}
+	sort.Strings(names)
+	var equipment []MenuEquipment
+	for _, name := range names {
+		equipment = append(equipment, *byName[name])
+	}
+
+	var conflicts []EquipmentConflict
+	if ovens <= 0 {
+		ovens = 1
+	}
+	if len(temperatureOrder) > ovens {
+		sort.Ints(temperatureOrder)
+		var needs []string
+		for _, degrees := range temperatureOrder {
+			needs = append(needs, fmt.Sprintf("%s at %dº F", strings.Join(ovenTemperatures[degrees], " and "),
+				degrees))
+		}
+		ovenString := "1 oven"
+		if ovens > 1 {
+			ovenString = fmt.Sprintf("%d ovens", ovens)
+		}
+		conflicts = append(conflicts, EquipmentConflict{Equipment: "oven",
+			Problem: fmt.Sprintf("%d temperatures needed with %s: %s, so stagger them or cook one ahead",
+				len(temperatureOrder), ovenString, strings.Join(needs, "; "))})
+	}
+	return equipment, conflicts
+}
+
+//EquipmentString lists the equipment of a menu with the recipes using each,
+//then the conflicts
+func EquipmentString(equipment []MenuEquipment, conflicts []EquipmentConflict) string {
+	if len(equipment) == 0 {
+		return "No equipment found in the recipes\n"
+	}
+	stringString := "Equipment:\n"
+	for _, item := range equipment {
+		var recipes []string
+		for _, recipe := range item.Recipes {
+			if temperature, ok := item.Temperatures[recipe]; ok {
+				recipe += " at " + temperature
+			}
+			recipes = append(recipes, recipe)
+		}
+		stringString += fmt.Sprintf("\t%-16s %s\n", item.Name, strings.Join(recipes, ", "))
+	}
+	for _, conflict := range conflicts {
+		stringString += "Conflict: " + conflict.String() + "\n"
+	}
+	return stringString
+}
+
+//cardWidth is the width of a printed menu card
+const cardWidth = 48
+
+//centre pads s with spaces to centre it in width
+func centre(s string, width int) string {
+	padding := (width - len([]rune(s))) / 2
+	if padding <= 0 {
+		return s
+	}
+	return strings.Repeat(" ", padding) + s
+}
+
+//Card lays the menu out as a card to print for the table, with each
+//course and the names and descriptions of its dishes
+func (m Menu) Card() string {
+	rule := strings.Repeat("~", cardWidth) + "\n"
+	stringString := rule + "\n" + centre(strings.ToUpper(m.Name), cardWidth) + "\n"
+	if !m.Date.IsZero() {
+		stringString += centre(m.Date.Format("Monday, January 2, 2006"), cardWidth) + "\n"
+	}
+	for _, course := range m.courses() {
+		stringString += "\n" + centre("- "+strings.Title(course)+" -", cardWidth) + "\n"
+		for _, dish := range m.Dishes {
+			if dish.Course != course {
+				continue
+			}
+			stringString += centre(dish.Recipe.Name, cardWidth) + "\n"
+			for _, line := range wrapWords(dish.Recipe.Description, cardWidth-8) {
+				stringString += centre(line, cardWidth) + "\n"
+			}
+		}
+	}
+	return stringString + "\n" + rule
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const menuUsage = `usage: cookbook menu <command> [arguments]
+
+commands:
+	list                       list every menu
+	new [-date <date>] [-guests <guests>] [-notes <notes>] <name>
+		create a menu, ie: new -date 2026-12-25 -guests 12 "Christmas dinner"
+	set [-date <date>] [-guests <guests>] [-notes <notes>] <menu>
+		change the date, guests or notes of a menu
+	delete <menu>              delete a menu and its dishes
+	add [-course <course>] [-per <servings>] [-notes <notes>] <menu> <recipe name>
+		serve a recipe as a course of a menu, with servings per guest,
+		default 1
+	remove <dish id>           remove a dish from its menu
+	show <menu>                list the dishes of a menu by course, scaled to
+	                           the guests
+	shop [flags] <menu>        combined shopping list for every dish, minus
+	                           inventory on hand, run with -h for flags
+	equipment <menu>           equipment needed by every dish, with conflicts
+	                           such as one oven at two temperatures
+	card [-o <file>] <menu>    print a menu card for the table
+
+<menu> is the name or id of a menu. Courses are appetizer, soup, salad, main,
+side, dessert, drink or any other name, default main. Each recipe is scaled
+from the servings it makes to the guests times its servings per guest.
+`
+
+//menuCommand runs the menu subcommand given on the command line
+func menuCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(menuUsage)
+		return errors.New("no menu command given")
+	}
+	switch args[0] {
+	case "list":
+		menus, err := selectMenus(db, "ORDER BY IFNULL(date, ''), name")
+		if err != nil {
+			return err
+		}
+		if len(menus) == 0 {
+			fmt.Println("No menus")
+		}
+		for _, menu := range menus {
+			fmt.Print(menu)
+		}
+	case "new", "set":
+		flags := flag.NewFlagSet("menu "+args[0], flag.ContinueOnError)
+		date := flags.String("date", "", "Day the menu is served as YYYY-MM-DD or a day of the week")
+		guests := flags.Int("guests", 0, "Number of guests to scale every dish to")
+		notes := flags.String("notes", "", "Notes about the menu")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			fmt.Print(menuUsage)
+			return fmt.Errorf("menu %s needs the name of a menu", args[0])
+		}
+		menu := backend.Menu{Name: strings.TrimSpace(flags.Arg(0))}
+		var err error
+		if args[0] == "set" {
+			if menu, err = findMenu(db, menu.Name); err != nil {
+				return err
+			}
+		} else {
+			existing, err := selectMenus(db, "WHERE name = ? COLLATE NOCASE", menu.Name)
+			if err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				return fmt.Errorf("there is already a menu called %s", menu.Name)
+			}
+		}
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "date":
+				if err == nil {
+					menu.Date, err = parsePlanDate(*date, today())
+				}
+			case "guests":
+				if *guests < 0 && err == nil {
+					err = fmt.Errorf("invalid guests %d", *guests)
+				}
+				menu.Guests = *guests
+			case "notes":
+				menu.Notes = *notes
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if err = saveMenu(db, &menu); err != nil {
+			return err
+		}
+		fmt.Print(menu)
+	case "delete":
+		if len(args) != 2 {
+			return errors.New("usage: cookbook menu delete <menu>")
+		}
+		menu, err := findMenu(db, args[1])
+		if err != nil {
+			return err
+		}
+		if err = deleteMenu(db, menu.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %s\n", menu.Name)
+	case "add":
+		flags := flag.NewFlagSet("menu add", flag.ContinueOnError)
+		course := flags.String("course", backend.CourseMain, "Course the dish is served in, ie: appetizer, main or dessert")
+		perGuest := flags.String("per", "", "Servings for each guest, default 1")
+		notes := flags.String("notes", "", "Notes about the dish")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 2 {
+			fmt.Print(menuUsage)
+			return errors.New("menu add needs a menu and a recipe name")
+		}
+		menu, err := findMenu(db, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		dish := backend.MenuDish{Course: backend.ParseCourse(*course), Notes: *notes}
+		if *perGuest != "" {
+			if dish.PerGuest, err = backend.ParseFraction(*perGuest); err != nil {
+				return fmt.Errorf("invalid servings per guest %q: %s", *perGuest, err)
+			}
+		}
+		if dish.Recipe, err = chooseRecipe(db, flags.Arg(1), bufio.NewReader(os.Stdin)); err != nil {
+			return err
+		}
+		if err = insertDish(db, menu.ID, &dish); err != nil {
+			return err
+		}
+		menu.Dishes = append(menu.Dishes, dish)
+		menu.Sort()
+		fmt.Print(menu)
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: cookbook menu remove <dish id>")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid dish id %q", args[1])
+		}
+		menus, err := selectMenus(db, "WHERE id = (SELECT menuID FROM menuDishes WHERE id = ?)", id)
+		if err != nil {
+			return err
+		}
+		if len(menus) == 0 {
+			return fmt.Errorf("no dish with id %d", id)
+		}
+		menu := menus[0]
+		if _, err = db.Exec("DELETE FROM menuDishes WHERE id = ?", id); err != nil {
+			return err
+		}
+		for i, dish := range menu.Dishes {
+			if dish.ID == id {
+				fmt.Printf("Removed %s from the %s of %s\n", dish.Recipe.Name, dish.Course, menu.Name)
+				menu.Dishes = append(menu.Dishes[:i], menu.Dishes[i+1:]...)
+				break
+			}
+		}
+		fmt.Print(menu)
+	case "show":
+		if len(args) != 2 {
+			return errors.New("usage: cookbook menu show <menu>")
+		}
+		menu, err := findMenu(db, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Print(menu)
+	case "shop":
+		flags := flag.NewFlagSet("menu shop", flag.ContinueOnError)
+		format := flags.String("format", "text", "Output format: text, md, json or csv")
+		output := flags.String("o", "", "File to write shopping list to, default stdout")
+		ignoreInventory := flags.Bool("all", false, "Do not subtract inventory on hand")
+		noSubstitutes := flags.Bool("nosubs", false, "Do not use substitutions for ingredients that are short")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: cookbook menu shop [flags] <menu>")
+		}
+		menu, err := findMenu(db, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		list, err := menu.ShoppingList()
+		if err != nil {
+			return err
+		}
+		if !*ignoreInventory {
+			if err = subtractInventory(db, &list, !*noSubstitutes); err != nil {
+				return err
+			}
+		}
+		exported, err := list.Export(*format)
+		if err != nil {
+			return err
+		}
+		if *output != "" {
+			return ioutil.WriteFile(*output, []byte(exported), 0644)
+		}
+		fmt.Print(exported)
+	case "equipment":
+		if len(args) != 2 {
+			return errors.New("usage: cookbook menu equipment <menu>")
+		}
+		menu, err := findMenu(db, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Print(backend.EquipmentString(menu.Equipment(config.Ovens)))
+	case "card":
+		flags := flag.NewFlagSet("menu card", flag.ContinueOnError)
+		output := flags.String("o", "", "File to write menu card to, default stdout")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: cookbook menu card [-o <file>] <menu>")
+		}
+		menu, err := findMenu(db, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		if *output != "" {
+			return ioutil.WriteFile(*output, []byte(menu.Card()), 0644)
+		}
+		fmt.Print(menu.Card())
+	default:
+		fmt.Print(menuUsage)
+		return fmt.Errorf("unknown menu command %s", args[0])
+	}
+	return nil
+}
+
+//selectMenus returns the menus matching the where clause, with their
+//dishes in the order they are served
+func selectMenus(db *sql.DB, where string, args ...interface{}) ([]backend.Menu, error) {
+	rows, err := db.Query("SELECT id, name, IFNULL(date, ''), IFNULL(guests, 0), IFNULL(notes, '') "+
+		"FROM menus "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	var menus []backend.Menu
+	for rows.Next() {
+		var menu backend.Menu
+		var date string
+		if err = rows.Scan(&menu.ID, &menu.Name, &date, &menu.Guests, &menu.Notes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if date != "" {
+			if menu.Date, err = parseDate(date); err != nil {
+				rows.Close()
+				return nil, err
+			}
+		}
+		menus = append(menus, menu)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range menus {
+		if err = selectDishes(db, &menus[i]); err != nil {
+			return nil, err
+		}
+	}
+	return menus, nil
+}
+
+//selectDishes reads the dishes of menu with their recipes
+func selectDishes(db *sql.DB, menu *backend.Menu) error {
+	rows, err := db.Query("SELECT id, IFNULL(course, ''), recipeID, IFNULL(perGuest, 0), IFNULL(notes, '') "+
+		"FROM menuDishes WHERE menuID = ? ORDER BY id", menu.ID)
+	if err != nil {
+		return err
+	}
+	var recipeIDs []int
+	menu.Dishes = nil
+	for rows.Next() {
+		var dish backend.MenuDish
+		var recipeID int
+		if err = rows.Scan(&dish.ID, &dish.Course, &recipeID, &dish.PerGuest, &dish.Notes); err != nil {
+			rows.Close()
+			return err
+		}
+		menu.Dishes = append(menu.Dishes, dish)
+		recipeIDs = append(recipeIDs, recipeID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for i, recipeID := range recipeIDs {
+		found, err := selectRecipesWhere(db, "WHERE recipes.id = ?", recipeID)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("dish %d of %s is a recipe that does not exist", menu.Dishes[i].ID, menu.Name)
+		}
+		menu.Dishes[i].Recipe = found[0]
+	}
+	menu.Sort()
+	return nil
+}
+
+//findMenu returns the menu with an id of ref when ref is a number, or else
+//the menu named ref. A number that is not an id is tried as a name.
+func findMenu(db *sql.DB, ref string) (backend.Menu, error) {
+	ref = strings.TrimSpace(ref)
+	var menus []backend.Menu
+	var err error
+	if id, convErr := strconv.Atoi(ref); convErr == nil {
+		if menus, err = selectMenus(db, "WHERE id = ?", id); err != nil {
+			return backend.Menu{}, err
+		}
+	}
+	if len(menus) == 0 {
+		if menus, err = selectMenus(db, "WHERE name = ? COLLATE NOCASE", ref); err != nil {
+			return backend.Menu{}, err
+		}
+	}
+	if len(menus) == 0 {
+		return backend.Menu{}, fmt.Errorf("no menu called %s", ref)
+	}
+	return menus[0], nil
+}
+
+//saveMenu stores a new menu, or the date, guests and notes of an existing
+//one, and sets the id of a new menu
+func saveMenu(db *sql.DB, menu *backend.Menu) error {
+	if menu.ID != 0 {
+		_, err := db.Exec("UPDATE menus SET date = ?, guests = ?, notes = ? WHERE id = ?",
+			nullDate(menu.Date), menu.Guests, menu.Notes, menu.ID)
+		return err
+	}
+	result, err := db.Exec("INSERT INTO menus (name, date, guests, notes) VALUES (?, ?, ?, ?)",
+		menu.Name, nullDate(menu.Date), menu.Guests, menu.Notes)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	menu.ID = int(id)
+	return nil
+}
+
+//insertDish adds a dish to a menu and sets its id
+func insertDish(db *sql.DB, menuID int, dish *backend.MenuDish) error {
+	result, err := db.Exec("INSERT INTO menuDishes (menuID, course, recipeID, perGuest, notes) "+
+		"VALUES (?, ?, ?, ?, ?)", menuID, dish.Course, dish.Recipe.ID, dish.PerGuest, dish.Notes)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	dish.ID = int(id)
+	return nil
+}
+
+//deleteMenu removes a menu and its dishes
+func deleteMenu(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, query := range []string{"DELETE FROM menuDishes WHERE menuID = ?", "DELETE FROM menus WHERE id = ?"} {
+		if _, err = tx.Exec(query, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
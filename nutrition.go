@@ -0,0 +1,440 @@
+package recipeDatabase
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Nutrients holds the amounts of the nutrients tracked for a food or recipe.
+//Calories are kcal, macros grams, minerals and vitamin C milligrams, and
+//vitamins A and D micrograms.
+type Nutrients struct {
+	Calories     float64 `json:"calories"`
+	Protein      float64 `json:"protein"`
+	Fat          float64 `json:"fat"`
+	SaturatedFat float64 `json:"saturatedFat"`
+	Carbohydrate float64 `json:"carbohydrate"`
+	Fiber        float64 `json:"fiber"`
+	Sugar        float64 `json:"sugar"`
+	Cholesterol  float64 `json:"cholesterol"`
+	Sodium       float64 `json:"sodium"`
+	Potassium    float64 `json:"potassium"`
+	Calcium      float64 `json:"calcium"`
+	Iron         float64 `json:"iron"`
+	VitaminA     float64 `json:"vitaminA"`
+	VitaminC     float64 `json:"vitaminC"`
+	VitaminD     float64 `json:"vitaminD"`
+}
+
+//nutrientField describes one field of Nutrients for display and import
+type nutrientField struct {
+	name  string
+	unit  string
+	value func(*Nutrients) *float64
+	fdcID []int // FoodData Central nutrient ids, in order of preference
+}
+
+//nutrientFields lists every field of Nutrients in the order they are shown
+var nutrientFields = []nutrientField{
+	{"Calories", "kcal", func(n *Nutrients) *float64 { return &n.Calories }, []int{1008, 2047, 2048}},
+	{"Protein", "g", func(n *Nutrients) *float64 { return &n.Protein }, []int{1003}},
+	{"Fat", "g", func(n *Nutrients) *float64 { return &n.Fat }, []int{1004}},
+	{"Saturated fat", "g", func(n *Nutrients) *float64 { return &n.SaturatedFat }, []int{1258}},
+	{"Carbohydrate", "g", func(n *Nutrients) *float64 { return &n.Carbohydrate }, []int{1005, 1050}},
+	{"Fiber", "g", func(n *Nutrients) *float64 { return &n.Fiber }, []int{1079}},
+	{"Sugar", "g", func(n *Nutrients) *float64 { return &n.Sugar }, []int{2000, 1063}},
+	{"Cholesterol", "mg", func(n *Nutrients) *float64 { return &n.Cholesterol }, []int{1253}},
+	{"Sodium", "mg", func(n *Nutrients) *float64 { return &n.Sodium }, []int{1093}},
+	{"Potassium", "mg", func(n *Nutrients) *float64 { return &n.Potassium }, []int{1092}},
+	{"Calcium", "mg", func(n *Nutrients) *float64 { return &n.Calcium }, []int{1087}},
+	{"Iron", "mg", func(n *Nutrients) *float64 { return &n.Iron }, []int{1089}},
+	{"Vitamin A", "µg", func(n *Nutrients) *float64 { return &n.VitaminA }, []int{1106}},
+	{"Vitamin C", "mg", func(n *Nutrients) *float64 { return &n.VitaminC }, []int{1162}},
+	{"Vitamin D", "µg", func(n *Nutrients) *float64 { return &n.VitaminD }, []int{1114}},
+}
+
+//Values returns pointers to every field of n, in display order, so they can
+//be scanned from or stored in a database
+func (n *Nutrients) Values() []interface{} {
+	values := make([]interface{}, len(nutrientFields))
+	for i, field := range nutrientFields {
+		values[i] = field.value(n)
+	}
+	return values
+}
+
+//Add returns the sum of two sets of nutrients
+func (n Nutrients) Add(other Nutrients) Nutrients {
+	for _, field := range nutrientFields {
+		*field.value(&n) += *field.value(&other)
+	}
+	return n
+}
+
+//Scale returns the nutrients multiplied by factor
+func (n Nutrients) Scale(factor float64) Nutrients {
+	for _, field := range nutrientFields {
+		*field.value(&n) *= factor
+	}
+	return n
+}
+
+func (n Nutrients) String() string {
+	stringString := ""
+	for _, field := range nutrientFields {
+		value := *field.value(&n)
+		format := "\t%-14s %.1f %s\n"
+		if value >= 10 {
+			format = "\t%-14s %.0f %s\n"
+		}
+		stringString += fmt.Sprintf(format, field.name+":", value, field.unit)
+	}
+	return stringString
+}
+
+//A FoodPortion is the weight of a household measure of a food, such as
+//1 large egg weighing 50 g
+type FoodPortion struct {
+	Amount float64 // amount of unit
+	Unit   string  // unit of measure, or a size such as large for a count
+	Grams  float64 // weight of the portion
+}
+
+//A Food is an entry of the FoodData Central dataset
+type Food struct {
+	FDCID       int           // FoodData Central id
+	Description string        // name of food, ie: Butter, salted
+	DataType    string        // dataset of food, ie: sr_legacy_food
+	Per100g     Nutrients     // nutrients in 100 grams
+	Portions    []FoodPortion // weights of household measures
+}
+
+//Grams returns the weight of ingredient in grams. Standard mass and volume
+//units are converted directly, or through the density of the ingredient,
+//and anything else through the portions of the food.
+func (f Food) Grams(ingredient Ingredient) (float64, error) {
+	if grams, err := ingredient.Grams(); err == nil {
+		return grams.Float64(), nil
+	}
+	unit := ingredient.QuantityNeeded.Unit
+	for _, portion := range f.Portions {
+		if portion.Amount <= 0 {
+			continue
+		}
+		converted, err := ingredient.convertQuantity(portion.Unit)
+		if err == nil {
+			return converted.Float64() / portion.Amount * portion.Grams, nil
+		}
+	}
+	// a plain count uses the first portion that is a size rather than a
+	// measure, such as 1 large egg
+	if _, standard := lookupUnit(unit); !standard {
+		for _, portion := range f.Portions {
+			if _, measure := lookupUnit(portion.Unit); !measure && portion.Amount > 0 {
+				return ingredient.QuantityNeeded.Amount.Float64() / portion.Amount * portion.Grams, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("cannot convert %s of %s to grams", ingredient.QuantityNeeded.Format(), ingredient.Name)
+}
+
+//An IngredientNutrition is the nutrition of one ingredient of a recipe
+type IngredientNutrition struct {
+	Name      string    `json:"name"`
+	Food      string    `json:"food,omitempty"` // description of food used, empty if unmapped
+	Grams     float64   `json:"grams"`
+	Nutrients Nutrients `json:"nutrients"`
+}
+
+//RecipeNutrition is the nutrition of a whole recipe and of one serving
+type RecipeNutrition struct {
+	Total       Nutrients             `json:"total"`
+	PerServing  Nutrients             `json:"perServing"`
+	Servings    float64               `json:"servings"` // zero if the recipe is not counted in servings
+	Ingredients []IngredientNutrition `json:"ingredients"`
+	Unmapped    []string              `json:"unmapped,omitempty"`    // ingredients with no food
+	Unconverted []string              `json:"unconverted,omitempty"` // ingredients that could not be weighed
+}
+
+//CalculateNutrition adds up the nutrition of every ingredient of recipe.
+//foods maps canonical ingredient names to their food. Ingredients with no
+//food, or that cannot be weighed, are left out and listed so the total can
+//be flagged as incomplete.
+func CalculateNutrition(recipe Recipe, foods map[string]Food) RecipeNutrition {
+	var nutrition RecipeNutrition
+	for _, ingredient := range recipe.Ingredients {
+		item := IngredientNutrition{Name: ingredient.Name}
+		food, ok := foods[CanonicalName(ingredient.Name)]
+		if !ok {
+			nutrition.Unmapped = append(nutrition.Unmapped, ingredient.Name)
+			nutrition.Ingredients = append(nutrition.Ingredients, item)
+			continue
+		}
+		item.Food = food.Description
+		grams, err := food.Grams(ingredient)
+		if err != nil {
+			nutrition.Unconverted = append(nutrition.Unconverted, ingredient.Name)
+			nutrition.Ingredients = append(nutrition.Ingredients, item)
+			continue
+		}
+		item.Grams = grams
+		item.Nutrients = food.Per100g.Scale(grams / 100)
+		nutrition.Total = nutrition.Total.Add(item.Nutrients)
+		nutrition.Ingredients = append(nutrition.Ingredients, item)
+	}
+	nutrition.PerServing = nutrition.Total
+	if servings := recipe.Servings(); servings.Sign() > 0 {
+		nutrition.Servings = servings.Float64()
+		nutrition.PerServing = nutrition.Total.Scale(1 / nutrition.Servings)
+	}
+	return nutrition
+}
+
+//Scale returns the nutrition of the recipe scaled by factor. The nutrition
+//per serving does not change, the recipe makes more servings.
+func (r RecipeNutrition) Scale(factor float64) RecipeNutrition {
+	scaled := r
+	scaled.Total = r.Total.Scale(factor)
+	scaled.Servings = r.Servings * factor
+	if scaled.Servings == 0 {
+		scaled.PerServing = scaled.Total
+	}
+	scaled.Ingredients = make([]IngredientNutrition, len(r.Ingredients))
+	for i, ingredient := range r.Ingredients {
+		ingredient.Grams *= factor
+		ingredient.Nutrients = ingredient.Nutrients.Scale(factor)
+		scaled.Ingredients[i] = ingredient
+	}
+	return scaled
+}
+
+//Complete reports whether every ingredient was counted
+func (r RecipeNutrition) Complete() bool {
+	return len(r.Unmapped) == 0 && len(r.Unconverted) == 0
+}
+
+//Warning describes the ingredients left out of the nutrition, empty if
+//there are none
+func (r RecipeNutrition) Warning() string {
+	stringString := ""
+	if len(r.Unmapped) > 0 {
+		stringString += fmt.Sprintf("Warning: no nutrition data for %s\n", strings.Join(r.Unmapped, ", "))
+	}
+	if len(r.Unconverted) > 0 {
+		stringString += fmt.Sprintf("Warning: could not weigh %s\n", strings.Join(r.Unconverted, ", "))
+	}
+	return stringString
+}
+
+func (r RecipeNutrition) String() string {
+	stringString := ""
+	if r.Servings > 0 {
+		stringString += fmt.Sprintf("Nutrition per serving, of %G: \n", r.Servings)
+		stringString += r.PerServing.String()
+	}
+	stringString += "Nutrition for whole recipe: \n"
+	stringString += r.Total.String()
+	return stringString + r.Warning()
+}
+
+//FDC data types imported by default, the generic foods of SR Legacy and
+//Foundation Foods rather than branded products
+var DefaultFDCDataTypes = []string{"sr_legacy_food", "foundation_food"}
+
+//FDCFiles are the CSV files of a FoodData Central download used by ReadFDC.
+//Portions and MeasureUnits are optional.
+type FDCFiles struct {
+	Foods         io.Reader // food.csv
+	FoodNutrients io.Reader // food_nutrient.csv
+	Portions      io.Reader // food_portion.csv
+	MeasureUnits  io.Reader // measure_unit.csv
+}
+
+//ReadFDC reads the foods of the given data types from a FoodData Central
+//CSV download, calling add for each food with its nutrients and portions.
+//Foods of other data types are skipped, and returned as the count skipped.
+func ReadFDC(files FDCFiles, dataTypes []string, add func(Food) error) (int, error) {
+	if files.Foods == nil || files.FoodNutrients == nil {
+		return 0, errors.New("food.csv and food_nutrient.csv are needed")
+	}
+	wanted := make(map[string]bool)
+	for _, dataType := range dataTypes {
+		wanted[dataType] = true
+	}
+
+	foods := make(map[int]*Food)
+	skipped := 0
+	err := readCSV(files.Foods, []string{"fdc_id", "data_type", "description"}, nil, func(row []string) error {
+		id, err := strconv.Atoi(row[0])
+		if err != nil {
+			return nil
+		}
+		if len(wanted) > 0 && !wanted[row[1]] {
+			skipped++
+			return nil
+		}
+		foods[id] = &Food{FDCID: id, DataType: row[1], Description: row[2]}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("food.csv: %s", err)
+	}
+
+	// rank of each nutrient id for the field it fills, so a preferred id
+	// wins over a fallback whichever order they are read in
+	type fieldRank struct{ field, rank int }
+	nutrientIDs := make(map[int]fieldRank)
+	for i, field := range nutrientFields {
+		for rank, id := range field.fdcID {
+			nutrientIDs[id] = fieldRank{i, rank}
+		}
+	}
+	filled := make(map[int]map[int]int)
+	err = readCSV(files.FoodNutrients, []string{"fdc_id", "nutrient_id", "amount"}, nil, func(row []string) error {
+		id, err := strconv.Atoi(row[0])
+		if err != nil || foods[id] == nil {
+			return nil
+		}
+		nutrientID, err := strconv.Atoi(row[1])
+		if err != nil {
+			return nil
+		}
+		target, ok := nutrientIDs[nutrientID]
+		if !ok {
+			return nil
+		}
+		amount, err := strconv.ParseFloat(row[2], 64)
+		if err != nil {
+			return nil
+		}
+		if filled[id] == nil {
+			filled[id] = make(map[int]int)
+		}
+		if rank, ok := filled[id][target.field]; ok && rank <= target.rank {
+			return nil
+		}
+		filled[id][target.field] = target.rank
+		*nutrientFields[target.field].value(&foods[id].Per100g) = amount
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("food_nutrient.csv: %s", err)
+	}
+
+	units := make(map[string]string)
+	if files.MeasureUnits != nil {
+		err = readCSV(files.MeasureUnits, []string{"id", "name"}, nil, func(row []string) error {
+			units[row[0]] = row[1]
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("measure_unit.csv: %s", err)
+		}
+	}
+	if files.Portions != nil {
+		optional := []string{"amount", "measure_unit_id", "modifier", "portion_description"}
+		err = readCSV(files.Portions, []string{"fdc_id", "gram_weight"}, optional, func(row []string) error {
+			id, err := strconv.Atoi(row[0])
+			if err != nil || foods[id] == nil {
+				return nil
+			}
+			portion, ok := fdcPortion(row[2], units[row[3]], row[4], row[1], row[5])
+			if ok {
+				foods[id].Portions = append(foods[id].Portions, portion)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("food_portion.csv: %s", err)
+		}
+	}
+
+	ids := make([]int, 0, len(foods))
+	for id := range foods {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if err = add(*foods[id]); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
+
+//fdcPortion reads one row of food_portion.csv. SR Legacy foods leave the
+//measure unit undetermined and put it in the modifier, such as "cup,
+//chopped" or "large", while Foundation foods use a measure unit.
+func fdcPortion(amount string, unit string, modifier string, grams string, description string) (FoodPortion, bool) {
+	var portion FoodPortion
+	var err error
+	if portion.Grams, err = strconv.ParseFloat(grams, 64); err != nil || portion.Grams <= 0 {
+		return portion, false
+	}
+	if portion.Amount, err = strconv.ParseFloat(amount, 64); err != nil || portion.Amount <= 0 {
+		portion.Amount = 1
+	}
+	if unit == "" || unit == "undetermined" {
+		unit = modifier
+		if unit == "" {
+			unit = description
+		}
+	}
+	// keep the measure, dropping details like "chopped" or "(8 fl oz)"
+	if cut := strings.IndexAny(unit, ",("); cut >= 0 {
+		unit = unit[:cut]
+	}
+	portion.Unit = strings.ToLower(strings.TrimSpace(unit))
+	return portion, true
+}
+
+//readCSV reads a CSV file with a header row, calling row for each line with
+//the values of the required columns followed by the optional columns, in
+//the order given. Optional columns missing from the file are empty.
+func readCSV(reader io.Reader, required []string, optional []string, row func([]string) error) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+	header, err := csvReader.Read()
+	if err != nil {
+		return err
+	}
+	columns := append(append([]string{}, required...), optional...)
+	index := make([]int, len(columns))
+	for i, column := range columns {
+		index[i] = -1
+		for j, name := range header {
+			// a byte order mark may start the first column name
+			if strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")) == column {
+				index[i] = j
+			}
+		}
+		if index[i] < 0 && i < len(required) {
+			return fmt.Errorf("missing column %s", column)
+		}
+	}
+	values := make([]string, len(columns))
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for i, j := range index {
+			values[i] = ""
+			if j >= 0 && j < len(record) {
+				values[i] = strings.TrimSpace(record[j])
+			}
+		}
+		if err = row(values); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const nutritionUsage = `usage: cookbook nutrition <command> [arguments]
+
+commands:
+	import [-types <data types>] <directory>
+		import a FoodData Central CSV download, default the SR Legacy and
+		Foundation foods. The directory holds food.csv, food_nutrient.csv and
+		optionally food_portion.csv and measure_unit.csv
+	search <term>              list foods with term in their description
+	map <ingredient> <fdc id>  use a food for every recipe ingredient named ingredient
+	unmap <ingredient>         forget the food of an ingredient
+	unmapped                   list ingredients used by recipes with no food
+	automap [-apply]           suggest foods for unmapped ingredients by name,
+	                           with -apply they are mapped
+	show [-format text|json] <recipe name>
+		show the nutrition of a recipe, per serving and in total
+`
+
+//nutrientColumns are the nutrient columns of nutritionFoods, in the same
+//order as backend.Nutrients.Values
+const nutrientColumns = "calories, protein, fat, saturatedFat, carbohydrate, fiber, sugar, " +
+	"cholesterol, sodium, potassium, calcium, iron, vitaminA, vitaminC, vitaminD"
+
+//nutritionCommand runs the nutrition subcommand given on the command line
+func nutritionCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(nutritionUsage)
+		return errors.New("no nutrition command given")
+	}
+	switch args[0] {
+	case "import":
+		flags := flag.NewFlagSet("nutrition import", flag.ContinueOnError)
+		types := flags.String("types", strings.Join(backend.DefaultFDCDataTypes, ","),
+			"Comma separated FoodData Central data types to import, empty for all")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: cookbook nutrition import [-types <data types>] <directory>")
+		}
+		imported, skipped, err := importFDC(db, flags.Arg(0), splitTags(*types))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d foods, skipped %d of other data types\n", imported, skipped)
+		return nil
+	case "search":
+		if len(args) < 2 {
+			return errors.New("usage: cookbook nutrition search <term>")
+		}
+		foods, err := selectFoods(db, "WHERE description LIKE ? ORDER BY length(description), description LIMIT 50",
+			"%"+strings.Join(args[1:], " ")+"%")
+		if err != nil {
+			return err
+		}
+		for _, food := range foods {
+			fmt.Printf("%d) %s, %.0f kcal per 100 g\n", food.FDCID, food.Description, food.Per100g.Calories)
+		}
+		return nil
+	case "map":
+		if len(args) != 3 {
+			return errors.New("usage: cookbook nutrition map <ingredient> <fdc id>")
+		}
+		fdcID, err := strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("invalid FoodData Central id %q", args[2])
+		}
+		foods, err := selectFoods(db, "WHERE fdcID = ?", fdcID)
+		if err != nil {
+			return err
+		}
+		if len(foods) == 0 {
+			return fmt.Errorf("no food with id %d, import the FoodData Central download first", fdcID)
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		entry, err := mapFoodTx(tx, args[1], fdcID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", entry.Name, foods[0].Description)
+		return nil
+	case "unmap":
+		if len(args) != 2 {
+			return errors.New("usage: cookbook nutrition unmap <ingredient>")
+		}
+		catalog, err := selectCatalog(db)
+		if err != nil {
+			return err
+		}
+		entry, ok := backend.FindCatalogIngredient(args[1], catalog)
+		if !ok {
+			return fmt.Errorf("%s is not in the catalog", args[1])
+		}
+		_, err = db.Exec("DELETE FROM ingredientFoods WHERE catalogID = ?", entry.ID)
+		return err
+	case "unmapped":
+		names, err := unmappedIngredients(db)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	case "automap":
+		flags := flag.NewFlagSet("nutrition automap", flag.ContinueOnError)
+		apply := flags.Bool("apply", false, "Map the foods suggested")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		return autoMapFoods(db, *apply)
+	case "show":
+		flags := flag.NewFlagSet("nutrition show", flag.ContinueOnError)
+		format := flags.String("format", "text", "Output format: text or json")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: cookbook nutrition show [-format text|json] <recipe name>")
+		}
+		recipe, err := chooseRecipe(db, flags.Arg(0), bufio.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		nutrition, err := recipeNutrition(db, recipe)
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			bytes, err := json.MarshalIndent(nutrition, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(bytes))
+		case "text":
+			fmt.Printf("%s \n\n", recipe.Name)
+			for _, ingredient := range nutrition.Ingredients {
+				if ingredient.Food != "" && ingredient.Grams > 0 {
+					fmt.Printf("\t%s: %.0f g of %s, %.0f kcal\n", ingredient.Name, ingredient.Grams,
+						ingredient.Food, ingredient.Nutrients.Calories)
+				}
+			}
+			fmt.Print(nutrition.String())
+		default:
+			return fmt.Errorf("unknown format %s, expected text or json", *format)
+		}
+		return nil
+	}
+	fmt.Print(nutritionUsage)
+	return errors.New("unknown nutrition command " + args[0])
+}
+
+//importFDC reads a FoodData Central CSV download from dir into the
+//nutritionFoods and foodPortions tables in a single transaction, replacing
+//foods already imported
+func importFDC(db *sql.DB, dir string, dataTypes []string) (int, int, error) {
+	var files backend.FDCFiles
+	readers := []struct {
+		name     string
+		required bool
+		reader   *io.Reader
+	}{
+		{"food.csv", true, &files.Foods},
+		{"food_nutrient.csv", true, &files.FoodNutrients},
+		{"food_portion.csv", false, &files.Portions},
+		{"measure_unit.csv", false, &files.MeasureUnits},
+	}
+	for _, csvFile := range readers {
+		file, err := os.Open(filepath.Join(dir, csvFile.name))
+		if err != nil {
+			if !csvFile.required && os.IsNotExist(err) {
+				continue
+			}
+			return 0, 0, err
+		}
+		defer file.Close()
+		*csvFile.reader = bufio.NewReader(file)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	foodStatement, err := tx.Prepare("INSERT OR REPLACE INTO nutritionFoods (fdcID, description, dataType, " +
+		nutrientColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
+	defer foodStatement.Close()
+	imported := 0
+	skipped, err := backend.ReadFDC(files, dataTypes, func(food backend.Food) error {
+		values := append([]interface{}{food.FDCID, food.Description, food.DataType}, food.Per100g.Values()...)
+		if _, err := foodStatement.Exec(values...); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM foodPortions WHERE fdcID = ?", food.FDCID); err != nil {
+			return err
+		}
+		for _, portion := range food.Portions {
+			_, err := tx.Exec("INSERT INTO foodPortions (fdcID, amount, unit, grams) VALUES (?, ?, ?, ?)",
+				food.FDCID, portion.Amount, portion.Unit, portion.Grams)
+			if err != nil {
+				return err
+			}
+		}
+		imported++
+		if imported%1000 == 0 {
+			infoLogger.Printf("Imported %d foods", imported)
+		}
+		return nil
+	})
+	if err != nil {
+		tx.Rollback()
+		return imported, skipped, err
+	}
+	return imported, skipped, tx.Commit()
+}
+
+//selectFoods returns the foods matching a where clause, with their portions
+func selectFoods(q queryer, where string, args ...interface{}) ([]backend.Food, error) {
+	rows, err := q.Query("SELECT fdcID, description, IFNULL(dataType, ''), "+nutrientColumns+
+		" FROM nutritionFoods "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	var foods []backend.Food
+	index := make(map[int]int)
+	for rows.Next() {
+		var food backend.Food
+		values := append([]interface{}{&food.FDCID, &food.Description, &food.DataType}, food.Per100g.Values()...)
+		if err = rows.Scan(values...); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[food.FDCID] = len(foods)
+		foods = append(foods, food)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range foods {
+		rows, err := q.Query("SELECT amount, IFNULL(unit, ''), grams FROM foodPortions WHERE fdcID = ? "+
+			"ORDER BY id", foods[i].FDCID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var portion backend.FoodPortion
+			if err = rows.Scan(&portion.Amount, &portion.Unit, &portion.Grams); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			foods[i].Portions = append(foods[i].Portions, portion)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return foods, nil
+}
+
+//selectFoodsByIngredient maps the canonical name of every catalog
+//ingredient with a food to that food
+func selectFoodsByIngredient(db *sql.DB) (map[string]backend.Food, error) {
+	rows, err := db.Query("SELECT ingredientCatalog.name, ingredientFoods.fdcID FROM ingredientFoods " +
+		"INNER JOIN ingredientCatalog ON ingredientFoods.catalogID = ingredientCatalog.id")
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int][]string)
+	for rows.Next() {
+		var name string
+		var fdcID int
+		if err = rows.Scan(&name, &fdcID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names[fdcID] = append(names[fdcID], name)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	foods := make(map[string]backend.Food)
+	if len(names) == 0 {
+		return foods, nil
+	}
+	found, err := selectFoods(db, "WHERE fdcID IN (SELECT fdcID FROM ingredientFoods)")
+	if err != nil {
+		return nil, err
+	}
+	for _, food := range found {
+		for _, name := range names[food.FDCID] {
+			foods[name] = food
+		}
+	}
+	return foods, nil
+}
+
+//recipeNutrition works out the nutrition of a recipe from the foods mapped
+//to its ingredients
+func recipeNutrition(db *sql.DB, recipe backend.Recipe) (backend.RecipeNutrition, error) {
+	foods, err := selectFoodsByIngredient(db)
+	if err != nil {
+		return backend.RecipeNutrition{}, err
+	}
+	return backend.CalculateNutrition(recipe, foods), nil
+}
+
+//addNutrition fills in the nutrition of a recipe for display, once any
+//ingredient has been mapped to a food
+func addNutrition(db *sql.DB, recipe *backend.Recipe) error {
+	foods, err := selectFoodsByIngredient(db)
+	if err != nil || len(foods) == 0 {
+		return err
+	}
+	nutrition := backend.CalculateNutrition(*recipe, foods)
+	recipe.Nutrition = &nutrition
+	return nil
+}
+
+//mapFoodTx uses the food fdcID for the catalog ingredient name, adding it to
+//the catalog if needed
+func mapFoodTx(tx *sql.Tx, name string, fdcID int) (backend.CatalogIngredient, error) {
+	catalog, err := newCatalogCache(tx)
+	if err != nil {
+		return backend.CatalogIngredient{}, err
+	}
+	entry, err := catalog.entry(name)
+	if err != nil {
+		return entry, err
+	}
+	_, err = tx.Exec("INSERT OR REPLACE INTO ingredientFoods (catalogID, fdcID) VALUES (?, ?)", entry.ID, fdcID)
+	return entry, err
+}
+
+//unmappedIngredients lists the catalog ingredients used by a recipe that
+//have no food
+func unmappedIngredients(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT ingredientCatalog.name FROM ingredientCatalog " +
+		"INNER JOIN ingredients ON ingredients.catalogID = ingredientCatalog.id " +
+		"LEFT JOIN ingredientFoods ON ingredientFoods.catalogID = ingredientCatalog.id " +
+		"WHERE ingredientFoods.fdcID IS NULL ORDER BY ingredientCatalog.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+//autoMapFoods suggests a food for every unmapped ingredient: the shortest
+//description whose first part, before a comma, is the ingredient name or
+//its plural, such as "Butter, salted" for butter. With apply the
+//suggestions are mapped.
+func autoMapFoods(db *sql.DB, apply bool) error {
+	names, err := unmappedIngredients(db)
+	if err != nil {
+		return err
+	}
+	type suggestion struct {
+		name string
+		food backend.Food
+	}
+	var suggestions []suggestion
+	for _, name := range names {
+		plural := backend.CatalogIngredient{Name: name}.PluralName()
+		foods, err := selectFoods(db, "WHERE lower(description) = ? OR lower(description) = ? "+
+			"OR lower(description) LIKE ? OR lower(description) LIKE ?", name, plural, name+",%", plural+",%")
+		if err != nil {
+			return err
+		}
+		if len(foods) == 0 {
+			fmt.Printf("%s: no food found\n", name)
+			continue
+		}
+		// raw foods first, then the least qualified description
+		sort.SliceStable(foods, func(a, b int) bool {
+			rawA := strings.Contains(strings.ToLower(foods[a].Description), "raw")
+			rawB := strings.Contains(strings.ToLower(foods[b].Description), "raw")
+			if rawA != rawB {
+				return rawA
+			}
+			return len(foods[a].Description) < len(foods[b].Description)
+		})
+		suggestions = append(suggestions, suggestion{name, foods[0]})
+		fmt.Printf("%s: %d) %s\n", name, foods[0].FDCID, foods[0].Description)
+	}
+	if !apply {
+		if len(suggestions) > 0 {
+			fmt.Println("Nothing mapped, run with -apply to map these, or map ingredients one by one")
+		}
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, suggested := range suggestions {
+		if _, err = mapFoodTx(tx, suggested.name, suggested.food.FDCID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,198 @@
+package recipeDatabase
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+//A Product is an entry in an offline product catalog, used to fill in new
+//inventory items from their barcode
+type Product struct {
+	GTIN            string   // normalized barcode
+	Name            string   // product name
+	Description     string   // generic name and brand
+	PackageQuantity Quantity // amount in one package, zero if unknown
+}
+
+//offProduct holds the Open Food Facts fields used by the catalog. The CSV
+//export uses the same names for its header row.
+type offProduct struct {
+	Code        string `json:"code"`
+	ProductName string `json:"product_name"`
+	GenericName string `json:"generic_name"`
+	Brands      string `json:"brands"`
+	Quantity    string `json:"quantity"`
+}
+
+//product converts an Open Food Facts entry to a Product, returning false
+//if it has no valid barcode or no name
+func (o offProduct) product() (Product, bool) {
+	gtin, err := NormalizeGTIN(strings.TrimSpace(o.Code))
+	if err != nil {
+		return Product{}, false
+	}
+	product := Product{GTIN: gtin, Name: strings.TrimSpace(o.ProductName)}
+	if product.Name == "" {
+		return Product{}, false
+	}
+	product.Description = strings.TrimSpace(o.GenericName)
+	if brands := strings.TrimSpace(o.Brands); brands != "" {
+		if product.Description != "" {
+			product.Description += ", "
+		}
+		product.Description += brands
+	}
+	product.PackageQuantity, _ = ParsePackageQuantity(o.Quantity)
+	return product, true
+}
+
+//multipackRegexp matches package sizes like "6 x 330 ml"
+var multipackRegexp = regexp.MustCompile(`^(\d+)\s*[xX×]\s*(.+)$`)
+
+//ParsePackageQuantity reads the package size printed on a product, such as
+//"500 g", "6 x 330 ml" or "16 oz (454 g)". Only the first size is used, and
+//it must be in a standard unit, since catalogs are full of free text.
+func ParsePackageQuantity(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	if paren := strings.IndexAny(s, "(/"); paren > 0 {
+		s = strings.TrimSpace(s[:paren])
+	}
+	count := NewFraction(1, 1)
+	if match := multipackRegexp.FindStringSubmatch(s); match != nil {
+		var err error
+		count, err = ParseFraction(match[1])
+		if err != nil {
+			return Quantity{}, err
+		}
+		s = match[2]
+	}
+	// catalogs use both decimal points and commas
+	s = strings.Replace(s, ",", ".", 1)
+	quantity, err := ParseQuantity(s)
+	if err != nil {
+		return Quantity{}, err
+	}
+	quantity.Unit = strings.TrimSuffix(strings.TrimSpace(quantity.Unit), ".")
+	if _, ok := lookupUnit(quantity.Unit); !ok || quantity.IsZero() {
+		return Quantity{}, fmt.Errorf("package size %q is not in a standard unit", s)
+	}
+	return quantity.Mul(count), nil
+}
+
+//ReadProducts reads an Open Food Facts catalog dump from reader and calls
+//add for every product with a valid barcode and a name. format is csv for
+//the tab separated CSV export, or jsonl for the JSON lines export. The
+//catalog is streamed since full dumps are several gigabytes. Returns the
+//number of entries skipped.
+func ReadProducts(reader io.Reader, format string, add func(Product) error) (int, error) {
+	switch strings.ToLower(format) {
+	case "csv", "tsv":
+		return readProductsCSV(reader, add)
+	case "jsonl", "json":
+		return readProductsJSONL(reader, add)
+	}
+	return 0, fmt.Errorf("unknown product catalog format %s, expected csv or jsonl", format)
+}
+
+func readProductsCSV(reader io.Reader, add func(Product) error) (int, error) {
+	buffered := bufio.NewReader(reader)
+	header, err := buffered.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	// the Open Food Facts export is tab separated despite its name
+	csvReader := csv.NewReader(io.MultiReader(strings.NewReader(header), buffered))
+	if strings.Contains(header, "\t") {
+		csvReader.Comma = '\t'
+	}
+	csvReader.LazyQuotes = true
+	csvReader.FieldsPerRecord = -1
+	columns, err := csvReader.Read()
+	if err != nil {
+		return 0, err
+	}
+	index := make(map[string]int)
+	for i, column := range columns {
+		index[strings.TrimSpace(column)] = i
+	}
+	if _, ok := index["code"]; !ok {
+		return 0, fmt.Errorf("product catalog has no code column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := index[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	skipped := 0
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return skipped, nil
+		}
+		if err != nil {
+			return skipped, err
+		}
+		entry := offProduct{field(record, "code"), field(record, "product_name"),
+			field(record, "generic_name"), field(record, "brands"), field(record, "quantity")}
+		product, ok := entry.product()
+		if !ok {
+			skipped++
+			continue
+		}
+		if err = add(product); err != nil {
+			return skipped, err
+		}
+	}
+}
+
+func readProductsJSONL(reader io.Reader, add func(Product) error) (int, error) {
+	scanner := bufio.NewScanner(reader)
+	// single products can be far longer than the default line limit
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	skipped := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry offProduct
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			skipped++
+			continue
+		}
+		product, ok := entry.product()
+		if !ok {
+			skipped++
+			continue
+		}
+		if err := add(product); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, scanner.Err()
+}
+
+//InventoryItem returns a new, empty inventory item for the product
+func (p Product) InventoryItem() InventoryItem {
+	return InventoryItem{EAN: p.GTIN, Name: p.Name, Description: p.Description,
+		PackageQuantity: p.PackageQuantity}
+}
+
+func (p Product) String() string {
+	stringString := fmt.Sprintf("%s %s", p.GTIN, p.Name)
+	if !p.PackageQuantity.IsZero() {
+		stringString += fmt.Sprintf(" (%s)", p.PackageQuantity.Format())
+	}
+	stringString += "\n"
+	if p.Description != "" {
+		stringString += "\t" + p.Description + "\n"
+	}
+	return stringString
+}
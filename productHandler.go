@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const productUsage = `usage: cookbook products <command> [arguments]
+
+commands:
+	import [-format csv|jsonl] <file>   import an Open Food Facts catalog dump
+	lookup <barcode>                    show the catalog entry for a barcode
+	search <term>                       list catalog entries with term in their name
+	check <barcode>                     check a barcode and show its normalized form
+`
+
+//productSelect is the column list used by every query that reads products
+const productSelect = "SELECT products.GTIN, IFNULL(products.name, ''), " +
+	"IFNULL(products.description, ''), products.packageQuantity, IFNULL(units.name, '') " +
+	"FROM products LEFT JOIN units ON products.packageQuantityUnits = units.id "
+
+//productCommand runs the products subcommand given on the command line
+func productCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(productUsage)
+		return errors.New("no products command given")
+	}
+	switch args[0] {
+	case "import":
+		flags := flag.NewFlagSet("products import", flag.ContinueOnError)
+		format := flags.String("format", "", "Format of catalog, csv or jsonl. Default from file extension")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() == 0 {
+			return errors.New("usage: cookbook products import [-format csv|jsonl] <file>")
+		}
+		filename := flags.Arg(0)
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(filename), ".")
+		}
+		imported, skipped, err := importProducts(db, filename, *format)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d products, skipped %d without a valid barcode or name\n", imported, skipped)
+	case "lookup":
+		if len(args) < 2 {
+			return errors.New("usage: cookbook products lookup <barcode>")
+		}
+		product, found, err := lookupProduct(db, args[1])
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("no product with barcode %s in catalog", args[1])
+		}
+		fmt.Print(product.String())
+	case "search":
+		if len(args) < 2 {
+			return errors.New("usage: cookbook products search <term>")
+		}
+		rows, err := db.Query(productSelect+"WHERE products.name LIKE ? ORDER BY products.name LIMIT 100",
+			"%"+strings.Join(args[1:], " ")+"%")
+		if err != nil {
+			return err
+		}
+		products, err := scanProducts(rows)
+		if err != nil {
+			return err
+		}
+		for _, product := range products {
+			fmt.Print(product.String())
+		}
+	case "check":
+		if len(args) < 2 {
+			return errors.New("usage: cookbook products check <barcode>")
+		}
+		gtin, err := backend.NormalizeGTIN(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is valid, stored as %s\n", args[1], gtin)
+	default:
+		fmt.Print(productUsage)
+		return fmt.Errorf("unknown products command %s", args[0])
+	}
+	return nil
+}
+
+//importProducts reads a catalog dump into the products table in a single
+//transaction, replacing products already in the catalog
+func importProducts(db *sql.DB, filename string, format string) (int, int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	statement, err := tx.Prepare("INSERT OR REPLACE INTO products (GTIN, name, description, " +
+		"packageQuantity, packageQuantityUnits) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
+	defer statement.Close()
+
+	// catalogs only use a handful of units, so look each up once
+	units := make(map[string]sql.NullInt64)
+	imported := 0
+	skipped, err := backend.ReadProducts(file, format, func(product backend.Product) error {
+		unit, ok := units[product.PackageQuantity.Unit]
+		if !ok {
+			var err error
+			unit, err = unitID(tx, product.PackageQuantity.Unit)
+			if err != nil {
+				return err
+			}
+			units[product.PackageQuantity.Unit] = unit
+		}
+		_, err := statement.Exec(product.GTIN, product.Name, product.Description,
+			product.PackageQuantity.Amount, unit)
+		if err != nil {
+			return err
+		}
+		imported++
+		if imported%10000 == 0 {
+			infoLogger.Printf("Imported %d products", imported)
+		}
+		return nil
+	})
+	if err != nil {
+		tx.Rollback()
+		return 0, skipped, err
+	}
+	return imported, skipped, tx.Commit()
+}
+
+//scanProducts reads every row returned by a query using productSelect
+func scanProducts(rows *sql.Rows) ([]backend.Product, error) {
+	defer rows.Close()
+	var products []backend.Product
+	for rows.Next() {
+		var product backend.Product
+		err := rows.Scan(&product.GTIN, &product.Name, &product.Description,
+			&product.PackageQuantity.Amount, &product.PackageQuantity.Unit)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+//lookupProduct finds the catalog entry for a barcode. Returns an error if
+//the barcode is not valid, and false if it is not in the catalog.
+func lookupProduct(db *sql.DB, barcode string) (backend.Product, bool, error) {
+	gtin, err := backend.NormalizeGTIN(barcode)
+	if err != nil {
+		return backend.Product{}, false, err
+	}
+	rows, err := db.Query(productSelect+"WHERE products.GTIN = ?", gtin)
+	if err != nil {
+		return backend.Product{}, false, err
+	}
+	products, err := scanProducts(rows)
+	if err != nil || len(products) == 0 {
+		return backend.Product{}, false, err
+	}
+	return products[0], true, nil
+}
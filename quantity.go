@@ -0,0 +1,207 @@
+package recipeDatabase
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//Quantity is an amount of something in a particular unit, such as
+//1 ½ cups of flour or 1.5 loaves of bread. Unit is the name of a unit, which
+//can be any value, but only standard units can be converted between.
+//The amount is not called Value as that name is needed for driver.Valuer.
+type Quantity struct {
+	Amount Fraction // amount of unit
+	Unit   string   // name of unit, empty for a plain count
+}
+
+//NewQuantity returns a quantity of value in unit
+func NewQuantity(value Fraction, unit string) Quantity {
+	return Quantity{value, strings.TrimSpace(unit)}
+}
+
+//ParseQuantity reads a quantity written as a number followed by a unit,
+//such as "1 1/2 cups", "1.5 loaves", "½ tsp" or "500g". A quantity with no
+//unit is a plain count.
+func ParseQuantity(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	// the value ends at the first character that cannot be part of a number
+	// or fraction, spaces inside mixed numbers are kept with the value
+	split := len(s)
+	for i, r := range s {
+		if unicode.IsDigit(r) || strings.ContainsRune("./- ", r) {
+			continue
+		}
+		if _, vulgar := vulgarRunes[r]; vulgar {
+			continue
+		}
+		split = i
+		break
+	}
+	value, err := ParseFraction(s[:split])
+	if err != nil {
+		return Quantity{}, err
+	}
+	return NewQuantity(value, s[split:]), nil
+}
+
+//vulgarRunes is the set of unicode fraction characters understood by
+//ParseFraction
+var vulgarRunes = func() map[rune]bool {
+	runes := make(map[rune]bool)
+	for _, vulgar := range vulgarFractions {
+		for _, r := range vulgar {
+			runes[r] = true
+		}
+	}
+	return runes
+}()
+
+//String returns the quantity exactly, in a form ParseQuantity can read back,
+//such as "1 1/2 cup"
+func (q Quantity) String() string {
+	if q.Unit == "" {
+		return q.Amount.String()
+	}
+	return q.Amount.String() + " " + q.Unit
+}
+
+//Format returns the quantity as it would be written in a recipe, following
+//the preferences in Display, such as "1 ½ cups"
+func (q Quantity) Format() string {
+	return displayQuantity(q.Amount, q.Unit)
+}
+
+//IsZero reports whether the quantity has no value
+func (q Quantity) IsZero() bool {
+	return q.Amount.IsZero()
+}
+
+//Mul returns the quantity multiplied by factor, in the same unit
+func (q Quantity) Mul(factor Fraction) Quantity {
+	return Quantity{q.Amount.Mul(factor), q.Unit}
+}
+
+//Convert returns the quantity in unit, using the standard conversion table
+func (q Quantity) Convert(unit string) (Quantity, error) {
+	value, err := ConvertUnits(q.Amount, q.Unit, unit)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{value, unit}, nil
+}
+
+//Normalize returns the quantity in the largest sensible kitchen unit of the
+//same measurement system, so 48 tsp becomes 1 cup
+func (q Quantity) Normalize() Quantity {
+	value, unit := normalizeUnit(q.Amount, q.Unit)
+	return Quantity{value, unit}
+}
+
+//Family returns the unit family of the quantity, UnknownFamily if its unit
+//is not a standard unit, or Count if it has no unit
+func (q Quantity) Family() UnitFamily {
+	if q.Unit == "" {
+		return Count
+	}
+	def, ok := lookupUnit(q.Unit)
+	if !ok {
+		return UnknownFamily
+	}
+	return def.Family
+}
+
+//Add returns q + o in the unit of q. o is converted to the unit of q, and an
+//error is returned if that is not possible. A zero q takes the unit of o.
+func (q Quantity) Add(o Quantity) (Quantity, error) {
+	if q.IsZero() && q.Unit == "" {
+		return o, nil
+	}
+	converted, err := o.Convert(q.Unit)
+	if err != nil {
+		return q, err
+	}
+	return Quantity{q.Amount.Add(converted.Amount), q.Unit}, nil
+}
+
+//Sub returns q - o in the unit of q. o is converted to the unit of q, and an
+//error is returned if that is not possible.
+func (q Quantity) Sub(o Quantity) (Quantity, error) {
+	converted, err := o.Convert(q.Unit)
+	if err != nil {
+		return q, err
+	}
+	return Quantity{q.Amount.Sub(converted.Amount), q.Unit}, nil
+}
+
+//Cmp compares q and o after converting o to the unit of q, and returns -1,
+//0 or +1
+func (q Quantity) Cmp(o Quantity) (int, error) {
+	converted, err := o.Convert(q.Unit)
+	if err != nil {
+		return 0, err
+	}
+	return q.Amount.Cmp(converted.Amount), nil
+}
+
+//MarshalJSON stores a quantity as its String() form, ie: "1 1/2 cup"
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.String())
+}
+
+//UnmarshalJSON reads a quantity from a string, or a plain number as a count
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var f float64
+		if numErr := json.Unmarshal(data, &f); numErr != nil {
+			return err
+		}
+		*q = Quantity{FractionFromFloat(f), ""}
+		return nil
+	}
+	parsed, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+//Value implements driver.Valuer, storing the quantity as text with an exact
+//num/den value, ie: "3/2 cup"
+func (q Quantity) Value() (driver.Value, error) {
+	value := q.Amount.value().RatString()
+	if q.Unit == "" {
+		return value, nil
+	}
+	return value + " " + q.Unit, nil
+}
+
+//Scan implements sql.Scanner for quantities stored by Value, or plain
+//numbers which are read as a count
+func (q *Quantity) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case string:
+		parsed, err := ParseQuantity(value)
+		if err != nil {
+			return err
+		}
+		*q = parsed
+	case []byte:
+		parsed, err := ParseQuantity(string(value))
+		if err != nil {
+			return err
+		}
+		*q = parsed
+	default:
+		var f Fraction
+		if err := f.Scan(src); err != nil {
+			return fmt.Errorf("cannot scan %T into Quantity", src)
+		}
+		*q = Quantity{f, ""}
+	}
+	return nil
+}
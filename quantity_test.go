@@ -0,0 +1,84 @@
+package recipeDatabase
+
+import "testing"
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		input  string
+		amount Fraction
+		unit   string
+	}{
+		{"1 1/2 cups", NewFraction(3, 2), "cups"},
+		{"1.5 loaves", NewFraction(3, 2), "loaves"},
+		{"½ tsp", NewFraction(1, 2), "tsp"},
+		{"500g", NewFraction(500, 1), "g"},
+		{"2 ¾ fl oz", NewFraction(11, 4), "fl oz"},
+		{"3", NewFraction(3, 1), ""},
+		{" 1-1/4 lb ", NewFraction(5, 4), "lb"},
+	}
+	for _, test := range tests {
+		got, err := ParseQuantity(test.input)
+		if err != nil {
+			t.Errorf("ParseQuantity(%q) returned error: %s", test.input, err)
+			continue
+		}
+		if got.Amount.Cmp(test.amount) != 0 || got.Unit != test.unit {
+			t.Errorf("ParseQuantity(%q) = %s %q, want %s %q", test.input, got.Amount, got.Unit, test.amount,
+				test.unit)
+		}
+		// String must be readable by ParseQuantity
+		again, err := ParseQuantity(got.String())
+		if err != nil || again.Amount.Cmp(got.Amount) != 0 || again.Unit != got.Unit {
+			t.Errorf("ParseQuantity(%q) does not read back as %q", got.String(), test.input)
+		}
+	}
+}
+
+func TestParseQuantityInvalid(t *testing.T) {
+	for _, input := range []string{"", "cup", "a pinch"} {
+		if got, err := ParseQuantity(input); err == nil {
+			t.Errorf("ParseQuantity(%q) = %s, want an error", input, got)
+		}
+	}
+}
+
+func TestQuantityNormalize(t *testing.T) {
+	tests := []struct {
+		quantity Quantity
+		want     Quantity
+	}{
+		{NewQuantity(NewFraction(48, 1), "tsp"), NewQuantity(NewFraction(1, 1), "cup")},
+		{NewQuantity(NewFraction(24, 1), "teaspoons"), NewQuantity(NewFraction(1, 2), "cup")},
+		{NewQuantity(NewFraction(20, 1), "oz"), NewQuantity(NewFraction(5, 4), "lb")},
+		{NewQuantity(NewFraction(2, 1), "eggs"), NewQuantity(NewFraction(2, 1), "eggs")},
+	}
+	for _, test := range tests {
+		got := test.quantity.Normalize()
+		if got.Amount.Cmp(test.want.Amount) != 0 || got.Unit != test.want.Unit {
+			t.Errorf("%s normalized to %s, want %s", test.quantity, got, test.want)
+		}
+	}
+}
+
+func TestQuantityArithmetic(t *testing.T) {
+	cup := NewQuantity(NewFraction(1, 1), "cup")
+	tbsp := NewQuantity(NewFraction(4, 1), "tbsp")
+	sum, err := cup.Add(tbsp)
+	if err != nil || sum.Amount.Cmp(NewFraction(5, 4)) != 0 || sum.Unit != "cup" {
+		t.Errorf("%s + %s = %s, %v, want 1 1/4 cup", cup, tbsp, sum, err)
+	}
+	difference, err := cup.Sub(tbsp)
+	if err != nil || difference.Amount.Cmp(NewFraction(3, 4)) != 0 {
+		t.Errorf("%s - %s = %s, %v, want 3/4 cup", cup, tbsp, difference, err)
+	}
+	if cmp, err := cup.Cmp(tbsp); err != nil || cmp != 1 {
+		t.Errorf("%s compared to %s = %d, %v, want 1", cup, tbsp, cmp, err)
+	}
+	// a zero quantity with no unit takes the unit of what is added
+	if sum, err = (Quantity{}).Add(tbsp); err != nil || sum.Unit != "tbsp" {
+		t.Errorf("0 + %s = %s, %v, want %s", tbsp, sum, err, tbsp)
+	}
+	if _, err = cup.Add(NewQuantity(NewFraction(1, 1), "g")); err == nil {
+		t.Errorf("%s + 1 g did not return an error", cup)
+	}
+}
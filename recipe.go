@@ -1,7 +1,13 @@
 package recipeDatabase
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -9,30 +15,40 @@ import (
 
 //A Recipe struct is the internal representation of a recipe from a database
 type Recipe struct {
-	ID                int          // id of recipe in database
-	Name              string       // name of recipe
-	Description       string       // description of recipe
-	Comments          string       // recipe comments
-	Source            string       // source of recipe
-	Author            string       // author of recipe
-	Ingredients       []Ingredient // ingredients of recipe
-	QuantityMade      int          // how much of unit recipe makes
-	QuantityMadeUnits Unit         // unit of recipe
-	Steps             []Step       // steps of recipe
-	EquipmentNeeded   []Equipment  // equipment needed to make recipe
-	Tags              []string     // recipe tags
+	ID              int              // id of recipe in database
+	Name            string           // name of recipe
+	Description     string           // description of recipe
+	Comments        string           // recipe comments
+	Source          string           // source of recipe
+	Author          string           // author of recipe
+	Ingredients     []Ingredient     // ingredients of recipe
+	QuantityMade    Quantity         // how much recipe makes
+	Steps           []Step           // steps of recipe
+	EquipmentNeeded []Equipment      // equipment needed to make recipe
+	Tags            []string         // recipe tags
+	Diets           []string         // diets the recipe suits, derived from its ingredients
+	Nutrition       *RecipeNutrition // nutrition facts, nil if not calculated
+	Cost            *RecipeCost      // cost of ingredients, nil if not priced
+	InitialVersion  int              // id of the original recipe, 0 if this is the original
+	Version         int              // revision number of recipe, 1 for the original
+	Journal         Journal          // times the recipe was made, most recent first
+	scaleFactor     float64          // factor recipe was scaled by, 0 if unscaled
 }
 
 func (r Recipe) String() string {
 	stringString := ""
 	stringString += fmt.Sprintf("%s \n\n ", r.Name)
-	if r.QuantityMade > 1 {
-		stringString += fmt.Sprintf("Makes %d %s's\n", r.QuantityMade, r.QuantityMadeUnits)
-	} else if r.QuantityMade == 1 {
-		stringString += fmt.Sprintf("Makes %d %s\n", r.QuantityMade, r.QuantityMadeUnits)
+	if r.Version > 1 {
+		stringString += fmt.Sprintf("Revision %d\n", r.Version)
+	}
+	if r.QuantityMade.Amount.Sign() > 0 {
+		stringString += fmt.Sprintf("Makes %s\n", r.QuantityMade.Format())
 	} else {
 		stringString += "Makes nothing, good job cookie\n"
 	}
+	if r.scaleFactor != 0 && r.scaleFactor != 1 {
+		stringString += fmt.Sprintf("Scaled %Gx from original recipe\n", r.scaleFactor)
+	}
 
 	var prepTime, cookTime, waitTime, otherTime time.Duration = 0, 0, 0, 0
 
@@ -71,7 +87,200 @@ func (r Recipe) String() string {
 	for _, tag := range r.Tags {
 		stringString += tag
 	}
+	if len(r.Diets) > 0 {
+		stringString += "\nSuits: " + strings.Join(r.Diets, ", ")
+	}
+	if r.Nutrition != nil {
+		stringString += "\n" + r.Nutrition.String()
+	}
+	if r.Cost != nil {
+		stringString += "\nCost: " + r.Cost.Summary() + r.Cost.Warning()
+	}
+	if len(r.Journal) > 0 {
+		stringString += "\n" + strings.TrimSuffix(r.Journal.Summary(), "\n")
+	}
 
 	stringString += "\n\n"
 	return stringString
 }
+
+//Scale returns a copy of the recipe with every ingredient and the quantity
+//made multiplied by factor. The stored recipe is not changed.
+func (r Recipe) Scale(factor float64) (Recipe, error) {
+	if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+		return r, fmt.Errorf("invalid scale factor %G", factor)
+	}
+	scaled := r
+	scaled.Ingredients = make([]Ingredient, len(r.Ingredients))
+	for i, ingredient := range r.Ingredients {
+		scaled.Ingredients[i] = ingredient.Scale(factor)
+	}
+	scaled.QuantityMade = r.QuantityMade.Mul(FractionFromFloat(factor))
+	if r.Nutrition != nil {
+		nutrition := r.Nutrition.Scale(factor)
+		scaled.Nutrition = &nutrition
+	}
+	if r.Cost != nil {
+		cost := r.Cost.Scale(factor)
+		scaled.Cost = &cost
+	}
+	if r.scaleFactor != 0 {
+		scaled.scaleFactor = r.scaleFactor * factor
+	} else {
+		scaled.scaleFactor = factor
+	}
+	return scaled, nil
+}
+
+//ScaleTo returns a copy of the recipe scaled so that it makes target.
+//The unit of target must match the unit of QuantityMade, or be convertable
+//to it.
+func (r Recipe) ScaleTo(target Quantity) (Recipe, error) {
+	if r.QuantityMade.IsZero() {
+		return r, errors.New("recipe has no quantity made to scale from")
+	}
+	if !sameUnit(target.Unit, r.QuantityMade.Unit) {
+		converted, err := target.Convert(r.QuantityMade.Unit)
+		if err != nil {
+			return r, fmt.Errorf("recipe makes %s, not %s", r.QuantityMade.Unit, target.Unit)
+		}
+		target = converted
+	}
+	return r.Scale(target.Amount.Div(r.QuantityMade.Amount).Float64())
+}
+
+//ReadRecipe creates a recipe struct by prompting user for input
+func ReadRecipe() (Recipe, error) {
+	var tempRecipe Recipe
+	reader := bufio.NewReader(os.Stdin)
+
+	prompts := []struct {
+		prompt string
+		field  *string
+	}{
+		{"Enter recipe Name: ", &tempRecipe.Name},
+		{"Enter recipe Description: ", &tempRecipe.Description},
+		{"Enter recipe Comments: ", &tempRecipe.Comments},
+		{"Enter recipe Source: ", &tempRecipe.Source},
+		{"Enter recipe Author: ", &tempRecipe.Author},
+	}
+	for _, p := range prompts {
+		fmt.Print(p.prompt)
+		tempString, err := reader.ReadString('\n')
+		if err != nil {
+			return tempRecipe, err
+		}
+		*p.field = strings.TrimSpace(tempString)
+	}
+
+	fmt.Print("Enter quantity recipe makes, ie: 1.5 loaves: ")
+	tempString, err := reader.ReadString('\n')
+	if err != nil {
+		return tempRecipe, err
+	}
+	tempRecipe.QuantityMade, err = ParseQuantity(tempString)
+	if err != nil {
+		return tempRecipe, err
+	}
+
+	for {
+		fmt.Print("Add an ingredient? (y/n): ")
+		tempString, err = reader.ReadString('\n')
+		if err != nil {
+			return tempRecipe, err
+		}
+		if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(tempString)), "y") {
+			break
+		}
+		tempIngredient, err := readIngredient(reader)
+		if err != nil {
+			return tempRecipe, err
+		}
+		tempRecipe.Ingredients = append(tempRecipe.Ingredients, tempIngredient)
+	}
+
+	fmt.Println("Enter steps as: <prep|cook|wait|other> <minutes> <instructions>. " +
+		"Enter a blank line when done")
+	for {
+		fmt.Print("Step: ")
+		tempString, err = reader.ReadString('\n')
+		if err != nil {
+			return tempRecipe, err
+		}
+		fields := strings.SplitN(strings.TrimSpace(tempString), " ", 3)
+		if len(fields) < 3 {
+			break
+		}
+		var tempStep Step
+		switch strings.ToLower(fields[0]) {
+		case "prep":
+			tempStep.StepType = Prep
+		case "cook":
+			tempStep.StepType = Cook
+		case "wait":
+			tempStep.StepType = Wait
+		default:
+			tempStep.StepType = Other
+		}
+		minutes, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			fmt.Println("Invalid number of minutes:", fields[1])
+			continue
+		}
+		tempStep.TimeNeeded = time.Duration(minutes * float64(time.Minute))
+		tempStep.Instructions = fields[2]
+		tempRecipe.Steps = append(tempRecipe.Steps, tempStep)
+	}
+
+	fmt.Print("Enter recipe Tags, separated by commas: ")
+	tempString, err = reader.ReadString('\n')
+	if err != nil {
+		return tempRecipe, err
+	}
+	for _, tag := range strings.Split(tempString, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tempRecipe.Tags = append(tempRecipe.Tags, tag)
+		}
+	}
+
+	return tempRecipe, nil
+}
+
+//HasTags reports whether the recipe has every one of tags, ignoring case
+func (r Recipe) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, recipeTag := range r.Tags {
+			if strings.EqualFold(strings.TrimSpace(tag), recipeTag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+//servingUnits are units of QuantityMade that count servings
+var servingUnits = map[string]bool{"": true, "serving": true, "servings": true, "portion": true,
+	"portions": true, "person": true, "people": true, "serves": true}
+
+//Servings returns how many servings the recipe makes, or zero when its
+//quantity made is not counted in servings, such as 2 loaves
+func (r Recipe) Servings() Fraction {
+	if !servingUnits[strings.ToLower(r.QuantityMade.Unit)] {
+		return Fraction{}
+	}
+	return r.QuantityMade.Amount
+}
+
+//TotalTime returns the time needed by every step of the recipe
+func (r Recipe) TotalTime() time.Duration {
+	var total time.Duration
+	for _, step := range r.Steps {
+		total += step.TimeNeeded
+	}
+	return total
+}
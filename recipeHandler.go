@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const recipesUsage = `usage: cookbook recipes <command> [arguments]
+
+commands:
+	list [filter flags]            list every recipe
+	search [filter flags] <term>   list recipes with term in their name,
+	                               description or ingredients
+
+filter flags:
+	-diet <diets>   only recipes suiting all of these comma separated diets,
+	                ie: vegan,gluten-free
+	-tags <tags>    only recipes with all of these comma separated tags
+
+Run cookbook diet for how the diets of recipes are derived.
+`
+
+//recipesCommand runs the recipes subcommand given on the command line
+func recipesCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(recipesUsage)
+		return errors.New("no recipes command given")
+	}
+	if args[0] != "list" && args[0] != "search" {
+		fmt.Print(recipesUsage)
+		return fmt.Errorf("unknown recipes command %s", args[0])
+	}
+	flags := flag.NewFlagSet("recipes "+args[0], flag.ContinueOnError)
+	diets := flags.String("diet", "", "Only recipes suiting all of these comma separated diets")
+	tags := flags.String("tags", "", "Only recipes with all of these comma separated tags")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	wantedDiets, err := backend.ParseDiets(*diets)
+	if err != nil {
+		return err
+	}
+	var recipes []backend.Recipe
+	if args[0] == "search" {
+		if flags.NArg() == 0 {
+			return errors.New("usage: cookbook recipes search [filter flags] <term>")
+		}
+		recipes, err = searchRecipes(db, strings.Join(flags.Args(), " "))
+	} else {
+		recipes, err = selectAllRecipes(db)
+	}
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, recipe := range recipes {
+		if !recipe.HasTags(splitTags(*tags)) || !recipe.SuitsDiets(wantedDiets) {
+			continue
+		}
+		found = true
+		fmt.Print(recipeSummary(recipe))
+	}
+	if !found {
+		fmt.Println("No recipes match")
+	}
+	return nil
+}
+
+//searchRecipes returns every recipe with term in its name, description or
+//the name of one of its ingredients, sorted by name
+func searchRecipes(db *sql.DB, term string) ([]backend.Recipe, error) {
+	like := "%" + term + "%"
+	return selectRecipesWhere(db, "WHERE recipes.name LIKE ? OR recipes.description LIKE ? "+
+		"OR recipes.id IN (SELECT ingredient_recipe.recipeID FROM ingredient_recipe "+
+		"INNER JOIN ingredients ON ingredient_recipe.ingredientID = ingredients.id "+
+		"WHERE ingredients.name LIKE ?) ORDER BY recipes.name", like, like, like)
+}
+
+//recipeSummary is a recipe on one line with its description, then its tags
+//and diets
+func recipeSummary(recipe backend.Recipe) string {
+	stringString := recipe.Name
+	if recipe.Description != "" {
+		stringString += ": " + recipe.Description
+	}
+	stringString += "\n"
+	if len(recipe.Tags) > 0 {
+		stringString += "\tTags: " + strings.Join(recipe.Tags, ", ") + "\n"
+	}
+	if len(recipe.Diets) > 0 {
+		stringString += "\tSuits: " + strings.Join(recipe.Diets, ", ") + "\n"
+	}
+	return stringString
+}
@@ -0,0 +1,141 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"time"
+)
+
+//A UsageRecord is one use of an inventory item, from cooking a recipe or
+//from adjusting the packages on hand
+type UsageRecord struct {
+	Date     time.Time
+	Packages Fraction // packages used
+	Reason   string   // cook, use, adjust or stocktake
+}
+
+//BelowMinimum reports whether fewer packages than the minimum stock level
+//are on hand. Items without a minimum are never below it.
+func (item InventoryItem) BelowMinimum() bool {
+	return item.MinimumStock.Sign() > 0 && item.Quantity.Cmp(item.MinimumStock) < 0
+}
+
+//ReorderPackages returns the number of packages to buy when the item is
+//below its minimum. This is the preferred reorder quantity, or enough whole
+//packages to get back to the minimum if that is more or no reorder
+//quantity is set.
+func (item InventoryItem) ReorderPackages() Fraction {
+	shortfall := item.MinimumStock.Sub(item.Quantity).Ceil()
+	if shortfall.Cmp(item.ReorderQuantity) > 0 {
+		return shortfall
+	}
+	return item.ReorderQuantity
+}
+
+//A RunOutPrediction estimates when an item will run out at the rate it has
+//been used
+type RunOutPrediction struct {
+	PackagesPerDay float64
+	RunOut         time.Time // zero if the item is not being used
+	Below          time.Time // when it falls below its minimum, zero if never or already below
+}
+
+//PredictRunOut estimates when item will run out from its usage since the
+//first record. Usage is averaged over the whole period, so a single large
+//use early on counts for less than the same use yesterday.
+func PredictRunOut(item InventoryItem, usage []UsageRecord, now time.Time) RunOutPrediction {
+	var prediction RunOutPrediction
+	if len(usage) == 0 {
+		return prediction
+	}
+	first := now
+	total := Fraction{}
+	for _, record := range usage {
+		if record.Date.Before(first) {
+			first = record.Date
+		}
+		total = total.Add(record.Packages)
+	}
+	// a period of less than a day would make one use look like a huge rate
+	days := now.Sub(first).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	prediction.PackagesPerDay = total.Float64() / days
+	if prediction.PackagesPerDay <= 0 {
+		return prediction
+	}
+	prediction.RunOut = now.Add(daysDuration(item.Quantity.Float64() / prediction.PackagesPerDay))
+	if above := item.Quantity.Sub(item.MinimumStock); item.MinimumStock.Sign() > 0 && above.Sign() > 0 {
+		prediction.Below = now.Add(daysDuration(above.Float64() / prediction.PackagesPerDay))
+	}
+	return prediction
+}
+
+//daysDuration converts a number of days to a duration
+func daysDuration(days float64) time.Duration {
+	return time.Duration(days * 24 * float64(time.Hour))
+}
+
+func (p RunOutPrediction) String() string {
+	if p.RunOut.IsZero() {
+		return "not used recently"
+	}
+	stringString := fmt.Sprintf("using %.2f packages a day, runs out about %s", p.PackagesPerDay,
+		p.RunOut.Format(DateFormat))
+	if !p.Below.IsZero() {
+		stringString += ", below minimum about " + p.Below.Format(DateFormat)
+	}
+	return stringString
+}
+
+//AddReorder adds packages of an item that is low on stock to the list. If
+//the list already has a line for the item the packages are added to it,
+//noting the amount when it can not be converted to the units of the line.
+func (l *ShoppingList) AddReorder(item InventoryItem) {
+	packages := item.ReorderPackages()
+	if packages.Sign() <= 0 {
+		return
+	}
+	amount := NewQuantity(packages, "")
+	if !item.PackageQuantity.IsZero() {
+		amount = item.PackageQuantity.Mul(packages)
+	}
+	name := CanonicalName(item.Name)
+	for i := range l.Items {
+		line := &l.Items[i]
+		if line.Name != name {
+			continue
+		}
+		reason := lowStockReason
+		if line.ToBuy.IsZero() {
+			line.ToBuy = amount
+		} else {
+			converted, err := Ingredient{Name: item.Name, QuantityNeeded: amount}.convertQuantity(line.ToBuy.Unit)
+			if err != nil {
+				// the packages are still bought, so note the amount they hold
+				// rather than starting a second line for the same item
+				reason = fmt.Sprintf("%s (%s, units differ)", lowStockReason, amount.Format())
+			} else {
+				line.ToBuy.Amount = line.ToBuy.Amount.Add(converted)
+			}
+		}
+		if line.PackageQuantity.IsZero() {
+			line.PackageQuantity = item.PackageQuantity
+		}
+		line.Packages = line.Packages.Add(packages)
+		line.addRecipe(reason)
+		return
+	}
+	l.Items = append(l.Items, ShoppingItem{
+		Name:            name,
+		ToBuy:           amount,
+		OnHand:          item.OnHand(),
+		PackageQuantity: item.PackageQuantity,
+		Packages:        packages,
+		Recipes:         []string{lowStockReason},
+	})
+}
+
+//lowStockReason is listed with the recipes of a shopping list line that
+//restocks an item below its minimum
+const lowStockReason = "low stock"
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+//shopCommand runs the shop subcommand given on the command line
+func shopCommand(db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("shop", flag.ContinueOnError)
+	format := flags.String("format", "text", "Output format: text, md, json or csv")
+	output := flags.String("o", "", "File to write shopping list to, default stdout")
+	ignoreInventory := flags.Bool("all", false, "Do not subtract inventory on hand")
+	reorder := flags.Bool("reorder", false, "Add items below their minimum stock level")
+	noSubstitutes := flags.Bool("nosubs", false, "Do not use substitutions for ingredients that are short")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: cookbook shop [flags] [<recipe name>[:scale] ...]")
+		fmt.Fprintln(flags.Output(), "ie: cookbook shop -format md \"Pancakes:2\" \"Chili\"")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 && !*reorder {
+		flags.Usage()
+		return errors.New("no recipes given to shop for")
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	var list backend.ShoppingList
+	for _, arg := range flags.Args() {
+		name, scale := parseRecipeScale(arg)
+		recipe, err := chooseRecipe(db, name, reader)
+		if err != nil {
+			return err
+		}
+		if err = list.AddRecipe(recipe, scale); err != nil {
+			return err
+		}
+	}
+
+	if !*ignoreInventory {
+		if err := subtractInventory(db, &list, !*noSubstitutes); err != nil {
+			return err
+		}
+	}
+	if *reorder {
+		low, err := lowStockItems(db)
+		if err != nil {
+			return err
+		}
+		for _, item := range low {
+			list.AddReorder(item)
+		}
+	}
+
+	exported, err := list.Export(*format)
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		return ioutil.WriteFile(*output, []byte(exported), 0644)
+	}
+	fmt.Print(exported)
+	return nil
+}
+
+//subtractInventory reduces a shopping list by the inventory on hand, and
+//with useSubstitutes covers what is short with substitutions on hand
+func subtractInventory(db *sql.DB, list *backend.ShoppingList, useSubstitutes bool) error {
+	inventory, err := inventoryByIngredient(db)
+	if err != nil {
+		return err
+	}
+	list.SubtractInventory(inventory)
+	if useSubstitutes {
+		substitutions, err := selectSubstitutions(db)
+		if err != nil {
+			return err
+		}
+		list.UseSubstitutes(inventory, substitutions)
+	}
+	return nil
+}
+
+//parseRecipeScale splits a recipe argument in the form name:scale. The
+//scale is optional and defaults to 1, so a name with a colon in it, such as
+//"Chili: Texas style", is the whole name.
+func parseRecipeScale(arg string) (string, float64) {
+	colon := strings.LastIndex(arg, ":")
+	if colon < 0 {
+		return arg, 1
+	}
+	scale, err := strconv.ParseFloat(strings.TrimSpace(arg[colon+1:]), 64)
+	if err != nil {
+		return arg, 1
+	}
+	return strings.TrimSpace(arg[:colon]), scale
+}
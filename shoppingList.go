@@ -0,0 +1,293 @@
+package recipeDatabase
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//A ShoppingItem is one line of a shopping list: the total amount of an
+//ingredient needed by a set of recipes, and how much of it still needs to
+//be bought once the inventory on hand is used.
+type ShoppingItem struct {
+	Name            string   `json:"name"`                 // canonical name of ingredient
+	Needed          Quantity `json:"needed"`               // amount needed by all recipes
+	OnHand          Quantity `json:"onHand"`               // amount of needed in inventory
+	ToBuy           Quantity `json:"toBuy"`                // amount left to buy
+	PackageQuantity Quantity `json:"packageQuantity"`      // size of one package, zero if unknown
+	Packages        Fraction `json:"-"`                    // whole packages to buy, zero if unknown
+	Recipes         []string `json:"recipes"`              // recipes that use ingredient
+	Substitute      string   `json:"substitute,omitempty"` // substitution used instead of buying, if any
+}
+
+//A ShoppingList aggregates the ingredients of several recipes. Ingredients
+//are combined when they have the same canonical name and their units can be
+//converted between each other.
+type ShoppingList struct {
+	Items []ShoppingItem
+}
+
+//AddRecipe adds every ingredient of recipe, scaled by scale, to the list
+func (l *ShoppingList) AddRecipe(recipe Recipe, scale float64) error {
+	if scale != 1 {
+		var err error
+		recipe, err = recipe.Scale(scale)
+		if err != nil {
+			return err
+		}
+	}
+	for _, ingredient := range recipe.Ingredients {
+		l.addIngredient(ingredient, recipe.Name)
+	}
+	return nil
+}
+
+//addIngredient adds one ingredient to the line for the same ingredient in
+//a compatible unit, or starts a new line
+func (l *ShoppingList) addIngredient(ingredient Ingredient, recipeName string) {
+	name := CanonicalName(ingredient.Name)
+	for i := range l.Items {
+		item := &l.Items[i]
+		if item.Name != name {
+			continue
+		}
+		converted, err := ingredient.convertQuantity(item.Needed.Unit)
+		if err != nil {
+			continue
+		}
+		item.Needed.Amount = item.Needed.Amount.Add(converted)
+		item.ToBuy = item.Needed
+		item.addRecipe(recipeName)
+		return
+	}
+	l.Items = append(l.Items, ShoppingItem{
+		Name:    name,
+		Needed:  ingredient.QuantityNeeded,
+		ToBuy:   ingredient.QuantityNeeded,
+		Recipes: []string{recipeName},
+	})
+}
+
+func (s *ShoppingItem) addRecipe(recipeName string) {
+	for _, name := range s.Recipes {
+		if name == recipeName {
+			return
+		}
+	}
+	s.Recipes = append(s.Recipes, recipeName)
+}
+
+//SubtractInventory reduces each line by the inventory on hand, and rounds
+//what is left up to whole packages. inventory maps canonical ingredient
+//names to the items that can be used for that ingredient. Items are used in
+//the order given, and the first item with a package size decides the
+//package to buy.
+func (l *ShoppingList) SubtractInventory(inventory map[string][]InventoryItem) {
+	for i := range l.Items {
+		item := &l.Items[i]
+		item.OnHand, item.PackageQuantity = onHand(item.Name, item.Needed.Unit, inventory)
+		item.ToBuy = item.Needed
+		item.ToBuy.Amount = item.Needed.Amount.Sub(item.OnHand.Amount)
+		if item.ToBuy.Amount.Sign() < 0 {
+			item.ToBuy.Amount = Fraction{}
+		}
+		item.Packages = Fraction{}
+		if !item.PackageQuantity.IsZero() && !item.ToBuy.IsZero() {
+			stock := InventoryItem{Name: item.Name, PackageQuantity: item.PackageQuantity}
+			packages, err := stock.PackagesOf(item.ToBuy)
+			if err == nil {
+				item.Packages = packages.Ceil()
+			}
+		}
+	}
+}
+
+//UseSubstitutes covers lines that still need something bought with a
+//substitution whose replacement ingredients are on hand, so nothing needs
+//to be bought for them. Inventory already used by other lines, or by an
+//earlier substitute, is not used twice. Call it after SubtractInventory.
+func (l *ShoppingList) UseSubstitutes(inventory map[string][]InventoryItem, substitutions []Substitution) {
+	reserved := make(map[string][]Quantity)
+	for _, item := range l.Items {
+		reserved[item.Name] = append(reserved[item.Name], item.OnHand)
+	}
+	available := func(ingredient Ingredient) bool {
+		name := CanonicalName(ingredient.Name)
+		have, _ := onHand(name, ingredient.QuantityNeeded.Unit, inventory)
+		for _, used := range reserved[name] {
+			converted, err := Ingredient{Name: name, QuantityNeeded: used}.convertQuantity(have.Unit)
+			if err == nil {
+				have.Amount = have.Amount.Sub(converted)
+			}
+		}
+		return have.Amount.Cmp(ingredient.QuantityNeeded.Amount) >= 0
+	}
+	for i := range l.Items {
+		item := &l.Items[i]
+		if item.ToBuy.Amount.Sign() <= 0 {
+			continue
+		}
+		substitute, ok := findSubstitute(item.Name, item.ToBuy, substitutions, available)
+		if !ok {
+			continue
+		}
+		for _, ingredient := range substitute.Replacement {
+			name := CanonicalName(ingredient.Name)
+			reserved[name] = append(reserved[name], ingredient.QuantityNeeded)
+		}
+		item.Substitute = fmt.Sprintf("for %s %s", item.ToBuy.Format(), substitute.String())
+		item.ToBuy.Amount = Fraction{}
+		item.Packages = Fraction{}
+	}
+}
+
+//onHand totals the inventory of an ingredient in unit, skipping items that
+//cannot be converted to it, and returns the package size of the first item
+//that has one
+func onHand(name string, unit string, inventory map[string][]InventoryItem) (Quantity, Quantity) {
+	total := NewQuantity(Fraction{}, unit)
+	var packageQuantity Quantity
+	for _, stock := range inventory[name] {
+		converted, err := Ingredient{Name: stock.Name, QuantityNeeded: stock.OnHand()}.convertQuantity(unit)
+		if err != nil {
+			continue
+		}
+		total.Amount = total.Amount.Add(converted)
+		if packageQuantity.IsZero() && !stock.PackageQuantity.IsZero() {
+			packageQuantity = stock.PackageQuantity
+		}
+	}
+	return total, packageQuantity
+}
+
+//ToBuyItems returns the lines that still need something bought, sorted by
+//name
+func (l ShoppingList) ToBuyItems() []ShoppingItem {
+	var items []ShoppingItem
+	for _, item := range l.Items {
+		if item.ToBuy.Amount.Sign() > 0 {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(a, b int) bool {
+		return items[a].Name < items[b].Name
+	})
+	return items
+}
+
+//SubstitutedItems returns the lines covered by a substitute, sorted by name
+func (l ShoppingList) SubstitutedItems() []ShoppingItem {
+	var items []ShoppingItem
+	for _, item := range l.Items {
+		if item.Substitute != "" {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(a, b int) bool {
+		return items[a].Name < items[b].Name
+	})
+	return items
+}
+
+//buyString describes what to buy for one line, in whole packages when the
+//package size is known
+func (s ShoppingItem) buyString() string {
+	if s.Packages.IsZero() || s.PackageQuantity.IsZero() {
+		return s.ToBuy.Format()
+	}
+	return fmt.Sprintf("%s × %s (need %s)", s.Packages.String(), s.PackageQuantity.Format(), s.ToBuy.Format())
+}
+
+func (l ShoppingList) String() string {
+	stringString := "Shopping List: \n"
+	for _, item := range l.ToBuyItems() {
+		stringString += fmt.Sprintf("\t%s: %s\n", item.Name, item.buyString())
+	}
+	substituted := l.SubstitutedItems()
+	if len(substituted) > 0 {
+		stringString += "Substitutes: \n"
+		for _, item := range substituted {
+			stringString += fmt.Sprintf("\t%s: %s\n", item.Name, item.Substitute)
+		}
+	}
+	return stringString
+}
+
+//Markdown returns the list as a markdown checklist
+func (l ShoppingList) Markdown() string {
+	stringString := "# Shopping List\n\n"
+	for _, item := range l.ToBuyItems() {
+		stringString += fmt.Sprintf("- [ ] **%s**: %s\n", item.Name, item.buyString())
+	}
+	substituted := l.SubstitutedItems()
+	if len(substituted) > 0 {
+		stringString += "\n## Substitutes\n\n"
+		for _, item := range substituted {
+			stringString += fmt.Sprintf("- **%s**: %s\n", item.Name, item.Substitute)
+		}
+	}
+	return stringString
+}
+
+//JSON returns the list as a JSON array of the items left to buy, followed
+//by the items covered by a substitute
+func (l ShoppingList) JSON() ([]byte, error) {
+	type jsonItem struct {
+		ShoppingItem
+		Packages string `json:"packages"`
+	}
+	items := []jsonItem{}
+	for _, item := range append(l.ToBuyItems(), l.SubstitutedItems()...) {
+		items = append(items, jsonItem{item, item.Packages.String()})
+	}
+	return json.MarshalIndent(items, "", "  ")
+}
+
+//CSV returns the list as comma separated values with a header row, with the
+//items covered by a substitute last
+func (l ShoppingList) CSV() (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	err := writer.Write([]string{"name", "to buy", "packages", "package size", "needed",
+		"on hand", "recipes", "substitute"})
+	if err != nil {
+		return "", err
+	}
+	for _, item := range append(l.ToBuyItems(), l.SubstitutedItems()...) {
+		packages := ""
+		if !item.Packages.IsZero() {
+			packages = item.Packages.String()
+		}
+		packageSize := ""
+		if !item.PackageQuantity.IsZero() {
+			packageSize = item.PackageQuantity.Format()
+		}
+		err = writer.Write([]string{item.Name, item.ToBuy.Format(), packages, packageSize,
+			item.Needed.Format(), item.OnHand.Format(), strings.Join(item.Recipes, "; "),
+			item.Substitute})
+		if err != nil {
+			return "", err
+		}
+	}
+	writer.Flush()
+	return buffer.String(), writer.Error()
+}
+
+//Export returns the list in format, which is one of text, md, json or csv
+func (l ShoppingList) Export(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", "text", "txt":
+		return l.String(), nil
+	case "md", "markdown":
+		return l.Markdown(), nil
+	case "json":
+		bytes, err := l.JSON()
+		return string(bytes) + "\n", err
+	case "csv":
+		return l.CSV()
+	}
+	return "", fmt.Errorf("unknown shopping list format %s, expected text, md, json or csv", format)
+}
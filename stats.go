@@ -0,0 +1,289 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//StatsOptions controls what a cookbook statistics report covers
+type StatsOptions struct {
+	Top         int       // entries in each ranked list, 10 if zero
+	StaleMonths int       // list recipes not made in this many months, 6 if zero
+	PeriodDays  int       // days of inventory usage for turnover, 30 if zero
+	Now         time.Time // day the report is for
+}
+
+//A RecipeCount is how often a recipe was made
+type RecipeCount struct {
+	Name     string  `json:"name"`
+	Made     int     `json:"made"`
+	LastMade string  `json:"lastMade,omitempty"` // as YYYY-MM-DD, empty if never made
+	Rating   float64 `json:"rating,omitempty"`   // average rating, 0 if not rated
+}
+
+//An IngredientUse is how much an ingredient is used across recipes
+type IngredientUse struct {
+	Name    string `json:"name"`
+	Recipes int    `json:"recipes"` // recipes using the ingredient
+	Cooked  int    `json:"cooked"`  // times a recipe using it was made
+}
+
+//A TagTime is the average time of the recipes with a tag
+type TagTime struct {
+	Tag            string  `json:"tag"`
+	Recipes        int     `json:"recipes"` // recipes with the tag and a time
+	AverageMinutes float64 `json:"averageMinutes"`
+}
+
+//An ItemStats is the value and turnover of an inventory item
+type ItemStats struct {
+	Name     string  `json:"name"`
+	OnHand   float64 `json:"onHand"`          // packages on hand
+	Value    float64 `json:"value,omitempty"` // value of packages on hand, 0 if not priced
+	Used     float64 `json:"used"`            // packages used over the period
+	Turnover float64 `json:"turnover"`        // packages used over the period per package on hand
+}
+
+//Stats is a report on how the cookbook and inventory are used
+type Stats struct {
+	Date           string          `json:"date"`
+	Recipes        int             `json:"recipes"`
+	Made           int             `json:"made"` // times any recipe was made
+	MostMade       []RecipeCount   `json:"mostMade"`
+	LeastMade      []RecipeCount   `json:"leastMade"`
+	StaleMonths    int             `json:"staleMonths"`
+	NotMade        []RecipeCount   `json:"notMade"` // not made in StaleMonths, least recently first
+	Ingredients    []IngredientUse `json:"ingredients"`
+	TagTimes       []TagTime       `json:"tagTimes"`
+	InventoryValue float64         `json:"inventoryValue"`
+	Unpriced       int             `json:"unpriced"` // items on hand without a price
+	PeriodDays     int             `json:"periodDays"`
+	Items          []ItemStats     `json:"items"` // highest turnover first
+}
+
+//ComputeStats builds a statistics report from every recipe, the journal of
+//times they were made, and the inventory with its prices and usage keyed by
+//inventory id
+func ComputeStats(recipes []Recipe, journal Journal, inventory []InventoryItem, prices map[int]PriceHistory,
+	usage map[int][]UsageRecord, options StatsOptions) Stats {
+	if options.Top <= 0 {
+		options.Top = 10
+	}
+	if options.StaleMonths <= 0 {
+		options.StaleMonths = 6
+	}
+	if options.PeriodDays <= 0 {
+		options.PeriodDays = 30
+	}
+	stats := Stats{Date: options.Now.Format(DateFormat), Recipes: len(recipes), Made: len(journal),
+		StaleMonths: options.StaleMonths, PeriodDays: options.PeriodDays}
+
+	made := make(map[int]Journal)
+	for _, entry := range journal {
+		made[entry.RecipeID] = append(made[entry.RecipeID], entry)
+	}
+	var counts []RecipeCount
+	var lastMade []time.Time
+	for _, recipe := range recipes {
+		count := RecipeCount{Name: recipe.Name, Made: len(made[recipe.ID])}
+		var last time.Time
+		for _, entry := range made[recipe.ID] {
+			if entry.Date.After(last) {
+				last = entry.Date
+			}
+		}
+		if !last.IsZero() {
+			count.LastMade = last.Format(DateFormat)
+		}
+		count.Rating, _ = made[recipe.ID].AverageRating()
+		counts = append(counts, count)
+		lastMade = append(lastMade, last)
+	}
+
+	// most made ties go to the most recently made, least made ties to the
+	// least recently made
+	order := make([]int, len(counts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if counts[order[a]].Made != counts[order[b]].Made {
+			return counts[order[a]].Made > counts[order[b]].Made
+		}
+		return lastMade[order[a]].After(lastMade[order[b]])
+	})
+	for _, i := range order {
+		if counts[i].Made == 0 || len(stats.MostMade) == options.Top {
+			break
+		}
+		stats.MostMade = append(stats.MostMade, counts[i])
+	}
+	for j := len(order) - 1; j >= 0 && len(stats.LeastMade) < options.Top; j-- {
+		stats.LeastMade = append(stats.LeastMade, counts[order[j]])
+	}
+	stale := options.Now.AddDate(0, -options.StaleMonths, 0)
+	sort.SliceStable(order, func(a, b int) bool {
+		return lastMade[order[a]].Before(lastMade[order[b]])
+	})
+	for _, i := range order {
+		if !lastMade[i].Before(stale) {
+			break
+		}
+		stats.NotMade = append(stats.NotMade, counts[i])
+	}
+
+	uses := make(map[string]*IngredientUse)
+	for _, recipe := range recipes {
+		seen := make(map[string]bool)
+		for _, ingredient := range recipe.Ingredients {
+			name := CanonicalName(ingredient.Name)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if uses[name] == nil {
+				uses[name] = &IngredientUse{Name: name}
+			}
+			uses[name].Recipes++
+			uses[name].Cooked += len(made[recipe.ID])
+		}
+	}
+	for _, use := range uses {
+		stats.Ingredients = append(stats.Ingredients, *use)
+	}
+	sort.Slice(stats.Ingredients, func(a, b int) bool {
+		ingredientA, ingredientB := stats.Ingredients[a], stats.Ingredients[b]
+		if ingredientA.Cooked != ingredientB.Cooked {
+			return ingredientA.Cooked > ingredientB.Cooked
+		}
+		if ingredientA.Recipes != ingredientB.Recipes {
+			return ingredientA.Recipes > ingredientB.Recipes
+		}
+		return ingredientA.Name < ingredientB.Name
+	})
+	if len(stats.Ingredients) > options.Top {
+		stats.Ingredients = stats.Ingredients[:options.Top]
+	}
+
+	tagTotals := make(map[string]time.Duration)
+	tagCounts := make(map[string]int)
+	for _, recipe := range recipes {
+		total := recipe.TotalTime()
+		if total <= 0 {
+			continue
+		}
+		for _, tag := range recipe.Tags {
+			tagTotals[tag] += total
+			tagCounts[tag]++
+		}
+	}
+	for tag, total := range tagTotals {
+		stats.TagTimes = append(stats.TagTimes, TagTime{tag, tagCounts[tag],
+			total.Minutes() / float64(tagCounts[tag])})
+	}
+	sort.Slice(stats.TagTimes, func(a, b int) bool {
+		return stats.TagTimes[a].Tag < stats.TagTimes[b].Tag
+	})
+
+	since := options.Now.AddDate(0, 0, -options.PeriodDays)
+	for _, item := range inventory {
+		itemStats := ItemStats{Name: item.Name, OnHand: item.Quantity.Float64()}
+		if price, ok := prices[item.ID].PriceOn(time.Time{}); ok {
+			value := price.Price.Mul(item.Quantity)
+			itemStats.Value = value.Float64()
+			stats.InventoryValue += itemStats.Value
+		} else if item.Quantity.Sign() > 0 {
+			stats.Unpriced++
+		}
+		used := Fraction{}
+		for _, record := range usage[item.ID] {
+			if !record.Date.Before(since) {
+				used = used.Add(record.Packages)
+			}
+		}
+		itemStats.Used = used.Float64()
+		// an item used up entirely turned over at least as often as it was used
+		onHand := itemStats.OnHand
+		if onHand < 1 {
+			onHand = 1
+		}
+		itemStats.Turnover = itemStats.Used / onHand
+		if itemStats.OnHand == 0 && itemStats.Used == 0 {
+			continue
+		}
+		stats.Items = append(stats.Items, itemStats)
+	}
+	sort.SliceStable(stats.Items, func(a, b int) bool {
+		if stats.Items[a].Turnover != stats.Items[b].Turnover {
+			return stats.Items[a].Turnover > stats.Items[b].Turnover
+		}
+		return stats.Items[a].Value > stats.Items[b].Value
+	})
+	return stats
+}
+
+//recipeCountsString lists recipe counts as a table under a heading
+func recipeCountsString(heading string, counts []RecipeCount) string {
+	stringString := heading + ":\n"
+	if len(counts) == 0 {
+		return stringString + "\tnone\n"
+	}
+	for _, count := range counts {
+		last := count.LastMade
+		if last == "" {
+			last = "never"
+		}
+		rating := ""
+		if count.Rating > 0 {
+			rating = fmt.Sprintf("%.1f/%d", count.Rating, MaxRating)
+		}
+		stringString += fmt.Sprintf("\t%-30s %5d  %-10s  %s\n", count.Name, count.Made, last, rating)
+	}
+	return stringString
+}
+
+//String shows the report as tables
+func (s Stats) String() string {
+	stringString := fmt.Sprintf("Cookbook statistics for %s\n%d recipes, made %d times\n\n", s.Date, s.Recipes,
+		s.Made)
+	stringString += recipeCountsString("Most made", s.MostMade) + "\n"
+	stringString += recipeCountsString("Least made", s.LeastMade) + "\n"
+	stringString += recipeCountsString(fmt.Sprintf("Not made in %d months", s.StaleMonths), s.NotMade) + "\n"
+
+	stringString += "Most used ingredients:\n"
+	if len(s.Ingredients) == 0 {
+		stringString += "\tnone\n"
+	}
+	for _, use := range s.Ingredients {
+		stringString += fmt.Sprintf("\t%-30s %3d recipes, cooked %d times\n", use.Name, use.Recipes, use.Cooked)
+	}
+
+	stringString += "\nAverage time by tag:\n"
+	if len(s.TagTimes) == 0 {
+		stringString += "\tno timed recipes\n"
+	}
+	for _, tagTime := range s.TagTimes {
+		stringString += fmt.Sprintf("\t%-30s %3d recipes, %s\n", tagTime.Tag, tagTime.Recipes,
+			(time.Duration(tagTime.AverageMinutes) * time.Minute).String())
+	}
+
+	stringString += fmt.Sprintf("\nInventory value: %s", FormatMoney(FractionFromFloat(s.InventoryValue)))
+	if s.Unpriced > 0 {
+		stringString += fmt.Sprintf(", %d items not priced", s.Unpriced)
+	}
+	stringString += fmt.Sprintf("\nTurnover over the last %d days:\n", s.PeriodDays)
+	if len(s.Items) == 0 {
+		stringString += "\tnothing on hand\n"
+	}
+	for _, item := range s.Items {
+		value := ""
+		if item.Value > 0 {
+			value = FormatMoney(FractionFromFloat(item.Value))
+		}
+		stringString += fmt.Sprintf("\t%-30s %7.2f on hand %9s  used %6.2f  turnover %.2f\n",
+			strings.TrimSpace(item.Name), item.OnHand, value, item.Used, item.Turnover)
+	}
+	return stringString
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	backend "github.com/sww1235/recipe-database"
+)
+
+//collectStats reads the recipes, journal and inventory and builds the
+//statistics report for options
+func collectStats(db *sql.DB, options backend.StatsOptions) (backend.Stats, error) {
+	if options.Now.IsZero() {
+		options.Now = today()
+	}
+	recipes, err := selectAllRecipes(db)
+	if err != nil {
+		return backend.Stats{}, err
+	}
+	journal, err := selectJournal(db, "")
+	if err != nil {
+		return backend.Stats{}, err
+	}
+	inventory, err := searchInventory(db, "")
+	if err != nil {
+		return backend.Stats{}, err
+	}
+	prices, err := selectPriceHistories(db)
+	if err != nil {
+		return backend.Stats{}, err
+	}
+	period := options.PeriodDays
+	if period <= 0 {
+		period = 30
+	}
+	usage, err := selectUsage(db, options.Now.AddDate(0, 0, -period))
+	if err != nil {
+		return backend.Stats{}, err
+	}
+	return backend.ComputeStats(recipes, journal, inventory, prices, usage, options), nil
+}
+
+//statsString shows a statistics report as a table or as JSON
+func statsString(stats backend.Stats, format string) (string, error) {
+	switch format {
+	case "table", "text":
+		return stats.String(), nil
+	case "json":
+		bytes, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(bytes) + "\n", nil
+	}
+	return "", fmt.Errorf("unknown stats format %s, expected table or json", format)
+}
+
+//displayStats prints the statistics report for the -stats flag
+func displayStats(db *sql.DB, format string, staleMonths int) error {
+	stats, err := collectStats(db, backend.StatsOptions{StaleMonths: staleMonths})
+	if err != nil {
+		return err
+	}
+	report, err := statsString(stats, format)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+	return nil
+}
+
+//statsHTTP shows the statistics report. Query parameters are months, for
+//recipes not made in that many months, top, days and format.
+func statsHTTP(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var options backend.StatsOptions
+	for name, value := range map[string]*int{"months": &options.StaleMonths, "top": &options.Top,
+		"days": &options.PeriodDays} {
+		if query.Get(name) == "" {
+			continue
+		}
+		number, err := strconv.Atoi(query.Get(name))
+		if err != nil {
+			http.Error(w, name+" must be a number", http.StatusBadRequest)
+			return
+		}
+		*value = number
+	}
+	stats, err := collectStats(db, options)
+	if err != nil {
+		infoLogger.Println("Error collecting statistics:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if wantsJSON(r) {
+		writeJSON(w, stats)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, stats.String())
+}
+
+//statsState is the open statistics screen of the CUI
+type statsState struct {
+	db      *sql.DB
+	options backend.StatsOptions
+	report  string // statistics for options
+}
+
+//refresh builds the report again after the options change
+func (s *statsState) refresh() {
+	stats, err := collectStats(s.db, s.options)
+	if err != nil {
+		s.report = fmt.Sprintln("Error collecting statistics:", err)
+		return
+	}
+	s.report = stats.String()
+}
+
+//statsScreen is the open statistics screen, nil when it is closed
+var statsScreen *statsState
+
+//statistics view names
+const (
+	statsMonthsView = "statsMonths"
+	statsReportView = "statsReport"
+)
+
+//openStats shows the statistics report over the main view
+func openStats(gui *gocui.Gui, db *sql.DB) error {
+	if screenOpen() {
+		return nil
+	}
+	statsScreen = &statsState{db: db}
+	statsScreen.refresh()
+	if err := statsLayout(gui); err != nil {
+		return err
+	}
+	bindings := []struct {
+		key     interface{}
+		handler func(*gocui.Gui, *gocui.View) error
+	}{
+		{gocui.KeyEnter, statsApplyMonths},
+		{gocui.KeyArrowDown, statsScrollDown},
+		{gocui.KeyArrowUp, statsScrollUp},
+		{gocui.KeyEsc, closeStats},
+	}
+	for _, binding := range bindings {
+		if err := gui.SetKeybinding(statsMonthsView, binding.key, gocui.ModNone, binding.handler); err != nil {
+			return err
+		}
+	}
+	gui.Cursor = true
+	_, err := gui.SetCurrentView(statsMonthsView)
+	return err
+}
+
+//statsLayout creates or resizes the statistics views
+func statsLayout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	monthsView, err := gui.SetView(statsMonthsView, 0, 0, maxX-1, 2, 0)
+	if err != nil {
+		if !gocui.IsUnknownView(err) {
+			return err
+		}
+		monthsView.Editable = true
+		monthsView.Title = "List recipes not made in this many months, default 6"
+	}
+
+	reportView, err := gui.SetView(statsReportView, 0, 3, maxX-1, maxY-3, 0)
+	if err != nil {
+		if !gocui.IsUnknownView(err) {
+			return err
+		}
+	}
+	reportView.Title = "Cookbook statistics"
+	reportView.Clear()
+	fmt.Fprint(reportView, statsScreen.report)
+	return nil
+}
+
+//statsApplyMonths lists the recipes not made in the number of months typed
+//in
+func statsApplyMonths(gui *gocui.Gui, view *gocui.View) error {
+	text := strings.TrimSpace(viewText(view))
+	statsScreen.options.StaleMonths = 0
+	if text != "" {
+		months, err := strconv.Atoi(text)
+		if err != nil || months < 1 {
+			statsScreen.report = fmt.Sprintf("Invalid number of months %q\n", text)
+			return statsLayout(gui)
+		}
+		statsScreen.options.StaleMonths = months
+	}
+	statsScreen.refresh()
+	return statsLayout(gui)
+}
+
+//statsScrollDown and statsScrollUp move through a report longer than the
+//screen
+func statsScrollDown(gui *gocui.Gui, _ *gocui.View) error {
+	return scrollView(gui, statsReportView, 1)
+}
+
+func statsScrollUp(gui *gocui.Gui, _ *gocui.View) error {
+	return scrollView(gui, statsReportView, -1)
+}
+
+//scrollView moves the origin of a view by lines, stopping at the top
+func scrollView(gui *gocui.Gui, name string, lines int) error {
+	view, err := gui.View(name)
+	if err != nil {
+		return err
+	}
+	x, y := view.Origin()
+	if y+lines < 0 {
+		return nil
+	}
+	return view.SetOrigin(x, y+lines)
+}
+
+//closeStats returns to the main view
+func closeStats(gui *gocui.Gui, _ *gocui.View) error {
+	for _, name := range []string{statsMonthsView, statsReportView} {
+		gui.DeleteKeybindings(name)
+		if err := gui.DeleteView(name); err != nil {
+			return err
+		}
+	}
+	statsScreen = nil
+	gui.Cursor = false
+	_, err := gui.SetCurrentView("main")
+	return err
+}
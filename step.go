@@ -18,6 +18,18 @@ const (
 	Other
 )
 
+func (s StepType) String() string {
+	switch s {
+	case Prep:
+		return "Prep"
+	case Cook:
+		return "Cook"
+	case Wait:
+		return "Wait"
+	}
+	return "Other"
+}
+
 type Step struct {
 	TimeNeeded   time.Duration
 	StepType     StepType
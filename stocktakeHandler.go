@@ -0,0 +1,546 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	backend "github.com/sww1235/recipe-database"
+)
+
+//stocktakeEntry is the change to one inventory item during a stocktake
+type stocktakeEntry struct {
+	Item   backend.InventoryItem // item as it was in the database, or the new item
+	New    bool                  // item was created this session
+	Change backend.Fraction      // packages added, negative when used
+}
+
+//stocktakeScan is one scan in the session log
+type stocktakeScan struct {
+	GTIN   string
+	Change backend.Fraction
+}
+
+//stocktakeSession holds every scan of a stocktake until it is committed to
+//the database or discarded
+type stocktakeSession struct {
+	db       *sql.DB
+	useMode  bool                       // scans use a package instead of adding one
+	entries  map[string]*stocktakeEntry // keyed by normalized barcode
+	order    []string                   // barcodes in the order first scanned
+	scans    []stocktakeScan
+	creating string // barcode being entered in the quick create form
+	message  string // result of the last action
+}
+
+//stocktake is the open stocktake session, nil when the screen is closed
+var stocktake *stocktakeSession
+
+//stocktake view names
+const (
+	stocktakeInputView   = "stocktakeInput"
+	stocktakeLogView     = "stocktakeLog"
+	stocktakeSummaryView = "stocktakeSummary"
+	stocktakeNameView    = "stocktakeName"
+	stocktakePackageView = "stocktakePackage"
+	stocktakeDescView    = "stocktakeDesc"
+)
+
+//stocktakeFormViews are the fields of the quick create form, in tab order
+var stocktakeFormViews = []string{stocktakeNameView, stocktakePackageView, stocktakeDescView}
+
+func newStocktakeSession(db *sql.DB) *stocktakeSession {
+	return &stocktakeSession{db: db, entries: make(map[string]*stocktakeEntry)}
+}
+
+//scanChange is the number of packages one scan adds in the current mode
+func (s *stocktakeSession) scanChange() backend.Fraction {
+	if s.useMode {
+		return backend.NewFraction(-1, 1)
+	}
+	return backend.NewFraction(1, 1)
+}
+
+//scan records one scan of code. Returns the normalized barcode and false if
+//the code is not in inventory and needs to be created first.
+func (s *stocktakeSession) scan(code string) (string, bool, error) {
+	gtin, err := backend.NormalizeGTIN(code)
+	if err != nil {
+		return "", false, err
+	}
+	entry, ok := s.entries[gtin]
+	if !ok {
+		items, err := selectInventory(s.db, "WHERE inventory.EAN = ?", gtin)
+		if err != nil {
+			return gtin, false, err
+		}
+		if len(items) == 0 {
+			return gtin, false, nil
+		}
+		entry = &stocktakeEntry{Item: items[0]}
+		s.entries[gtin] = entry
+		s.order = append(s.order, gtin)
+	}
+	s.apply(gtin, s.scanChange())
+	return gtin, true, nil
+}
+
+//create adds an item that was not in inventory, and applies the scan that
+//found it
+func (s *stocktakeSession) create(item backend.InventoryItem) {
+	item.Quantity = backend.Fraction{}
+	s.entries[item.EAN] = &stocktakeEntry{Item: item, New: true}
+	s.order = append(s.order, item.EAN)
+	s.apply(item.EAN, s.scanChange())
+}
+
+func (s *stocktakeSession) apply(gtin string, change backend.Fraction) {
+	entry := s.entries[gtin]
+	entry.Change = entry.Change.Add(change)
+	s.scans = append(s.scans, stocktakeScan{gtin, change})
+	s.message = fmt.Sprintf("%s: %s", entry.Item.Name, entry.result())
+}
+
+//undo removes the last scan from the session
+func (s *stocktakeSession) undo() {
+	if len(s.scans) == 0 {
+		s.message = "Nothing to undo"
+		return
+	}
+	last := s.scans[len(s.scans)-1]
+	s.scans = s.scans[:len(s.scans)-1]
+	entry := s.entries[last.GTIN]
+	entry.Change = entry.Change.Sub(last.Change)
+	s.message = fmt.Sprintf("Undid scan of %s", entry.Item.Name)
+	// an item created this session is dropped with its last scan
+	if entry.New && !s.scanned(last.GTIN) {
+		delete(s.entries, last.GTIN)
+		for i, gtin := range s.order {
+			if gtin == last.GTIN {
+				s.order = append(s.order[:i], s.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+//scanned reports whether gtin has any scans left in the session
+func (s *stocktakeSession) scanned(gtin string) bool {
+	for _, scan := range s.scans {
+		if scan.GTIN == gtin {
+			return true
+		}
+	}
+	return false
+}
+
+//result describes the packages of an entry before and after the session
+func (e stocktakeEntry) result() string {
+	after := e.Item.Quantity.Add(e.Change)
+	if after.Sign() < 0 {
+		after = backend.Fraction{}
+	}
+	sign := ""
+	if e.Change.Sign() >= 0 {
+		sign = "+"
+	}
+	stringString := fmt.Sprintf("%s%s (%s → %s packages)", sign, e.Change.String(),
+		e.Item.Quantity.DecimalString(2), after.DecimalString(2))
+	if e.New {
+		stringString += " new"
+	}
+	return stringString
+}
+
+//commit applies every change in the session to the database in one
+//transaction and clears the session. Quantities never go below zero.
+func (s *stocktakeSession) commit() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, gtin := range s.order {
+		entry := s.entries[gtin]
+		if entry.New {
+			item := entry.Item
+			if entry.Change.Sign() > 0 {
+				item.AddLot(backend.InventoryLot{Quantity: entry.Change, Purchased: today()})
+			}
+			if _, err = insertInventoryItemTx(tx, item); err != nil {
+				tx.Rollback()
+				return err
+			}
+			continue
+		}
+		if entry.Change.IsZero() {
+			continue
+		}
+		// read the item again in case it changed since the first scan
+		items, err := selectInventory(tx, "WHERE inventory.id = ?", entry.Item.ID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if len(items) == 0 {
+			tx.Rollback()
+			return fmt.Errorf("%s was removed from inventory", entry.Item.Name)
+		}
+		item := items[0]
+		// scanning in packages is a purchase, scanning them out uses the
+		// first expiring
+		if entry.Change.Sign() > 0 {
+			item.AddLot(backend.InventoryLot{Quantity: entry.Change, Purchased: today()})
+		} else {
+			quantity := item.Quantity.Add(entry.Change)
+			if quantity.Sign() < 0 {
+				quantity = backend.Fraction{}
+			}
+			usage := backend.UsageRecord{Date: today(), Packages: item.Quantity.Sub(quantity), Reason: "stocktake"}
+			item.SetQuantity(quantity)
+			if usage.Packages.Sign() > 0 {
+				if err = recordUsageTx(tx, item.ID, usage, 0); err != nil {
+					tx.Rollback()
+					return err
+				}
+			}
+		}
+		if err = saveInventoryItemTx(tx, &item); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	s.message = fmt.Sprintf("Committed %d scans of %d items", len(s.scans), len(s.order))
+	s.clear()
+	return nil
+}
+
+//discard throws away every scan in the session
+func (s *stocktakeSession) discard() {
+	s.message = fmt.Sprintf("Discarded %d scans", len(s.scans))
+	s.clear()
+}
+
+func (s *stocktakeSession) clear() {
+	s.entries = make(map[string]*stocktakeEntry)
+	s.order = nil
+	s.scans = nil
+}
+
+//logString lists every scan of the session, oldest first
+func (s *stocktakeSession) logString() string {
+	stringString := ""
+	for _, scan := range s.scans {
+		action := "added"
+		if scan.Change.Sign() < 0 {
+			action = "used"
+		}
+		stringString += fmt.Sprintf("%s %s %s\n", scan.GTIN, action, s.entries[scan.GTIN].Item.Name)
+	}
+	return stringString
+}
+
+//summaryString lists the net change to each item scanned
+func (s *stocktakeSession) summaryString() string {
+	stringString := ""
+	if s.message != "" {
+		stringString += s.message + "\n\n"
+	}
+	for _, gtin := range s.order {
+		entry := s.entries[gtin]
+		stringString += fmt.Sprintf("%s: %s\n", entry.Item.Name, entry.result())
+	}
+	return stringString
+}
+
+//openStocktake shows the stocktake screen over the main view
+func openStocktake(gui *gocui.Gui, db *sql.DB) error {
+	if screenOpen() {
+		return nil
+	}
+	stocktake = newStocktakeSession(db)
+	if err := stocktakeLayout(gui); err != nil {
+		return err
+	}
+	bindings := []struct {
+		key     interface{}
+		handler func(*gocui.Gui, *gocui.View) error
+	}{
+		{gocui.KeyEnter, stocktakeScanKey},
+		{gocui.KeyCtrlT, stocktakeToggleMode},
+		{gocui.KeyCtrlU, stocktakeUndo},
+		{gocui.KeyCtrlS, stocktakeCommit},
+		{gocui.KeyCtrlD, stocktakeDiscard},
+		{gocui.KeyEsc, closeStocktake},
+	}
+	for _, binding := range bindings {
+		if err := gui.SetKeybinding(stocktakeInputView, binding.key, gocui.ModNone, binding.handler); err != nil {
+			return err
+		}
+	}
+	gui.Cursor = true
+	_, err := gui.SetCurrentView(stocktakeInputView)
+	return err
+}
+
+//stocktakeLayout creates or resizes the stocktake views
+func stocktakeLayout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	half := maxX / 2
+
+	inputView, err := gui.SetView(stocktakeInputView, 0, 0, maxX-1, 2, 0)
+	if err != nil {
+		if !gocui.IsUnknownView(err) {
+			return err
+		}
+		inputView.Editable = true
+	}
+	if stocktake.useMode {
+		inputView.Title = "Scan barcode: use mode"
+	} else {
+		inputView.Title = "Scan barcode: add mode"
+	}
+
+	logView, err := gui.SetView(stocktakeLogView, 0, 3, half-1, maxY-3, 0)
+	if err != nil {
+		if !gocui.IsUnknownView(err) {
+			return err
+		}
+		logView.Title = "Session log"
+		logView.Autoscroll = true
+	}
+	logView.Clear()
+	fmt.Fprint(logView, stocktake.logString())
+
+	summaryView, err := gui.SetView(stocktakeSummaryView, half, 3, maxX-1, maxY-3, 0)
+	if err != nil {
+		if !gocui.IsUnknownView(err) {
+			return err
+		}
+		summaryView.Title = "Changes"
+		summaryView.Wrap = true
+	}
+	summaryView.Clear()
+	fmt.Fprint(summaryView, stocktake.summaryString())
+
+	if stocktake.creating != "" {
+		return stocktakeFormLayout(gui)
+	}
+	return nil
+}
+
+//stocktakeFormLayout creates or resizes the quick create form
+func stocktakeFormLayout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	titles := []string{"Name", "Package size, ie: 5 lb", "Description"}
+	top := maxY/2 - 5
+	for i, name := range stocktakeFormViews {
+		view, err := gui.SetView(name, maxX/4, top+i*3, maxX*3/4, top+i*3+2, 0)
+		if err != nil {
+			if !gocui.IsUnknownView(err) {
+				return err
+			}
+			view.Editable = true
+			view.Title = titles[i]
+			if i == 0 {
+				view.Title = "New item " + stocktake.creating + ": " + view.Title
+			}
+		}
+		if _, err = gui.SetViewOnTop(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//viewText returns the text typed into an editable view
+func viewText(view *gocui.View) string {
+	return strings.TrimSpace(view.Buffer())
+}
+
+//clearInput empties an editable view ready for the next entry
+func clearInput(view *gocui.View) error {
+	view.Clear()
+	if err := view.SetOrigin(0, 0); err != nil {
+		return err
+	}
+	return view.SetCursor(0, 0)
+}
+
+func stocktakeScanKey(gui *gocui.Gui, view *gocui.View) error {
+	code := viewText(view)
+	if err := clearInput(view); err != nil {
+		return err
+	}
+	if code == "" {
+		return nil
+	}
+	gtin, found, err := stocktake.scan(code)
+	if err != nil {
+		stocktake.message = err.Error()
+		return stocktakeLayout(gui)
+	}
+	if !found {
+		return openStocktakeForm(gui, gtin)
+	}
+	return stocktakeLayout(gui)
+}
+
+func stocktakeToggleMode(gui *gocui.Gui, _ *gocui.View) error {
+	stocktake.useMode = !stocktake.useMode
+	return stocktakeLayout(gui)
+}
+
+func stocktakeUndo(gui *gocui.Gui, _ *gocui.View) error {
+	stocktake.undo()
+	return stocktakeLayout(gui)
+}
+
+func stocktakeCommit(gui *gocui.Gui, _ *gocui.View) error {
+	if err := stocktake.commit(); err != nil {
+		stocktake.message = "Commit failed: " + err.Error()
+	}
+	return stocktakeLayout(gui)
+}
+
+func stocktakeDiscard(gui *gocui.Gui, _ *gocui.View) error {
+	stocktake.discard()
+	return stocktakeLayout(gui)
+}
+
+//closeStocktake returns to the main view. Uncommitted scans must be
+//committed or discarded first.
+func closeStocktake(gui *gocui.Gui, _ *gocui.View) error {
+	if len(stocktake.scans) > 0 {
+		stocktake.message = "Commit (^S) or discard (^D) this session before leaving"
+		return stocktakeLayout(gui)
+	}
+	for _, name := range []string{stocktakeInputView, stocktakeLogView, stocktakeSummaryView} {
+		gui.DeleteKeybindings(name)
+		if err := gui.DeleteView(name); err != nil {
+			return err
+		}
+	}
+	stocktake = nil
+	gui.Cursor = false
+	_, err := gui.SetCurrentView("main")
+	return err
+}
+
+//openStocktakeForm shows the quick create form for a barcode that is not in
+//inventory, filled in from the product catalog when possible
+func openStocktakeForm(gui *gocui.Gui, gtin string) error {
+	stocktake.creating = gtin
+	if err := stocktakeFormLayout(gui); err != nil {
+		return err
+	}
+	product, found, err := lookupProduct(stocktake.db, gtin)
+	if err != nil {
+		return err
+	}
+	stocktake.message = "Unknown barcode " + gtin
+	if found {
+		stocktake.message += ", filled in from product catalog"
+		values := []string{product.Name, "", product.Description}
+		if !product.PackageQuantity.IsZero() {
+			values[1] = product.PackageQuantity.String()
+		}
+		for i, name := range stocktakeFormViews {
+			view, err := gui.View(name)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(view, values[i])
+			// leave the cursor after the text, or at the edge of the view
+			// for long product names
+			width, _ := view.Size()
+			cursor := len([]rune(values[i]))
+			if cursor >= width {
+				cursor = width - 1
+			}
+			if err = view.SetCursor(cursor, 0); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range stocktakeFormViews {
+		bindings := []struct {
+			key     interface{}
+			handler func(*gocui.Gui, *gocui.View) error
+		}{
+			{gocui.KeyTab, stocktakeFormNext},
+			{gocui.KeyEnter, stocktakeFormSave},
+			{gocui.KeyEsc, closeStocktakeForm},
+		}
+		for _, binding := range bindings {
+			if err := gui.SetKeybinding(name, binding.key, gocui.ModNone, binding.handler); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err = gui.SetCurrentView(stocktakeNameView); err != nil {
+		return err
+	}
+	return stocktakeLayout(gui)
+}
+
+//stocktakeFormNext moves to the next field of the quick create form
+func stocktakeFormNext(gui *gocui.Gui, view *gocui.View) error {
+	next := 0
+	for i, name := range stocktakeFormViews {
+		if name == view.Name() {
+			next = (i + 1) % len(stocktakeFormViews)
+		}
+	}
+	_, err := gui.SetCurrentView(stocktakeFormViews[next])
+	return err
+}
+
+//stocktakeFormSave adds the item from the quick create form to the session
+func stocktakeFormSave(gui *gocui.Gui, _ *gocui.View) error {
+	var values []string
+	for _, name := range stocktakeFormViews {
+		view, err := gui.View(name)
+		if err != nil {
+			return err
+		}
+		values = append(values, viewText(view))
+	}
+	item := backend.InventoryItem{EAN: stocktake.creating, Name: values[0], Description: values[2]}
+	if item.Name == "" {
+		stocktake.message = "New item needs a name"
+		return stocktakeLayout(gui)
+	}
+	if values[1] != "" {
+		var err error
+		item.PackageQuantity, err = backend.ParseQuantity(values[1])
+		if err != nil {
+			stocktake.message = "Invalid package size: " + err.Error()
+			return stocktakeLayout(gui)
+		}
+	}
+	stocktake.create(item)
+	return closeStocktakeForm(gui, nil)
+}
+
+//closeStocktakeForm removes the quick create form and returns to scanning
+func closeStocktakeForm(gui *gocui.Gui, _ *gocui.View) error {
+	if stocktake.creating == "" {
+		return errors.New("quick create form is not open")
+	}
+	for _, name := range stocktakeFormViews {
+		gui.DeleteKeybindings(name)
+		if err := gui.DeleteView(name); err != nil {
+			return err
+		}
+	}
+	stocktake.creating = ""
+	if _, err := gui.SetCurrentView(stocktakeInputView); err != nil {
+		return err
+	}
+	return stocktakeLayout(gui)
+}
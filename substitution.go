@@ -0,0 +1,175 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"strings"
+)
+
+//A Substitution replaces an amount of one ingredient with a set of other
+//ingredients, such as 1 cup of buttermilk with 1 cup of milk and 1 tbsp of
+//lemon juice. An ingredient can have several substitutions.
+type Substitution struct {
+	ID          int          // id of substitution in database
+	Ingredient  string       // ingredient replaced
+	Quantity    Quantity     // amount of ingredient the replacements are for
+	Replacement []Ingredient // ingredients to use instead
+	Notes       string       // how to use the replacement, ie: let stand 5 minutes
+}
+
+func (s Substitution) String() string {
+	stringString := fmt.Sprintf("%s %s = %s", s.Quantity.Format(), s.Ingredient,
+		replacementString(s.Replacement))
+	if s.Notes != "" {
+		stringString += " (" + s.Notes + ")"
+	}
+	return stringString
+}
+
+//replacementString lists replacement ingredients as "1 cup milk + 1 tbsp
+//lemon juice"
+func replacementString(replacement []Ingredient) string {
+	var parts []string
+	for _, ingredient := range replacement {
+		if ingredient.QuantityNeeded.IsZero() {
+			parts = append(parts, ingredient.Name)
+			continue
+		}
+		parts = append(parts, ingredient.QuantityNeeded.Format()+" "+ingredient.Name)
+	}
+	return strings.Join(parts, " + ")
+}
+
+//Matches reports whether the substitution replaces the ingredient name
+func (s Substitution) Matches(name string) bool {
+	return CanonicalName(s.Ingredient) == CanonicalName(name)
+}
+
+//For returns the replacement ingredients scaled to replace amount of the
+//ingredient. amount must be convertable to the unit of the substitution,
+//directly or through the density of the ingredient.
+func (s Substitution) For(amount Quantity) ([]Ingredient, error) {
+	converted, err := Ingredient{Name: s.Ingredient, QuantityNeeded: amount}.convertQuantity(s.Quantity.Unit)
+	if err != nil {
+		return nil, err
+	}
+	if s.Quantity.Amount.IsZero() {
+		return nil, fmt.Errorf("substitution for %s has no quantity", s.Ingredient)
+	}
+	factor := converted.Div(s.Quantity.Amount)
+	var scaled []Ingredient
+	for _, ingredient := range s.Replacement {
+		ingredient.QuantityNeeded = ingredient.QuantityNeeded.Mul(factor).Normalize()
+		scaled = append(scaled, ingredient)
+	}
+	return scaled, nil
+}
+
+//SubstitutionsFor returns every substitution for the ingredient name
+func SubstitutionsFor(name string, substitutions []Substitution) []Substitution {
+	var found []Substitution
+	for _, substitution := range substitutions {
+		if substitution.Matches(name) {
+			found = append(found, substitution)
+		}
+	}
+	return found
+}
+
+//A UsedSubstitute is a substitution chosen for part of an ingredient that
+//is not on hand
+type UsedSubstitute struct {
+	Substitution Substitution
+	Replacement  []Ingredient // replacement scaled to the amount substituted
+}
+
+func (u UsedSubstitute) String() string {
+	stringString := "use " + replacementString(u.Replacement)
+	if u.Substitution.Notes != "" {
+		stringString += " (" + u.Substitution.Notes + ")"
+	}
+	return stringString
+}
+
+//findSubstitute returns the first substitution for amount of the ingredient
+//name where available reports every replacement ingredient is on hand
+func findSubstitute(name string, amount Quantity, substitutions []Substitution,
+	available func(Ingredient) bool) (UsedSubstitute, bool) {
+	for _, substitution := range SubstitutionsFor(name, substitutions) {
+		replacement, err := substitution.For(amount)
+		if err != nil {
+			continue
+		}
+		usable := true
+		for _, ingredient := range replacement {
+			if !available(ingredient) {
+				usable = false
+				break
+			}
+		}
+		if usable {
+			return UsedSubstitute{substitution, replacement}, true
+		}
+	}
+	return UsedSubstitute{}, false
+}
+
+//RecipeSubstitutions lists the substitutions for each ingredient of a
+//recipe, scaled to the amount the recipe uses
+func RecipeSubstitutions(recipe Recipe, substitutions []Substitution) string {
+	stringString := ""
+	for _, ingredient := range recipe.Ingredients {
+		for _, substitution := range SubstitutionsFor(ingredient.Name, substitutions) {
+			replacement, err := substitution.For(ingredient.QuantityNeeded)
+			if err != nil {
+				continue
+			}
+			stringString += fmt.Sprintf("\t%s %s: use %s", ingredient.QuantityNeeded.Format(),
+				ingredient.Name, replacementString(replacement))
+			if substitution.Notes != "" {
+				stringString += " (" + substitution.Notes + ")"
+			}
+			stringString += "\n"
+		}
+	}
+	if stringString == "" {
+		return ""
+	}
+	return "Substitutions: \n" + stringString
+}
+
+//sub builds a substitution for DefaultSubstitutions
+func sub(quantity string, ingredient string, notes string, replacement ...string) Substitution {
+	substitution := Substitution{Ingredient: ingredient, Notes: notes}
+	substitution.Quantity, _ = ParseQuantity(quantity)
+	for i := 0; i+1 < len(replacement); i += 2 {
+		amount, _ := ParseQuantity(replacement[i])
+		substitution.Replacement = append(substitution.Replacement,
+			Ingredient{Name: replacement[i+1], QuantityNeeded: amount})
+	}
+	return substitution
+}
+
+//DefaultSubstitutions are common kitchen substitutions, used to seed a new
+//database
+var DefaultSubstitutions = []Substitution{
+	sub("1 cup", "buttermilk", "let stand 5 minutes", "1 cup", "milk", "1 tbsp", "lemon juice"),
+	sub("1 cup", "buttermilk", "let stand 5 minutes", "1 cup", "milk", "1 tbsp", "white vinegar"),
+	sub("1 cup", "buttermilk", "", "3/4 cup", "plain yogurt", "1/4 cup", "milk"),
+	sub("1 tsp", "baking powder", "", "1/4 tsp", "baking soda", "1/2 tsp", "cream of tartar"),
+	sub("1 cup", "cake flour", "sift together", "14 tbsp", "all purpose flour", "2 tbsp", "cornstarch"),
+	sub("1 cup", "self-rising flour", "", "1 cup", "all purpose flour", "1 1/2 tsp", "baking powder",
+		"1/4 tsp", "salt"),
+	sub("1", "egg", "for baking, let stand 5 minutes", "1 tbsp", "ground flaxseed", "3 tbsp", "water"),
+	sub("1 cup", "heavy cream", "will not whip", "3/4 cup", "milk", "1/4 cup", "butter"),
+	sub("1 cup", "brown sugar", "", "1 cup", "sugar", "1 tbsp", "molasses"),
+	sub("1 cup", "powdered sugar", "blend until fine", "1 cup", "sugar", "1 tbsp", "cornstarch"),
+	sub("1 cup", "sour cream", "", "1 cup", "plain yogurt"),
+	sub("1 cup", "milk", "", "1/2 cup", "evaporated milk", "1/2 cup", "water"),
+	sub("1 cup", "butter", "for cooking, not for creaming", "3/4 cup", "vegetable oil"),
+	sub("1 oz", "unsweetened chocolate", "", "3 tbsp", "cocoa powder", "1 tbsp", "butter"),
+	sub("1 tbsp", "cornstarch", "for thickening", "2 tbsp", "all purpose flour"),
+	sub("1 cup", "honey", "", "1 1/4 cup", "sugar", "1/4 cup", "water"),
+	sub("1 tbsp", "lemon juice", "", "1/2 tbsp", "white vinegar"),
+	sub("1 clove", "garlic", "", "1/8 tsp", "garlic powder"),
+	sub("1 tbsp", "fresh herbs", "", "1 tsp", "dried herbs"),
+}
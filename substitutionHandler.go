@@ -0,0 +1,217 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+const substitutionUsage = `usage: cookbook subs <command> [arguments]
+
+commands:
+	list [ingredient]   list every substitution, or those for ingredient
+	add [-notes <notes>] <amount ingredient> <amount replacement> [<amount replacement> ...]
+		add a substitution, ie: add -notes "let stand 5 minutes" "1 cup buttermilk"
+		"1 cup milk" "1 tbsp lemon juice"
+	delete <id>         delete a substitution
+
+Substitutions are used by canmake and shop when an ingredient is not on hand
+but its replacements are.
+`
+
+//substitutionCommand runs the subs subcommand given on the command line
+func substitutionCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		fmt.Print(substitutionUsage)
+		return errors.New("no subs command given")
+	}
+	switch args[0] {
+	case "list":
+		substitutions, err := selectSubstitutions(db)
+		if err != nil {
+			return err
+		}
+		if len(args) > 1 {
+			substitutions = backend.SubstitutionsFor(args[1], substitutions)
+		}
+		for _, substitution := range substitutions {
+			fmt.Printf("%d: %s\n", substitution.ID, substitution)
+		}
+		return nil
+	case "add":
+		flags := flag.NewFlagSet("subs add", flag.ContinueOnError)
+		notes := flags.String("notes", "", "How to use the replacement")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() < 2 {
+			fmt.Print(substitutionUsage)
+			return errors.New("subs add needs an ingredient and at least one replacement")
+		}
+		replaced, err := backend.ParseIngredient(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		substitution := backend.Substitution{Ingredient: replaced.Name,
+			Quantity: replaced.QuantityNeeded, Notes: *notes}
+		if substitution.Quantity.IsZero() {
+			return errors.New("substitution needs the amount of " + replaced.Name + " it replaces")
+		}
+		for _, arg := range flags.Args()[1:] {
+			replacement, err := backend.ParseIngredient(arg)
+			if err != nil {
+				return err
+			}
+			substitution.Replacement = append(substitution.Replacement, replacement)
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		id, err := insertSubstitutionTx(tx, substitution)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		substitution.ID = int(id)
+		fmt.Printf("%d: %s\n", substitution.ID, substitution)
+		return nil
+	case "delete":
+		if len(args) < 2 {
+			return errors.New("usage: subs delete <id>")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid substitution id %q", args[1])
+		}
+		return deleteSubstitution(db, id)
+	}
+	fmt.Print(substitutionUsage)
+	return errors.New("unknown subs command " + args[0])
+}
+
+//selectSubstitutions reads every substitution and its replacement
+//ingredients from the database
+func selectSubstitutions(db *sql.DB) ([]backend.Substitution, error) {
+	rows, err := db.Query("SELECT substitutions.id, substitutions.ingredient, substitutions.quantity, " +
+		"IFNULL(units.name, ''), IFNULL(substitutions.notes, '') FROM substitutions " +
+		"LEFT JOIN units ON substitutions.quantityUnits = units.id ORDER BY substitutions.ingredient, " +
+		"substitutions.id")
+	if err != nil {
+		return nil, err
+	}
+	var substitutions []backend.Substitution
+	index := make(map[int]int)
+	for rows.Next() {
+		var substitution backend.Substitution
+		err = rows.Scan(&substitution.ID, &substitution.Ingredient, &substitution.Quantity.Amount,
+			&substitution.Quantity.Unit, &substitution.Notes)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[substitution.ID] = len(substitutions)
+		substitutions = append(substitutions, substitution)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query("SELECT substitutionIngredients.substitutionID, substitutionIngredients.name, " +
+		"substitutionIngredients.quantity, IFNULL(units.name, '') FROM substitutionIngredients " +
+		"LEFT JOIN units ON substitutionIngredients.quantityUnits = units.id " +
+		"ORDER BY substitutionIngredients.id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var substitutionID int
+		var ingredient backend.Ingredient
+		err = rows.Scan(&substitutionID, &ingredient.Name, &ingredient.QuantityNeeded.Amount,
+			&ingredient.QuantityNeeded.Unit)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[substitutionID]; ok {
+			substitutions[i].Replacement = append(substitutions[i].Replacement, ingredient)
+		}
+	}
+	return substitutions, rows.Err()
+}
+
+//insertSubstitutionTx stores a substitution and its replacement ingredients
+//as part of tx and returns its id
+func insertSubstitutionTx(tx *sql.Tx, substitution backend.Substitution) (int64, error) {
+	unit, err := unitID(tx, substitution.Quantity.Unit)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("INSERT INTO substitutions (ingredient, quantity, quantityUnits, notes) "+
+		"VALUES (?, ?, ?, ?)", substitution.Ingredient, substitution.Quantity.Amount, unit, substitution.Notes)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for _, ingredient := range substitution.Replacement {
+		unit, err := unitID(tx, ingredient.QuantityNeeded.Unit)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec("INSERT INTO substitutionIngredients (substitutionID, name, quantity, "+
+			"quantityUnits) VALUES (?, ?, ?, ?)", id, ingredient.Name, ingredient.QuantityNeeded.Amount, unit)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+//deleteSubstitution removes a substitution and its replacement ingredients
+func deleteSubstitution(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM substitutionIngredients WHERE substitutionID = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM substitutions WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count, err := result.RowsAffected(); err == nil && count == 0 {
+		tx.Rollback()
+		return fmt.Errorf("no substitution with id %d", id)
+	}
+	return tx.Commit()
+}
+
+//seedSubstitutions adds the default substitutions to a new database
+func seedSubstitutions(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, substitution := range backend.DefaultSubstitutions {
+		if _, err = insertSubstitutionTx(tx, substitution); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,289 @@
+package recipeDatabase
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+//SeasonalIngredients is when produce is in season in a temperate northern
+//hemisphere climate. Ingredients are matched by the words of their names,
+//so "fresh strawberries" is strawberry.
+var SeasonalIngredients = map[string][]time.Month{
+	"apple":            {time.August, time.September, time.October, time.November},
+	"apricot":          {time.June, time.July},
+	"asparagus":        {time.April, time.May, time.June},
+	"beet":             {time.July, time.August, time.September, time.October},
+	"blackberry":       {time.July, time.August, time.September},
+	"blueberry":        {time.June, time.July, time.August},
+	"brussels sprout":  {time.October, time.November, time.December, time.January},
+	"butternut squash": {time.September, time.October, time.November, time.December},
+	"cherry":           {time.June, time.July},
+	"corn":             {time.July, time.August, time.September},
+	"cranberry":        {time.October, time.November, time.December},
+	"cucumber":         {time.June, time.July, time.August},
+	"eggplant":         {time.July, time.August, time.September},
+	"fava bean":        {time.May, time.June},
+	"fig":              {time.August, time.September},
+	"green bean":       {time.June, time.July, time.August, time.September},
+	"kale":             {time.October, time.November, time.December, time.January, time.February},
+	"leek":             {time.October, time.November, time.December, time.January, time.February},
+	"parsnip":          {time.October, time.November, time.December, time.January, time.February},
+	"pea":              {time.May, time.June, time.July},
+	"peach":            {time.July, time.August},
+	"pear":             {time.August, time.September, time.October, time.November},
+	"plum":             {time.July, time.August, time.September},
+	"pumpkin":          {time.September, time.October, time.November},
+	"radish":           {time.April, time.May, time.June},
+	"raspberry":        {time.June, time.July, time.August},
+	"rhubarb":          {time.April, time.May, time.June},
+	"strawberry":       {time.May, time.June, time.July},
+	"sweet potato":     {time.September, time.October, time.November},
+	"tomato":           {time.July, time.August, time.September},
+	"zucchini":         {time.June, time.July, time.August},
+}
+
+//pantryQualifiers are words that make produce a pantry good kept all year,
+//so "tomato paste" and "frozen peas" are not seasonal
+var pantryQualifiers = []string{"canned", "dried", "frozen", "paste", "puree", "sauce", "starch", "syrup",
+	"vinegar", "juice", "cider", "jam", "preserves", "powder", "flour", "meal", "oil", "extract"}
+
+//InSeason reports whether the ingredient name is seasonal produce, and if
+//so whether it is in season in month. southern shifts the seasons by six
+//months for the southern hemisphere. Names with a pantry qualifier, such as
+//canned or paste, are not produce.
+func InSeason(name string, month time.Month, southern bool) (bool, bool) {
+	if southern {
+		month = (month+5)%12 + 1
+	}
+	words := nameWords(name)
+	for _, word := range words {
+		for _, qualifier := range pantryQualifiers {
+			if sameWord(word, qualifier) {
+				return false, false
+			}
+		}
+	}
+	// the longest match wins, so "sweet potato" is not just "potato"
+	best, bestLength := "", 0
+	for produce := range SeasonalIngredients {
+		length := len(nameWords(produce))
+		if length > bestLength && len(phraseSpans(words, produce)) > 0 {
+			best, bestLength = produce, length
+		}
+	}
+	if best == "" {
+		return false, false
+	}
+	for _, seasonal := range SeasonalIngredients[best] {
+		if seasonal == month {
+			return true, true
+		}
+	}
+	return true, false
+}
+
+//SuggestOptions filters and weighs the recipes suggested
+type SuggestOptions struct {
+	Tags     []string      // only suggest recipes with every one of these tags
+	Diets    []string      // only suggest recipes suiting every one of these diets
+	MaxTime  time.Duration // only suggest recipes taking at most this long in total, 0 for any
+	Staples  []string      // pantry staples counted as on hand, DefaultPantryStaples if nil
+	Southern bool          // use southern hemisphere seasons
+	Now      time.Time     // day to suggest recipes for
+}
+
+//A Suggestion is a recommended recipe and why it was recommended
+type Suggestion struct {
+	Recipe  Recipe   `json:"-"`
+	Name    string   `json:"name"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+func (s Suggestion) String() string {
+	stringString := s.Name + "\n"
+	for _, reason := range s.Reasons {
+		stringString += "\t" + reason + "\n"
+	}
+	return stringString
+}
+
+//How much each reason counts towards the score of a suggestion
+const (
+	weightNeverMade = 0.5  // never made, worth trying
+	weightLastMade  = 1.0  // not made in a long time, reached after 60 days
+	weightRecent    = -1.5 // made in the last week
+	weightRating    = 1.0  // per point of rating away from the middle
+	weightCoverage  = 1.5  // every ingredient on hand
+	weightExpiring  = 0.75 // per ingredient expiring soon, up to 3
+	weightInSeason  = 0.5  // per ingredient in season, up to 2
+	weightOutSeason = -0.5 // per ingredient out of season
+)
+
+//expiringDays is how soon a lot must expire to count as expiring soon
+const expiringDays = 7
+
+//SuggestRecipes scores every recipe matching options by how long since it
+//was made, its rating, how much of it is on hand, ingredients expiring soon
+//and produce in season, best first. journal is every time a recipe was
+//made, and inventory maps canonical ingredient names to inventory items.
+func SuggestRecipes(recipes []Recipe, journal Journal, inventory map[string][]InventoryItem,
+	options SuggestOptions) []Suggestion {
+	staples := make(map[string]bool)
+	names := options.Staples
+	if names == nil {
+		names = DefaultPantryStaples
+	}
+	for _, name := range names {
+		staples[CanonicalName(name)] = true
+	}
+	made := make(map[int]Journal)
+	for _, entry := range journal {
+		made[entry.RecipeID] = append(made[entry.RecipeID], entry)
+	}
+	expiring := options.Now.AddDate(0, 0, expiringDays+1)
+
+	var suggestions []Suggestion
+	for _, recipe := range recipes {
+		if !recipe.HasTags(options.Tags) || !recipe.SuitsDiets(options.Diets) {
+			continue
+		}
+		total := recipe.TotalTime()
+		if options.MaxTime > 0 && (total <= 0 || total > options.MaxTime) {
+			continue
+		}
+		suggestion := Suggestion{Recipe: recipe, Name: recipe.Name}
+		add := func(score float64, reason string, args ...interface{}) {
+			suggestion.Score += score
+			suggestion.Reasons = append(suggestion.Reasons, fmt.Sprintf(reason, args...))
+		}
+
+		history := made[recipe.ID]
+		if len(history) == 0 {
+			add(weightNeverMade, "never made")
+		} else {
+			var last time.Time
+			for _, entry := range history {
+				if entry.Date.After(last) {
+					last = entry.Date
+				}
+			}
+			days := int(options.Now.Sub(last).Hours() / 24)
+			switch {
+			case days == 0:
+				add(weightRecent, "made today")
+			case days < 7:
+				add(weightRecent, "made %d days ago", days)
+			case days >= 30:
+				add(weightLastMade*minFloat(float64(days)/60, 1), "not made in %d days", days)
+			}
+			if average, rated := history.AverageRating(); rated > 0 {
+				middle := float64(MaxRating+1) / 2
+				add(weightRating*(average-middle)/(middle-1), "rated %.1f/%d", average, MaxRating)
+			}
+		}
+
+		if total > 0 && options.MaxTime > 0 {
+			suggestion.Reasons = append(suggestion.Reasons, "takes "+total.String())
+		}
+
+		coverage := CoverRecipe(recipe, inventory, staples, nil)
+		if len(recipe.Ingredients) > 0 {
+			if coverage.CanMake() {
+				add(weightCoverage, "everything is on hand")
+			} else if coverage.Coverage >= 0.5 {
+				add(weightCoverage*coverage.Coverage, "%.0f%% of the ingredients are on hand, missing %d",
+					coverage.Coverage*100, len(coverage.Missing()))
+			} else {
+				add(weightCoverage*coverage.Coverage, "missing %d ingredients", len(coverage.Missing()))
+			}
+		}
+
+		expiringCount, inSeason := 0, 0
+		for _, ingredient := range recipe.Ingredients {
+			soonest := time.Time{}
+			for _, item := range inventory[CanonicalName(ingredient.Name)] {
+				for _, lot := range item.Lots {
+					// lots that have already expired should be thrown out,
+					// not used up
+					if lot.Quantity.Sign() > 0 && lot.ExpiresBefore(expiring) &&
+						!lot.Expires.Before(options.Now) && (soonest.IsZero() || lot.Expires.Before(soonest)) {
+						soonest = lot.Expires
+					}
+				}
+			}
+			if !soonest.IsZero() && expiringCount < 3 {
+				expiringCount++
+				add(weightExpiring, "uses %s expiring %s", ingredient.Name, soonest.Format(DateFormat))
+			}
+			seasonal, now := InSeason(ingredient.Name, options.Now.Month(), options.Southern)
+			switch {
+			case seasonal && now && inSeason < 2:
+				inSeason++
+				add(weightInSeason, "%s is in season", ingredient.Name)
+			case seasonal && !now:
+				add(weightOutSeason, "%s is out of season", ingredient.Name)
+			}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	sort.SliceStable(suggestions, func(a, b int) bool {
+		if suggestions[a].Score != suggestions[b].Score {
+			return suggestions[a].Score > suggestions[b].Score
+		}
+		return suggestions[a].Name < suggestions[b].Name
+	})
+	return suggestions
+}
+
+//minFloat returns the smaller of a and b
+func minFloat(a float64, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+//Surprise picks one of suggestions at random, favouring better scores, so
+//the best suggestion is the most likely but any can come up
+func Surprise(suggestions []Suggestion, random *rand.Rand) (Suggestion, bool) {
+	if len(suggestions) == 0 {
+		return Suggestion{}, false
+	}
+	lowest := suggestions[0].Score
+	for _, suggestion := range suggestions {
+		if suggestion.Score < lowest {
+			lowest = suggestion.Score
+		}
+	}
+	// every suggestion gets a chance, even the lowest scored
+	weights := make([]float64, len(suggestions))
+	total := 0.0
+	for i, suggestion := range suggestions {
+		weights[i] = suggestion.Score - lowest + 1
+		total += weights[i]
+	}
+	pick := random.Float64() * total
+	for i, weight := range weights {
+		if pick < weight {
+			return suggestions[i], true
+		}
+		pick -= weight
+	}
+	return suggestions[len(suggestions)-1], true
+}
+
+//SuggestionsString lists suggestions with their reasons
+func SuggestionsString(suggestions []Suggestion) string {
+	if len(suggestions) == 0 {
+		return "No recipes match\n"
+	}
+	var parts []string
+	for i, suggestion := range suggestions {
+		parts = append(parts, fmt.Sprintf("%d) %s", i+1, suggestion.String()))
+	}
+	return strings.Join(parts, "")
+}
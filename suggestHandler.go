@@ -0,0 +1,146 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	backend "github.com/sww1235/recipe-database"
+)
+
+//suggestRecipes scores every recipe in the database for options, best first
+func suggestRecipes(db *sql.DB, options backend.SuggestOptions) ([]backend.Suggestion, error) {
+	if options.Now.IsZero() {
+		options.Now = today()
+	}
+	if options.Staples == nil {
+		options.Staples = config.PantryStaples
+	}
+	options.Southern = config.SouthernHemisphere
+	recipes, err := selectAllRecipes(db)
+	if err != nil {
+		return nil, err
+	}
+	journal, err := selectJournal(db, "")
+	if err != nil {
+		return nil, err
+	}
+	inventory, err := inventoryByIngredient(db)
+	if err != nil {
+		return nil, err
+	}
+	return backend.SuggestRecipes(recipes, journal, inventory, options), nil
+}
+
+//pickSuggestions keeps the best count suggestions, or one picked at random
+//when surprise is set
+func pickSuggestions(suggestions []backend.Suggestion, count int, surprise bool) []backend.Suggestion {
+	if surprise {
+		random := rand.New(rand.NewSource(time.Now().UnixNano()))
+		if suggestion, ok := backend.Surprise(suggestions, random); ok {
+			return []backend.Suggestion{suggestion}
+		}
+		return nil
+	}
+	if count > 0 && len(suggestions) > count {
+		return suggestions[:count]
+	}
+	return suggestions
+}
+
+//suggestCommand runs the suggest subcommand given on the command line
+func suggestCommand(db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	tags := flags.String("tags", "", "Only suggest recipes with all of these comma separated tags")
+	diets := flags.String("diet", "", "Only suggest recipes suiting all of these comma separated diets, ie: vegan,gluten-free")
+	maxTime := flags.Duration("time", 0, "Only suggest recipes taking at most this long in total, ie: 45m")
+	count := flags.Int("n", 5, "Number of recipes to suggest, 0 for all")
+	surprise := flags.Bool("surprise", false, "Pick one matching recipe at random, favouring better suggestions")
+	format := flags.String("format", "text", "Output format: text or json")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: cookbook suggest [flags]")
+		fmt.Fprintln(flags.Output(), "Suggests recipes by when they were last made, ratings, inventory on hand")
+		fmt.Fprintln(flags.Output(), "and expiring soon, and produce in season, with the reasons for each")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	options := backend.SuggestOptions{Tags: splitTags(*tags), MaxTime: *maxTime}
+	var err error
+	if options.Diets, err = backend.ParseDiets(*diets); err != nil {
+		return err
+	}
+	suggestions, err := suggestRecipes(db, options)
+	if err != nil {
+		return err
+	}
+	suggestions = pickSuggestions(suggestions, *count, *surprise)
+	switch *format {
+	case "json":
+		if suggestions == nil {
+			suggestions = []backend.Suggestion{}
+		}
+		bytes, err := json.MarshalIndent(suggestions, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(bytes))
+	case "text":
+		if *surprise && len(suggestions) == 1 {
+			fmt.Print(suggestions[0].String())
+			break
+		}
+		fmt.Print(backend.SuggestionsString(suggestions))
+	default:
+		return fmt.Errorf("unknown format %s, expected text or json", *format)
+	}
+	return nil
+}
+
+//suggestHTTP suggests recipes. Query parameters match the suggest command:
+//tags, diet, time, n, surprise and format.
+func suggestHTTP(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	options := backend.SuggestOptions{Tags: splitTags(query.Get("tags"))}
+	var err error
+	if options.Diets, err = backend.ParseDiets(query.Get("diet")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if maxTime := query.Get("time"); maxTime != "" {
+		if options.MaxTime, err = time.ParseDuration(maxTime); err != nil {
+			http.Error(w, "time must be a duration, ie: 45m", http.StatusBadRequest)
+			return
+		}
+	}
+	count := 5
+	if n := query.Get("n"); n != "" {
+		if count, err = strconv.Atoi(n); err != nil {
+			http.Error(w, "n must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+	surprise, _ := strconv.ParseBool(query.Get("surprise"))
+	suggestions, err := suggestRecipes(db, options)
+	if err != nil {
+		infoLogger.Println("Error suggesting recipes:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	suggestions = pickSuggestions(suggestions, count, surprise)
+	if wantsJSON(r) {
+		if suggestions == nil {
+			suggestions = []backend.Suggestion{}
+		}
+		writeJSON(w, suggestions)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, backend.SuggestionsString(suggestions))
+}
@@ -0,0 +1,121 @@
+package recipeDatabase
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInSeason(t *testing.T) {
+	tests := []struct {
+		name     string
+		month    time.Month
+		southern bool
+		seasonal bool
+		now      bool
+	}{
+		{"ripe tomatoes", time.August, false, true, true},
+		{"tomato", time.January, false, true, false},
+		{"tomato", time.January, true, true, true},
+		{"sweet potatoes", time.October, false, true, true},
+		{"potato", time.October, false, false, false},
+		{"tomato paste", time.August, false, false, false},
+		{"frozen peas", time.May, false, false, false},
+		{"fresh strawberries", time.June, false, true, true},
+		{"flour", time.June, false, false, false},
+	}
+	for _, test := range tests {
+		seasonal, now := InSeason(test.name, test.month, test.southern)
+		if seasonal != test.seasonal || now != test.now {
+			t.Errorf("InSeason(%q, %s, %v) = %v, %v, want %v, %v", test.name, test.month, test.southern,
+				seasonal, now, test.seasonal, test.now)
+		}
+	}
+}
+
+//testSuggestRecipes returns three recipes to suggest from on 2026-07-15,
+//with when they were made and what is on hand
+func testSuggestRecipes(t *testing.T) ([]Recipe, Journal, map[string][]InventoryItem) {
+	recipes := []Recipe{
+		{ID: 1, Name: "tomato salad", Ingredients: []Ingredient{
+			{Name: "tomatoes", QuantityNeeded: NewQuantity(NewFraction(2, 1), "")},
+			{Name: "cucumber", QuantityNeeded: NewQuantity(NewFraction(1, 1), "")}}},
+		{ID: 2, Name: "pumpkin pie", Ingredients: []Ingredient{
+			{Name: "pumpkin", QuantityNeeded: NewQuantity(NewFraction(1, 1), "")}}},
+		{ID: 3, Name: "stew", Tags: []string{"dinner"}, Ingredients: []Ingredient{
+			{Name: "beef", QuantityNeeded: NewQuantity(NewFraction(1, 1), "lb")}}},
+	}
+	journal := Journal{
+		{RecipeID: 2, Date: testDate(t, "2026-07-12"), Rating: 5},
+		{RecipeID: 3, Date: testDate(t, "2026-04-16"), Rating: 3},
+	}
+	tomatoes := InventoryItem{ID: 1, Name: "tomatoes"}
+	tomatoes.AddLot(InventoryLot{Quantity: NewFraction(3, 1), Expires: testDate(t, "2026-07-18")})
+	inventory := map[string][]InventoryItem{
+		CanonicalName("tomatoes"): {tomatoes},
+		CanonicalName("cucumber"): {{ID: 2, Name: "cucumber", Quantity: NewFraction(1, 1)}},
+	}
+	return recipes, journal, inventory
+}
+
+func TestSuggestRecipes(t *testing.T) {
+	recipes, journal, inventory := testSuggestRecipes(t)
+	options := SuggestOptions{Staples: []string{}, Now: testDate(t, "2026-07-15")}
+	suggestions := SuggestRecipes(recipes, journal, inventory, options)
+	tests := []struct {
+		name    string
+		score   float64
+		reasons []string
+	}{
+		{"tomato salad", 3.75, []string{"never made", "everything is on hand",
+			"uses tomatoes expiring 2026-07-18", "tomatoes is in season", "cucumber is in season"}},
+		{"stew", 1, []string{"not made in 90 days", "rated 3.0/5", "missing 1 ingredients"}},
+		{"pumpkin pie", -1, []string{"made 3 days ago", "rated 5.0/5", "missing 1 ingredients",
+			"pumpkin is out of season"}},
+	}
+	if len(suggestions) != len(tests) {
+		t.Fatalf("SuggestRecipes returned %d suggestions, want %d", len(suggestions), len(tests))
+	}
+	for i, test := range tests {
+		suggestion := suggestions[i]
+		if suggestion.Name != test.name || suggestion.Score != test.score {
+			t.Errorf("suggestion %d is %s scoring %.2f, want %s scoring %.2f", i+1, suggestion.Name,
+				suggestion.Score, test.name, test.score)
+		}
+		if !reflect.DeepEqual(suggestion.Reasons, test.reasons) {
+			t.Errorf("%s suggested because %q, want %q", suggestion.Name, suggestion.Reasons, test.reasons)
+		}
+	}
+
+	options.Tags = []string{"Dinner"}
+	if suggestions = SuggestRecipes(recipes, journal, inventory, options); len(suggestions) != 1 ||
+		suggestions[0].Name != "stew" {
+		t.Errorf("SuggestRecipes for dinner returned %v, want only stew", suggestions)
+	}
+	// recipes without a time are left out when the time is limited
+	options.Tags, options.MaxTime = nil, time.Hour
+	if suggestions = SuggestRecipes(recipes, journal, inventory, options); len(suggestions) != 0 {
+		t.Errorf("SuggestRecipes within an hour returned %v, want none", suggestions)
+	}
+}
+
+func TestSurprise(t *testing.T) {
+	if _, ok := Surprise(nil, rand.New(rand.NewSource(1))); ok {
+		t.Error("Surprise with no suggestions returned one")
+	}
+	suggestions := []Suggestion{{Name: "best", Score: 3}, {Name: "worst", Score: -1}}
+	random := rand.New(rand.NewSource(1))
+	picked := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		suggestion, ok := Surprise(suggestions, random)
+		if !ok {
+			t.Fatal("Surprise returned no suggestion")
+		}
+		picked[suggestion.Name]++
+	}
+	// the best is picked five times as often as the worst
+	if picked["worst"] == 0 || picked["best"] < 3*picked["worst"] {
+		t.Errorf("Surprise picked %v, want best far more often but worst at least once", picked)
+	}
+}
@@ -12,8 +12,26 @@ type temperature struct {
 	Unit  TempUnit
 }
 
+//String renders the temperature in the preferred temperature scale from
+//Display, or as stored if there is no preference or the stored scale is not
+//recognized.
 func (t temperature) String() string {
-	return fmt.Sprintf("%Gº %v", t.Value, t.Unit)
+	if Display.TempScale != 0 && Display.TempScale != t.Unit && t.Unit.valid() {
+		return fmt.Sprintf("%.0fº %c", t.Convert(rune(Display.TempScale)), Display.TempScale)
+	}
+	if t.Unit == 0 {
+		return fmt.Sprintf("%Gº", t.Value)
+	}
+	return fmt.Sprintf("%Gº %c", t.Value, t.Unit)
+}
+
+//valid reports whether t is one of the accepted temperature scales
+func (t TempUnit) valid() bool {
+	switch t {
+	case 'F', 'C', 'K', 'R':
+		return true
+	}
+	return false
 }
 
 func (t temperature) Convert(dest rune) float64 {
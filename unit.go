@@ -1,6 +1,9 @@
 package recipeDatabase
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Unit struct {
 	ID          int    // id of unit in database
@@ -17,3 +20,359 @@ func (u Unit) String() string {
 	return stringString
 
 }
+
+//UnitFamily groups units that can be converted between each other using
+//a fixed factor. Converting between families (ie: cups to grams) requires
+//a conversion specific to an ingredient.
+type UnitFamily int
+
+//declare enum for UnitFamily
+const (
+	UnknownFamily UnitFamily = iota // 0
+	Volume
+	Mass
+	Count
+)
+
+//MeasurementSystem is the system of measurement a standard unit belongs to
+type MeasurementSystem int
+
+//declare enum for MeasurementSystem
+const (
+	AnySystem MeasurementSystem = iota // 0
+	Imperial
+	Metric
+)
+
+//unitDefinition stores a standard unit and how it converts to the base unit
+//of its family. The base units are milliliters for volume and grams for mass.
+type unitDefinition struct {
+	Name       string            // canonical name of unit
+	Plural     string            // name of unit when there is more than one
+	Aliases    []string          // other accepted spellings of unit
+	Family     UnitFamily        // family of unit
+	System     MeasurementSystem // measurement system of unit
+	ToBase     float64           // multiply by this to convert to base unit
+	Kitchen    bool              // unit is used when normalizing quantities
+	Minimum    float64           // smallest amount comfortably measured in unit
+	Increments []Fraction        // amounts that can be measured with common tools
+	Fine       Fraction          // increment for amounts below the first of Increments, zero if none
+}
+
+//standardUnits is the conversion table for all units that can be converted
+//without needing any information about the ingredient being measured.
+//ToBase is written as an exact decimal so conversions between units of the
+//same system stay exact once converted to a Fraction.
+var standardUnits = []unitDefinition{
+	{Name: "pinch", Plural: "pinches", Aliases: []string{"pinches"}, Family: Volume,
+		System: Imperial, ToBase: 0.308057599609375, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 1)}},
+	{Name: "dash", Plural: "dashes", Aliases: []string{"dashes"}, Family: Volume,
+		System: Imperial, ToBase: 0.61611519921875, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 1)}},
+	{Name: "tsp", Plural: "tsp", Aliases: []string{"teaspoon", "teaspoons", "t"}, Family: Volume,
+		System: Imperial, ToBase: 4.92892159375, Kitchen: true, Minimum: 0.125,
+		Increments: []Fraction{NewFraction(1, 8)}},
+	{Name: "tbsp", Plural: "tbsp", Aliases: []string{"tablespoon", "tablespoons", "tbs", "T"}, Family: Volume,
+		System: Imperial, ToBase: 14.78676478125, Kitchen: true, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 2)}},
+	{Name: "fl oz", Plural: "fl oz", Aliases: []string{"fluid ounce", "fluid ounces", "floz"}, Family: Volume,
+		System: Imperial, ToBase: 29.5735295625, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 2)}},
+	{Name: "cup", Plural: "cups", Aliases: []string{"cups", "c"}, Family: Volume,
+		System: Imperial, ToBase: 236.5882365, Kitchen: true, Minimum: 0.25,
+		Increments: []Fraction{NewFraction(1, 4), NewFraction(1, 3)}},
+	{Name: "pint", Plural: "pints", Aliases: []string{"pints", "pt"}, Family: Volume,
+		System: Imperial, ToBase: 473.176473, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 2)}},
+	{Name: "quart", Plural: "quarts", Aliases: []string{"quarts", "qt"}, Family: Volume,
+		System: Imperial, ToBase: 946.352946, Kitchen: true, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 4)}},
+	{Name: "gallon", Plural: "gallons", Aliases: []string{"gallons", "gal"}, Family: Volume,
+		System: Imperial, ToBase: 3785.411784, Kitchen: true, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 4)}},
+	{Name: "mL", Plural: "mL", Aliases: []string{"ml", "milliliter", "milliliters", "millilitre", "millilitres"}, Family: Volume,
+		System: Metric, ToBase: 1, Kitchen: true, Minimum: 1,
+		Increments: []Fraction{NewFraction(5, 1)}, Fine: NewFraction(1, 1)},
+	{Name: "L", Plural: "L", Aliases: []string{"l", "liter", "liters", "litre", "litres"}, Family: Volume,
+		System: Metric, ToBase: 1000, Kitchen: true, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 20)}},
+	{Name: "mg", Plural: "mg", Aliases: []string{"milligram", "milligrams"}, Family: Mass,
+		System: Metric, ToBase: 0.001, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 1)}},
+	{Name: "g", Plural: "g", Aliases: []string{"gram", "grams"}, Family: Mass,
+		System: Metric, ToBase: 1, Kitchen: true, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 1)}},
+	{Name: "kg", Plural: "kg", Aliases: []string{"kilogram", "kilograms"}, Family: Mass,
+		System: Metric, ToBase: 1000, Kitchen: true, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 100)}},
+	{Name: "oz", Plural: "oz", Aliases: []string{"ounce", "ounces"}, Family: Mass,
+		System: Imperial, ToBase: 28.349523125, Kitchen: true, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 4)}},
+	{Name: "lb", Plural: "lb", Aliases: []string{"lbs", "pound", "pounds"}, Family: Mass,
+		System: Imperial, ToBase: 453.59237, Kitchen: true, Minimum: 1,
+		Increments: []Fraction{NewFraction(1, 4)}},
+}
+
+//countIncrements are used to round quantities of units that are not in the
+//conversion table, such as loaves or eggs.
+var countIncrements = []Fraction{NewFraction(1, 4), NewFraction(1, 3)}
+
+//irregularPlurals are plurals of words commonly used as units that do not
+//follow the usual english rules
+var irregularPlurals = map[string]string{
+	"loaf": "loaves", "leaf": "leaves", "half": "halves", "knife": "knives",
+	"potato": "potatoes", "tomato": "tomatoes", "mouse": "mice",
+	"fish": "fish", "sheep": "sheep", "bunch": "bunches",
+}
+
+//lookupUnit finds the standard unit definition matching name. Aliases that
+//differ only by case are accepted, except for the single letter aliases
+//where case is significant (t and T).
+func lookupUnit(name string) (unitDefinition, bool) {
+	name = strings.TrimSpace(name)
+	for _, def := range standardUnits {
+		if def.Name == name {
+			return def, true
+		}
+		for _, alias := range def.Aliases {
+			if alias == name {
+				return def, true
+			}
+		}
+	}
+	if len(name) > 1 {
+		for _, def := range standardUnits {
+			if strings.EqualFold(def.Name, name) {
+				return def, true
+			}
+			for _, alias := range def.Aliases {
+				if len(alias) > 1 && strings.EqualFold(alias, name) {
+					return def, true
+				}
+			}
+		}
+	}
+	return unitDefinition{}, false
+}
+
+//ConvertUnits converts value from fromUnit to toUnit using the standard
+//conversion table. Returns an error if either unit is unknown or the units
+//are not in the same family.
+func ConvertUnits(value Fraction, fromUnit string, toUnit string) (Fraction, error) {
+	if sameUnit(fromUnit, toUnit) {
+		return value, nil
+	}
+	from, ok := lookupUnit(fromUnit)
+	if !ok {
+		return Fraction{}, fmt.Errorf("unknown unit %q", fromUnit)
+	}
+	to, ok := lookupUnit(toUnit)
+	if !ok {
+		return Fraction{}, fmt.Errorf("unknown unit %q", toUnit)
+	}
+	if from.Family != to.Family {
+		return Fraction{}, fmt.Errorf("cannot convert %s to %s", fromUnit, toUnit)
+	}
+	return value.MulFloat(from.ToBase).Div(FractionFromFloat(to.ToBase)), nil
+}
+
+//normalizeUnit picks the largest kitchen unit in the same family and
+//measurement system as unit that can still comfortably measure value, so
+//48 tsp becomes 1 cup. Only units that can measure the value exactly with
+//their increments are used, so 4 tsp stays 4 tsp rather than becoming
+//1 1/3 tbsp. If no unit can, or unit is unknown, it is returned unchanged.
+func normalizeUnit(value Fraction, unit string) (Fraction, string) {
+	from, ok := lookupUnit(unit)
+	if !ok || value.IsZero() {
+		return value, unit
+	}
+	base := value.MulFloat(from.ToBase)
+	bestValue, bestUnit := value, unit
+	bestFactor := 0.0
+	for _, def := range standardUnits {
+		if !def.Kitchen || def.Family != from.Family || def.System != from.System {
+			continue
+		}
+		converted := base.Div(FractionFromFloat(def.ToBase))
+		if converted.Cmp(FractionFromFloat(def.Minimum)) >= 0 && def.ToBase > bestFactor &&
+			measurable(converted, def.Increments) {
+			bestValue, bestUnit, bestFactor = converted, def.Name, def.ToBase
+		}
+	}
+	return bestValue, bestUnit
+}
+
+//measurable reports whether value is a whole number of one of increments
+func measurable(value Fraction, increments []Fraction) bool {
+	for _, increment := range increments {
+		if increment.Sign() > 0 && value.Div(increment).value().IsInt() {
+			return true
+		}
+	}
+	return false
+}
+
+//ConvertToSystem converts quantity of unit into the largest kitchen unit of
+//system that can comfortably measure it, staying within the same unit family
+//(cups become mL, not grams). If unit is not a standard unit, or is too small
+//to be measured in system, quantity and unit are returned unchanged and ok is
+//false.
+func ConvertToSystem(quantity Fraction, unit string, system MeasurementSystem) (Fraction, string, bool) {
+	from, ok := lookupUnit(unit)
+	if !ok {
+		return quantity, unit, false
+	}
+	if system == AnySystem || from.System == system {
+		return quantity, unit, true
+	}
+	base := quantity.MulFloat(from.ToBase)
+	bestValue, bestUnit := quantity, unit
+	bestFactor := 0.0
+	for _, def := range standardUnits {
+		if !def.Kitchen || def.Family != from.Family || def.System != system {
+			continue
+		}
+		converted := base.Div(FractionFromFloat(def.ToBase))
+		if converted.Cmp(FractionFromFloat(def.Minimum)) >= 0 && def.ToBase > bestFactor {
+			bestValue, bestUnit, bestFactor = converted, def.Name, def.ToBase
+		}
+	}
+	return bestValue, bestUnit, bestFactor != 0
+}
+
+//Pluralize returns the name of unit to use for quantity. Standard units are
+//always given by their canonical name, and abbreviated units such as tsp or
+//g are never pluralized.
+func Pluralize(unit string, quantity Fraction) string {
+	singular := quantity.Cmp(NewFraction(1, 1)) <= 0 && !quantity.IsZero()
+	if def, ok := lookupUnit(unit); ok {
+		if singular {
+			return def.Name
+		}
+		return def.Plural
+	}
+	if unit == "" || singular {
+		return unit
+	}
+	return pluralWord(unit)
+}
+
+//pluralWord applies the usual english rules for making a word plural
+func pluralWord(word string) string {
+	lowerWord := strings.ToLower(word)
+	if plural, ok := irregularPlurals[lowerWord]; ok {
+		return plural
+	}
+	for _, plural := range irregularPlurals {
+		if plural == lowerWord {
+			return word
+		}
+	}
+	switch {
+	case strings.HasSuffix(lowerWord, "ss"), strings.HasSuffix(lowerWord, "x"),
+		strings.HasSuffix(lowerWord, "z"), strings.HasSuffix(lowerWord, "ch"),
+		strings.HasSuffix(lowerWord, "sh"):
+		return word + "es"
+	case strings.HasSuffix(lowerWord, "s"):
+		// assume word is already plural
+		return word
+	case strings.HasSuffix(lowerWord, "y") && len(lowerWord) > 1 &&
+		!strings.ContainsAny(lowerWord[len(lowerWord)-2:len(lowerWord)-1], "aeiou"):
+		return word[:len(word)-1] + "ies"
+	}
+	return word + "s"
+}
+
+//singularWord undoes pluralWord as far as it can, so that "eggs" and "egg"
+//or "berries" and "berry" can be matched
+func singularWord(word string) string {
+	lowerWord := strings.ToLower(word)
+	for singular, plural := range irregularPlurals {
+		if plural == lowerWord && singular != plural {
+			return singular
+		}
+	}
+	switch {
+	case strings.HasSuffix(lowerWord, "ies") && len(lowerWord) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(lowerWord, "oes"), strings.HasSuffix(lowerWord, "ches"),
+		strings.HasSuffix(lowerWord, "shes"), strings.HasSuffix(lowerWord, "xes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(lowerWord, "ss"), strings.HasSuffix(lowerWord, "us"),
+		strings.HasSuffix(lowerWord, "is"), strings.HasSuffix(lowerWord, "ses"):
+		return word
+	case strings.HasSuffix(lowerWord, "s"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+//sameUnit reports whether a and b name the same unit, ignoring case and
+//plural forms.
+func sameUnit(a string, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	defA, okA := lookupUnit(a)
+	defB, okB := lookupUnit(b)
+	if okA || okB {
+		return okA && okB && defA.Name == defB.Name
+	}
+	return strings.EqualFold(pluralWord(a), b) || strings.EqualFold(a, pluralWord(b))
+}
+
+//FormatQuantity renders quantity of unit the way it would be written in
+//a recipe: rounded to an amount that can be measured, as a kitchen fraction
+//for imperial and count units, as a decimal for metric units, with the unit
+//name pluralized as needed. ie: "1 ¾ cups", "⅓ loaf", "250 g".
+func FormatQuantity(quantity Fraction, unit string) string {
+	increments := countIncrements
+	metric := false
+	if def, ok := lookupUnit(unit); ok {
+		increments = def.Increments
+		metric = def.System == Metric
+		// small amounts are rounded finely, so 2 mL does not become 5 mL
+		if !def.Fine.IsZero() && quantity.Cmp(def.Increments[0]) < 0 {
+			increments = []Fraction{def.Fine}
+		}
+	}
+
+	var rounded Fraction
+	if metric {
+		rounded = quantity
+		if Display.RoundMetric {
+			rounded = roundToIncrements(quantity, increments)
+		}
+	} else {
+		rounded = roundToIncrements(quantity, increments)
+	}
+
+	var quantityString string
+	if metric {
+		quantityString = rounded.DecimalString(2)
+	} else {
+		quantityString = rounded.KitchenString()
+	}
+	if unit == "" {
+		return quantityString
+	}
+	return quantityString + " " + Pluralize(unit, rounded)
+}
+
+//roundToIncrements rounds quantity to whichever of increments gives the
+//closest result.
+func roundToIncrements(quantity Fraction, increments []Fraction) Fraction {
+	if len(increments) == 0 {
+		return quantity
+	}
+	best := quantity.RoundTo(increments[0])
+	for _, increment := range increments[1:] {
+		candidate := quantity.RoundTo(increment)
+		if candidate.Sub(quantity).Abs().Cmp(best.Sub(quantity).Abs()) < 0 {
+			best = candidate
+		}
+	}
+	return best
+}
@@ -0,0 +1,131 @@
+package recipeDatabase
+
+import "testing"
+
+func TestConvertUnits(t *testing.T) {
+	tests := []struct {
+		value    Fraction
+		fromUnit string
+		toUnit   string
+		want     Fraction
+	}{
+		{NewFraction(3, 1), "tsp", "tbsp", NewFraction(1, 1)},
+		{NewFraction(1, 1), "cup", "tablespoons", NewFraction(16, 1)},
+		{NewFraction(1, 2), "gallon", "quarts", NewFraction(2, 1)},
+		{NewFraction(1, 1), "lb", "oz", NewFraction(16, 1)},
+		{NewFraction(3, 2), "kg", "g", NewFraction(1500, 1)},
+		{NewFraction(250, 1), "mL", "L", NewFraction(1, 4)},
+		{NewFraction(2, 1), "cups", "cup", NewFraction(2, 1)},
+		// units outside the table convert only to themselves
+		{NewFraction(2, 1), "loaves", "loaf", NewFraction(2, 1)},
+	}
+	for _, test := range tests {
+		got, err := ConvertUnits(test.value, test.fromUnit, test.toUnit)
+		if err != nil {
+			t.Errorf("ConvertUnits(%s, %q, %q) returned error: %s", test.value, test.fromUnit, test.toUnit, err)
+			continue
+		}
+		if got.Cmp(test.want) != 0 {
+			t.Errorf("ConvertUnits(%s, %q, %q) = %s, want %s", test.value, test.fromUnit, test.toUnit, got,
+				test.want)
+		}
+	}
+}
+
+func TestConvertUnitsErrors(t *testing.T) {
+	tests := []struct {
+		fromUnit string
+		toUnit   string
+	}{
+		{"cup", "g"},
+		{"oz", "fl oz"},
+		{"handful", "cup"},
+		{"cup", "handful"},
+	}
+	for _, test := range tests {
+		if got, err := ConvertUnits(NewFraction(1, 1), test.fromUnit, test.toUnit); err == nil {
+			t.Errorf("ConvertUnits(1, %q, %q) = %s, want an error", test.fromUnit, test.toUnit, got)
+		}
+	}
+}
+
+func TestNormalizeUnit(t *testing.T) {
+	tests := []struct {
+		value     Fraction
+		unit      string
+		wantValue Fraction
+		wantUnit  string
+	}{
+		{NewFraction(48, 1), "tsp", NewFraction(1, 1), "cup"},
+		{NewFraction(6, 1), "tsp", NewFraction(2, 1), "tbsp"},
+		{NewFraction(1, 2), "tsp", NewFraction(1, 2), "tsp"},
+		{NewFraction(32, 1), "oz", NewFraction(2, 1), "lb"},
+		{NewFraction(2500, 1), "g", NewFraction(5, 2), "kg"},
+		{NewFraction(1500, 1), "mL", NewFraction(3, 2), "L"},
+		// measurement systems are never mixed
+		{NewFraction(500, 1), "g", NewFraction(500, 1), "g"},
+		// units that can not measure the value exactly are not used
+		{NewFraction(4, 1), "tsp", NewFraction(4, 1), "tsp"},
+		{NewFraction(6, 1), "tbsp", NewFraction(6, 1), "tbsp"},
+		{NewFraction(3, 8), "cup", NewFraction(6, 1), "tbsp"},
+		{NewFraction(1234, 1), "g", NewFraction(1234, 1), "g"},
+		{NewFraction(3, 1), "eggs", NewFraction(3, 1), "eggs"},
+	}
+	for _, test := range tests {
+		gotValue, gotUnit := normalizeUnit(test.value, test.unit)
+		if gotValue.Cmp(test.wantValue) != 0 || gotUnit != test.wantUnit {
+			t.Errorf("normalizeUnit(%s, %q) = %s %s, want %s %s", test.value, test.unit, gotValue, gotUnit,
+				test.wantValue, test.wantUnit)
+		}
+	}
+}
+
+func TestFormatQuantity(t *testing.T) {
+	defer func(display DisplayOptions) { Display = display }(Display)
+	Display.RoundMetric = true
+	tests := []struct {
+		quantity Fraction
+		unit     string
+		want     string
+	}{
+		{NewFraction(3, 10), "cup", "⅓ cup"},
+		{NewFraction(7, 4), "cups", "1 ¾ cups"},
+		{NewFraction(1, 100), "tsp", "⅛ tsp"},
+		{NewFraction(5, 2), "loaf", "2 ½ loaves"},
+		{NewFraction(2, 1), "", "2"},
+		{FractionFromFloat(252.4), "g", "252 g"},
+		{FractionFromFloat(1.234), "kg", "1.23 kg"},
+		{NewFraction(1, 1), "mL", "1 mL"},
+		{NewFraction(2, 1), "mL", "2 mL"},
+		{NewFraction(12, 1), "mL", "10 mL"},
+		{NewFraction(248, 1), "mL", "250 mL"},
+	}
+	for _, test := range tests {
+		if got := FormatQuantity(test.quantity, test.unit); got != test.want {
+			t.Errorf("FormatQuantity(%s, %q) = %q, want %q", test.quantity, test.unit, got, test.want)
+		}
+	}
+}
+
+func TestSingularAndPluralWords(t *testing.T) {
+	tests := []struct {
+		singular string
+		plural   string
+	}{
+		{"egg", "eggs"},
+		{"berry", "berries"},
+		{"loaf", "loaves"},
+		{"tomato", "tomatoes"},
+		{"bunch", "bunches"},
+		{"dash", "dashes"},
+		{"fish", "fish"},
+	}
+	for _, test := range tests {
+		if got := pluralWord(test.singular); got != test.plural {
+			t.Errorf("pluralWord(%q) = %q, want %q", test.singular, got, test.plural)
+		}
+		if got := singularWord(test.plural); got != test.singular {
+			t.Errorf("singularWord(%q) = %q, want %q", test.plural, got, test.singular)
+		}
+	}
+}